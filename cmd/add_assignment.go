@@ -46,9 +46,11 @@ func init() {
 					ctx.Error.Printf("grades have been entered for assignment %v; in order to overwrite, first delete all grades for this assignment\n", asgn.Code)
 					exitLogic()
 				}
+				if err := ctx.DB.ReplaceAssignment(asgn); err != nil {
+					ctx.Error.Printf("could not overwrite assignment: %v\n", err)
+					exitLogic()
+				}
 				ctx.Warn.Printf("warning: overwriting assignment %v\n", asgn.Code)
-				ctx.DB.DeleteAssignment(asgn.Code)
-				ctx.DB.AddAssignment(asgn)
 				changed = true
 			} else {
 				ctx.Warn.Printf("assignment %v already in database; use --force to overwrite\n", asgn.Code)
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
+	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 
 	"github.com/joshlf/kudos/lib/config"
 	"github.com/joshlf/kudos/lib/dev"
@@ -43,37 +47,58 @@ func (s sortableStudents) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 // and the exit codes used are chosen based on this
 // assumption.
 func lookupStudent(ctx *kudos.Context, u string) *student {
+	usr := lookupUser(ctx, u)
+	s := student{usr: usr}
+	if isNumeric(u) {
+		s.str = usr.Uid
+	} else {
+		s.str = usr.Username
+	}
+
+	ss, ok := ctx.DB.Students[usr.Uid]
+	if !ok {
+		ctx.Error.Printf("no such student: %v\n", s.str)
+		exitLogic()
+	}
+	s.student = ss
+
+	return &s
+}
+
+// Looks up a user by either username or UID.
+// If an error is encountered, it is logged to
+// ctx.Error, and the process exits.
+//
+// It is assumed that the argument is obtained
+// from a user-supplied command-line argument,
+// and the exit codes used are chosen based on this
+// assumption.
+func lookupUser(ctx *kudos.Context, u string) *user.User {
 	if len(u) == 0 {
 		ctx.Error.Println("bad username or uid: empty")
 		exitUsage()
 	}
 
-	numeric := true
-	for _, c := range u {
-		if !(c >= '0' && c <= '9') {
-			numeric = false
-		}
+	usr, err := findUser(u)
+	if err != nil {
+		ctx.Error.Println(err)
+		dev.Fail()
 	}
+	return usr
+}
 
-	var usr *user.User
-	var err error
-
-	if numeric {
-		usr, err = user.LookupId(u)
-		if err != nil {
-			ctx.Error.Printf("could not find user with uid %v: %v\n", u, err)
-			dev.Fail()
-		}
-	} else {
-		usr, err = user.Lookup(u)
-		if err != nil {
-			ctx.Error.Printf("could not find user %v: %v\n", u, err)
-			dev.Fail()
-		}
+// Like lookupStudent, but returns any error
+// encountered instead of exiting.
+func findStudent(ctx *kudos.Context, u string) (*student, error) {
+	if len(u) == 0 {
+		return nil, fmt.Errorf("bad username or uid: empty")
+	}
+	usr, err := findUser(u)
+	if err != nil {
+		return nil, err
 	}
-
 	s := student{usr: usr}
-	if numeric {
+	if isNumeric(u) {
 		s.str = usr.Uid
 	} else {
 		s.str = usr.Username
@@ -81,12 +106,53 @@ func lookupStudent(ctx *kudos.Context, u string) *student {
 
 	ss, ok := ctx.DB.Students[usr.Uid]
 	if !ok {
-		ctx.Error.Printf("no such student: %v\n", s.str)
-		exitLogic()
+		return nil, fmt.Errorf("no such student: %v", s.str)
 	}
 	s.student = ss
+	return &s, nil
+}
 
-	return &s
+// Looks up a user by either username or UID.
+func findUser(u string) (*user.User, error) {
+	if isNumeric(u) {
+		usr, err := user.LookupId(u)
+		if err != nil {
+			return nil, fmt.Errorf("could not find user with uid %v: %v", u, err)
+		}
+		return usr, nil
+	}
+	usr, err := user.Lookup(u)
+	if err != nil {
+		return nil, fmt.Errorf("could not find user %v: %v", u, err)
+	}
+	return usr, nil
+}
+
+// Asks the user the given yes-or-no question on
+// standard error, and returns whether they answered
+// yes. Anything other than "y" or "yes" (including
+// an error reading standard input) is taken as no.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%v [y/N] ", question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(os.Stderr)
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
 }
 
 // Looks up the username of the user with the
@@ -120,6 +186,22 @@ func getUserBlacklistPath(ctx *kudos.Context) string {
 	return filepath.Join(u.HomeDir, config.UserBlacklistFileName)
 }
 
+// Reads the blacklist of the given user. If the
+// user has no blacklist, nil is returned. If the
+// blacklist exists but cannot be read, a warning
+// is logged and nil is returned.
+func readUserBlacklist(ctx *kudos.Context, usr *user.User) []string {
+	path := filepath.Join(usr.HomeDir, config.UserBlacklistFileName)
+	uids, err := kudos.ParseBlacklistFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ctx.Warn.Printf("warning: could not read blacklist for %v: %v; ignoring\n", usr.Username, err)
+		}
+		return nil
+	}
+	return uids
+}
+
 // attempts to open the database; if an error is
 // encountered, it is logged and the process exits
 func openDB(ctx *kudos.Context) {
@@ -210,6 +292,32 @@ func readPubDB(ctx *kudos.Context) {
 	}
 }
 
+// Publishes the given per-student information (see
+// kudos.PubStudent). This should be called only after
+// the database changes from which pubs were computed
+// have been committed. If an error is encountered,
+// it is logged and the process exits.
+func publishStudents(ctx *kudos.Context, pubs []*kudos.PubStudent) {
+	if !tryPublishStudents(ctx, pubs) {
+		dev.Fail()
+	}
+}
+
+// Like publishStudents, but if an error is encountered,
+// it is logged and false is returned rather than exiting,
+// for callers which have more work to do regardless.
+func tryPublishStudents(ctx *kudos.Context, pubs []*kudos.PubStudent) (ok bool) {
+	ok = true
+	for _, p := range pubs {
+		err := ctx.WritePubStudent(p)
+		if err != nil {
+			ctx.Error.Printf("could not publish information to %v: %v\n", lookupUsernameForUID(ctx, p.UID), err)
+			ok = false
+		}
+	}
+	return ok
+}
+
 // Validates the assignment code and tries to fetch
 // the assignment from the database. If either validation
 // or lookup fails, an error is logged and the process
@@ -233,6 +341,24 @@ func getAssignment(ctx *kudos.Context, code string, logName bool) *kudos.Assignm
 	return asgn
 }
 
+// Validates the section code and tries to fetch the
+// section from the database. If either validation or
+// lookup fails, an error is logged and the process
+// exits (exitUsage for an invalid code and exitLogic
+// for a nonexistent section).
+func getSection(ctx *kudos.Context, code string) *kudos.Section {
+	if err := kudos.ValidateCode(code); err != nil {
+		ctx.Error.Printf("bad section code %q: %v\n", code, err)
+		exitUsage()
+	}
+	s, ok := ctx.DB.Sections[code]
+	if !ok {
+		ctx.Error.Printf("no such section: %v\n", code)
+		exitLogic()
+	}
+	return s
+}
+
 // Validates the given code. If it is invalid, an error
 // is logged and exitUsage() is called. If logCode is true,
 // the log message will include the code itself. This is
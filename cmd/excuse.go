@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdExcuse = &cobra.Command{
+	Use:   "excuse",
+	Short: "Manage students' excusals from assignments and problems",
+	Long: "A student who is excused from a problem (or from an entire assignment) " +
+		"is not graded on it: the problem's points are left out of both the " +
+		"student's total and the total it is out of, and out of final grades and " +
+		"statistics. An excusal takes precedence over any grades the student has " +
+		"been given on the problem.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		exitUsage()
+	}
+	cmdExcuse.Run = f
+	addAllGlobalFlagsTo(cmdExcuse.Flags())
+	cmdMain.AddCommand(cmdExcuse)
+}
+
+// Looks up the problem of asgn given by code. If code
+// is the empty string, the empty string is returned
+// (referring to the entire assignment). If the problem
+// cannot be found, an error is logged and the process
+// exits.
+func getExcusalProblem(ctx *kudos.Context, asgn *kudos.Assignment, code string) string {
+	if code == "" {
+		return ""
+	}
+	if err := kudos.ValidateCode(code); err != nil {
+		ctx.Error.Printf("bad problem code %q: %v\n", code, err)
+		exitUsage()
+	}
+	if _, ok := asgn.FindProblemByCode(code); !ok {
+		ctx.Error.Printf("no such problem: %v\n", code)
+		exitLogic()
+	}
+	return code
+}
+
+var cmdExcuseGrant = &cobra.Command{
+	Use:   "grant <assignment> [<problem>] <student>",
+	Short: "Excuse a student from an assignment or problem",
+	Long: "Excuse a student from the given problem, or from the entire " +
+		"assignment if no problem is given, replacing any previous excusal.",
+}
+
+func init() {
+	var reasonFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		switch {
+		case len(args) != 2 && len(args) != 3:
+			cmd.Usage()
+			exitUsage()
+		case reasonFlag == "":
+			fmt.Fprintln(os.Stderr, "must specify reason with --reason")
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		var pcode string
+		if len(args) == 3 {
+			pcode = getExcusalProblem(ctx, asgn, args[1])
+		}
+		s := lookupStudent(ctx, args[len(args)-1])
+
+		replaced := ctx.DB.Excuse(asgn, s.student.UID, pcode, kudos.Excusal{
+			Reason:     reasonFlag,
+			GranterUID: cur.Uid,
+			Time:       time.Now(),
+		})
+		if replaced {
+			ctx.Warn.Println("warning: replacing previous excusal")
+		}
+		pubs := releasedPubs(ctx, asgn.Code, s.student.UID)
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+	}
+	cmdExcuseGrant.Run = f
+	addAllGlobalFlagsTo(cmdExcuseGrant.Flags())
+	cmdExcuseGrant.Flags().StringVarP(&reasonFlag, "reason", "", "", "the reason the student was excused")
+	cmdExcuse.AddCommand(cmdExcuseGrant)
+}
+
+var cmdExcuseRevoke = &cobra.Command{
+	Use:   "revoke <assignment> [<problem>] <student>",
+	Short: "Revoke a student's excusal",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 && len(args) != 3 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		var pcode string
+		if len(args) == 3 {
+			pcode = getExcusalProblem(ctx, asgn, args[1])
+		}
+		s := lookupStudent(ctx, args[len(args)-1])
+
+		if !ctx.DB.Unexcuse(asgn, s.student.UID, pcode) {
+			ctx.Error.Println("excusal does not exist")
+			exitLogic()
+		}
+		pubs := releasedPubs(ctx, asgn.Code, s.student.UID)
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+	}
+	cmdExcuseRevoke.Run = f
+	addAllGlobalFlagsTo(cmdExcuseRevoke.Flags())
+	cmdExcuse.AddCommand(cmdExcuseRevoke)
+}
+
+var cmdExcuseList = &cobra.Command{
+	Use:   "list",
+	Short: "List excusals",
+}
+
+func init() {
+	var studentFlag string
+	var assignmentFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var acodes []string
+		if cmd.Flag("assignment").Changed {
+			acodes = []string{getAssignment(ctx, assignmentFlag, false).Code}
+		} else {
+			for code := range ctx.DB.Assignments {
+				acodes = append(acodes, code)
+			}
+			sort.Strings(acodes)
+		}
+		var stud *student
+		if cmd.Flag("student").Changed {
+			stud = lookupStudent(ctx, studentFlag)
+		}
+
+		for _, acode := range acodes {
+			asgn := ctx.DB.Assignments[acode]
+			var pairs unameUIDPairs
+			for uid, g := range ctx.DB.Grades[acode] {
+				if len(g.Excused) > 0 && (stud == nil || uid == stud.student.UID) {
+					pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+				}
+			}
+			sort.Sort(pairs)
+
+			for _, pair := range pairs {
+				g := ctx.DB.Grades[acode][pair.uid]
+				// list the entire assignment first,
+				// and then problems in pre-order
+				pcodes := []string{""}
+				asgn.TraverseProblemsPreOrder(func(p kudos.Problem) {
+					pcodes = append(pcodes, p.Code)
+				})
+				for _, pcode := range pcodes {
+					e, ok := g.Excused[pcode]
+					if !ok {
+						continue
+					}
+					name := acode
+					if pcode != "" {
+						name += " " + pcode
+					}
+					fmt.Printf("%v for %v: excused by %v on %v: %v\n", name, pair.uname,
+						lookupUsernameForUID(ctx, e.GranterUID), e.Time.Format(kudos.DateFormat), e.Reason)
+				}
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdExcuseList.Run = f
+	addAllGlobalFlagsTo(cmdExcuseList.Flags())
+	cmdExcuseList.Flags().StringVarP(&studentFlag, "student", "", "", "only list this student's excusals")
+	cmdExcuseList.Flags().StringVarP(&assignmentFlag, "assignment", "", "", "only list excusals for this assignment")
+	cmdExcuse.AddCommand(cmdExcuseList)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdExport = &cobra.Command{
+	Use:   "export",
+	Short: "Export course data",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		exitUsage()
+	}
+	cmdExport.Run = f
+	addAllGlobalFlagsTo(cmdExport.Flags())
+	cmdMain.AddCommand(cmdExport)
+}
+
+var cmdExportGrades = &cobra.Command{
+	Use:   "grades [<assignment> ...]",
+	Short: "Export grades",
+	Long: "Export a gradebook with a row for each student and a column for each of " +
+		"the given assignments (or all assignments if none are given). With " +
+		"--by=assignment (the default), each column holds students' totals " +
+		"(adjusted for lateness); with --by=problem, there is a column for each " +
+		"problem and subproblem, headed <assignment>:<problem>.\n\n" +
+		"The csv format marks missing, incomplete, and excused grades as " +
+		"\"missing\", \"incomplete\", and \"excused\", and marks subproblems whose " +
+		"parent problem was graded as a whole as \"covered\"; the json format gives " +
+		"each grade's status explicitly. The canvas format produces a CSV file which " +
+		"can be imported into a Canvas gradebook; missing, incomplete, and covered " +
+		"grades are left blank, and excused grades are marked \"EX\". By default, " +
+		"Canvas columns are headed by assignment names; to use different headers " +
+		"(for example, to match existing Canvas assignments), pass --column-map " +
+		"with a JSON file mapping column keys (as in the csv format) to headers. " +
+		"If --column-map is given, only the mapped columns are exported.\n\n" +
+		"Students who have dropped the course are left out unless --include-dropped " +
+		"is given.",
+}
+
+func init() {
+	var byFlag string
+	var formatFlag string
+	var columnMapFlag string
+	var outputFlag string
+	var precisionFlag uint8
+	var includeDroppedFlag bool
+	var sectionFlag string
+
+	formatDecimal := func(d kudos.Decimal) string {
+		return d.Round(int(precisionFlag)).String()
+	}
+
+	writeCSV := func(w io.Writer, g *kudos.Gradebook) error {
+		cw := csv.NewWriter(w)
+		header := []string{"username", "uid"}
+		for _, c := range g.Columns {
+			header = append(header, c.Key)
+		}
+		cw.Write(header)
+		for _, r := range g.Rows {
+			rec := []string{r.Username, r.UID}
+			for _, c := range r.Cells {
+				if c.Status == kudos.CellGraded {
+					rec = append(rec, formatDecimal(c.Grade))
+				} else {
+					rec = append(rec, string(c.Status))
+				}
+			}
+			cw.Write(rec)
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	writeJSON := func(w io.Writer, g *kudos.Gradebook) error {
+		buf, err := json.MarshalIndent(g, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(buf, '\n'))
+		return err
+	}
+
+	// columns maps column keys to headers;
+	// if nil, all columns are written
+	writeCanvas := func(w io.Writer, g *kudos.Gradebook, columns map[string]string) error {
+		cw := csv.NewWriter(w)
+		header := []string{"Student", "ID", "SIS User ID", "SIS Login ID", "Section"}
+		points := []string{"Points Possible", "", "", "", ""}
+		// indices of the columns to write
+		var cols []int
+		for i, c := range g.Columns {
+			name := c.Name
+			if columns != nil {
+				var ok bool
+				if name, ok = columns[c.Key]; !ok {
+					continue
+				}
+			}
+			cols = append(cols, i)
+			header = append(header, name)
+			points = append(points, formatDecimal(c.OutOf))
+		}
+		cw.Write(header)
+		cw.Write(points)
+		for _, r := range g.Rows {
+			rec := []string{r.Username, "", "", r.Username, ""}
+			for _, i := range cols {
+				// Canvas uses "EX" to mark excused grades
+				switch c := r.Cells[i]; c.Status {
+				case kudos.CellGraded:
+					rec = append(rec, formatDecimal(c.Grade))
+				case kudos.CellExcused:
+					rec = append(rec, "EX")
+				default:
+					rec = append(rec, "")
+				}
+			}
+			cw.Write(rec)
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	f := func(cmd *cobra.Command, args []string) {
+		ctx := getContext()
+		switch {
+		case byFlag != "assignment" && byFlag != "problem":
+			ctx.Error.Printf("bad --by value %q: must be assignment or problem\n", byFlag)
+			exitUsage()
+		case formatFlag != "csv" && formatFlag != "json" && formatFlag != "canvas":
+			ctx.Error.Printf("bad --format value %q: must be csv, json, or canvas\n", formatFlag)
+			exitUsage()
+		case cmd.Flag("column-map").Changed && formatFlag != "canvas":
+			ctx.Error.Println("--column-map can only be used with --format=canvas")
+			exitUsage()
+		}
+		for _, a := range args {
+			if err := kudos.ValidateCode(a); err != nil {
+				ctx.Error.Printf("bad assignment code %q: %v\n", a, err)
+				exitUsage()
+			}
+		}
+		addCourseConfig(ctx)
+
+		var columns map[string]string
+		if cmd.Flag("column-map").Changed {
+			file, err := os.Open(columnMapFlag)
+			if err != nil {
+				ctx.Error.Printf("could not open column map: %v\n", err)
+				exitLogic()
+			}
+			err = json.NewDecoder(file).Decode(&columns)
+			file.Close()
+			if err != nil {
+				ctx.Error.Printf("could not parse column map: %v\n", err)
+				exitLogic()
+			}
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var asgns []*kudos.Assignment
+		if len(args) > 0 {
+			for _, a := range args {
+				asgns = append(asgns, getAssignment(ctx, a, true))
+			}
+		} else {
+			var acodes []string
+			for code := range ctx.DB.Assignments {
+				acodes = append(acodes, code)
+			}
+			sort.Strings(acodes)
+			for _, code := range acodes {
+				asgns = append(asgns, ctx.DB.Assignments[code])
+			}
+		}
+
+		if cmd.Flag("section").Changed {
+			getSection(ctx, sectionFlag)
+		}
+		var pairs unameUIDPairs
+		for uid, s := range ctx.DB.Students {
+			if (s.Dropped() && !includeDroppedFlag) || !ctx.DB.InSection(uid, sectionFlag) {
+				continue
+			}
+			pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+		}
+		sort.Sort(pairs)
+		var uids []string
+		unames := make(map[string]string)
+		for _, pair := range pairs {
+			uids = append(uids, pair.uid)
+			unames[pair.uid] = pair.uname
+		}
+		uname := func(uid string) string { return unames[uid] }
+
+		var g *kudos.Gradebook
+		if byFlag == "problem" {
+			g = ctx.DB.ProblemGradebook(asgns, uids, uname)
+		} else {
+			g = ctx.DB.AssignmentGradebook(ctx.Course, asgns, uids, uname)
+		}
+		closeDB(ctx)
+
+		if columns != nil {
+			keys := make(map[string]bool)
+			for _, c := range g.Columns {
+				keys[c.Key] = true
+			}
+			for key := range columns {
+				if !keys[key] {
+					ctx.Error.Printf("bad column map: no such column: %v\n", key)
+					exitLogic()
+				}
+			}
+		}
+
+		w := io.Writer(os.Stdout)
+		if cmd.Flag("output").Changed {
+			file, err := os.Create(outputFlag)
+			if err != nil {
+				ctx.Error.Printf("could not create output file: %v\n", err)
+				exitLogic()
+			}
+			defer file.Close()
+			w = file
+		}
+
+		var err error
+		switch formatFlag {
+		case "csv":
+			err = writeCSV(w, g)
+		case "json":
+			err = writeJSON(w, g)
+		case "canvas":
+			err = writeCanvas(w, g, columns)
+		}
+		if err != nil {
+			ctx.Error.Printf("could not write grades: %v\n", err)
+			exitLogic()
+		}
+	}
+	cmdExportGrades.Run = f
+	addAllGlobalFlagsTo(cmdExportGrades.Flags())
+	cmdExportGrades.Flags().StringVarP(&byFlag, "by", "", "assignment", "export totals by assignment or grades by problem (assignment or problem)")
+	cmdExportGrades.Flags().StringVarP(&formatFlag, "format", "", "csv", "output format (csv, json, or canvas)")
+	cmdExportGrades.Flags().StringVarP(&columnMapFlag, "column-map", "", "", "JSON file mapping column keys to Canvas column headers")
+	cmdExportGrades.Flags().StringVarP(&outputFlag, "output", "o", "", "write to this file instead of standard output")
+	cmdExportGrades.Flags().Uint8VarP(&precisionFlag, "precision", "", 2, "the maximum number of digits after the decimal point to use when formatting grades")
+	cmdExportGrades.Flags().BoolVarP(&includeDroppedFlag, "include-dropped", "", false, "include students who have dropped the course")
+	cmdExportGrades.Flags().StringVarP(&sectionFlag, "section", "", "", "only include students in this section")
+	cmdExport.AddCommand(cmdExportGrades)
+}
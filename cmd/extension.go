@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdExtension = &cobra.Command{
+	Use:   "extension",
+	Short: "Manage students' extensions",
+	// TODO(joshlf): long description
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		exitUsage()
+	}
+	cmdExtension.Run = f
+	addAllGlobalFlagsTo(cmdExtension.Flags())
+	cmdMain.AddCommand(cmdExtension)
+}
+
+// Looks up the handin of asgn given by code. If code
+// is the empty string, asgn must have only one handin,
+// which is returned. If the handin cannot be found, an
+// error is logged and the process exits.
+func getHandin(ctx *kudos.Context, asgn *kudos.Assignment, code string) kudos.Handin {
+	if code == "" {
+		if len(asgn.Handins) > 1 {
+			ctx.Error.Println("assignment has multiple handins; please specify one")
+			exitUsage()
+		}
+		return asgn.Handins[0]
+	}
+	if len(asgn.Handins) == 1 {
+		ctx.Error.Println("assignment has one handin; cannot specify handin")
+		exitUsage()
+	}
+	if err := kudos.ValidateCode(code); err != nil {
+		ctx.Error.Printf("bad handin code %q: %v\n", code, err)
+		exitUsage()
+	}
+	h, ok := asgn.FindHandinByCode(code)
+	if !ok {
+		ctx.Error.Printf("no such handin: %v\n", code)
+		exitLogic()
+	}
+	return h
+}
+
+var cmdExtensionGrant = &cobra.Command{
+	Use:   "grant <assignment> [<handin>] <student> <new-due>",
+	Short: "Grant a student an extension",
+	Long: "Grant a student a new due date for a handin, replacing any previous " +
+		"extension. The due date must be in the format \"" + kudos.DateFormat + "\", " +
+		"quoted so that it is passed as a single argument.",
+}
+
+func init() {
+	var reasonFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		switch {
+		case len(args) != 3 && len(args) != 4:
+			cmd.Usage()
+			exitUsage()
+		case reasonFlag == "":
+			fmt.Fprintln(os.Stderr, "must specify reason with --reason")
+			exitUsage()
+		}
+		ctx := getContext()
+		due, err := kudos.ParseDate(args[len(args)-1])
+		if err != nil {
+			ctx.Error.Printf("could not parse due date: %v\n", err)
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		var hcode string
+		if len(args) == 4 {
+			hcode = args[1]
+		}
+		h := getHandin(ctx, asgn, hcode)
+		s := lookupStudent(ctx, args[len(args)-2])
+
+		if e, ok := ctx.DB.Extensions[asgn.Code][h.Code][s.student.UID]; ok {
+			ctx.Warn.Printf("warning: replacing previous extension (due %v)\n", e.Due.Format(kudos.DateFormat))
+		}
+		if !due.After(h.Due) {
+			ctx.Warn.Printf("warning: new due date is not after original due date (%v)\n", h.Due.Format(kudos.DateFormat))
+		}
+
+		ctx.DB.GrantExtension(asgn.Code, h.Code, s.student.UID, &kudos.Extension{
+			Due:        due,
+			GranterUID: cur.Uid,
+			Reason:     reasonFlag,
+			Time:       time.Now(),
+		})
+		ctx.DB.UpdateLateDays(ctx.Course, s.student.UID)
+		pub := ctx.DB.PubStudent(ctx.Course, s.student.UID)
+		commitDB(ctx)
+		publishStudents(ctx, []*kudos.PubStudent{pub})
+	}
+	cmdExtensionGrant.Run = f
+	addAllGlobalFlagsTo(cmdExtensionGrant.Flags())
+	cmdExtensionGrant.Flags().StringVarP(&reasonFlag, "reason", "", "", "the reason the extension was granted")
+	cmdExtension.AddCommand(cmdExtensionGrant)
+}
+
+var cmdExtensionRevoke = &cobra.Command{
+	Use:   "revoke <assignment> [<handin>] <student>",
+	Short: "Revoke a student's extension",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 && len(args) != 3 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		var hcode string
+		if len(args) == 3 {
+			hcode = args[1]
+		}
+		h := getHandin(ctx, asgn, hcode)
+		s := lookupStudent(ctx, args[len(args)-1])
+
+		if !ctx.DB.RevokeExtension(asgn.Code, h.Code, s.student.UID) {
+			ctx.Error.Println("extension does not exist")
+			exitLogic()
+		}
+		ctx.DB.UpdateLateDays(ctx.Course, s.student.UID)
+		pub := ctx.DB.PubStudent(ctx.Course, s.student.UID)
+		commitDB(ctx)
+		publishStudents(ctx, []*kudos.PubStudent{pub})
+	}
+	cmdExtensionRevoke.Run = f
+	addAllGlobalFlagsTo(cmdExtensionRevoke.Flags())
+	cmdExtension.AddCommand(cmdExtensionRevoke)
+}
+
+var cmdExtensionList = &cobra.Command{
+	Use:   "list",
+	Short: "List extensions",
+}
+
+func init() {
+	var studentFlag string
+	var assignmentFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var acodes []string
+		if cmd.Flag("assignment").Changed {
+			acodes = []string{getAssignment(ctx, assignmentFlag, false).Code}
+		} else {
+			for code := range ctx.DB.Extensions {
+				acodes = append(acodes, code)
+			}
+			sort.Strings(acodes)
+		}
+		var stud *student
+		if cmd.Flag("student").Changed {
+			stud = lookupStudent(ctx, studentFlag)
+		}
+
+		for _, acode := range acodes {
+			var hcodes []string
+			for hcode := range ctx.DB.Extensions[acode] {
+				hcodes = append(hcodes, hcode)
+			}
+			sort.Strings(hcodes)
+			for _, hcode := range hcodes {
+				var pairs unameUIDPairs
+				for uid := range ctx.DB.Extensions[acode][hcode] {
+					if stud == nil || uid == stud.student.UID {
+						pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+					}
+				}
+				sort.Sort(pairs)
+
+				name := acode
+				if hcode != "" {
+					name += " " + hcode
+				}
+				for _, pair := range pairs {
+					e := ctx.DB.Extensions[acode][hcode][pair.uid]
+					fmt.Printf("%v for %v: due %v (granted by %v on %v: %v)\n", name, pair.uname,
+						e.Due.Format(kudos.DateFormat), lookupUsernameForUID(ctx, e.GranterUID),
+						e.Time.Format(kudos.DateFormat), e.Reason)
+				}
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdExtensionList.Run = f
+	addAllGlobalFlagsTo(cmdExtensionList.Flags())
+	cmdExtensionList.Flags().StringVarP(&studentFlag, "student", "", "", "only list this student's extensions")
+	cmdExtensionList.Flags().StringVarP(&assignmentFlag, "assignment", "", "", "only list extensions for this assignment")
+	cmdExtension.AddCommand(cmdExtensionList)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdFinalGrades = &cobra.Command{
+	Use:   "final-grades",
+	Short: "Compute students' final grades",
+	Long: "Compute each student's final grade according to the grading scheme " +
+		"in the course config. Students who are missing grades (or who have " +
+		"incomplete grades) are flagged, and their provisional grade is computed " +
+		"using only their complete grades.",
+}
+
+func init() {
+	var studentFlag string
+	var showCategoriesFlag bool
+	var precisionFlag uint8
+
+	stripRegex := regexp.MustCompile(`\.?0*$`)
+	formatFloat := func(f float64) string {
+		s := fmt.Sprintf("%.*f", int(precisionFlag), f)
+		if precisionFlag == 0 {
+			return s
+		}
+		// truncate trailing 0s (and optionally, trailing period)
+		return stripRegex.ReplaceAllString(s, "")
+	}
+
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+		if ctx.Course.Grading == nil {
+			ctx.Error.Println("course has no grading scheme")
+			exitLogic()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		// FinalGrade reports these as incomplete
+		// for every student, so warn about them once
+		for _, cat := range ctx.Course.Grading.Categories {
+			for _, code := range cat.Assignments {
+				if _, ok := ctx.DB.Assignments[code]; !ok {
+					ctx.Warn.Printf("warning: assignment %v in grading scheme has not been added\n", code)
+				}
+			}
+		}
+
+		var pairs unameUIDPairs
+		if cmd.Flag("student").Changed {
+			s := lookupStudent(ctx, studentFlag)
+			pairs = unameUIDPairs{{lookupUsernameForUID(ctx, s.student.UID), s.student.UID}}
+		} else {
+			for uid := range ctx.DB.Students {
+				pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+			}
+			sort.Sort(pairs)
+		}
+
+		incomplete := 0
+		for _, pair := range pairs {
+			g, err := ctx.DB.FinalGrade(ctx.Course, pair.uid)
+			if err != nil {
+				ctx.Error.Printf("could not compute final grade: %v\n", err)
+				exitLogic()
+			}
+			grade := formatFloat(g.Percent) + "%"
+			if g.Letter != "" {
+				grade += " (" + g.Letter + ")"
+			}
+			if len(g.Excused) > 0 {
+				grade += fmt.Sprintf(" (excused from %v)", strings.Join(g.Excused, ", "))
+			}
+			if len(g.Incomplete) > 0 {
+				incomplete++
+				fmt.Printf("%v: INCOMPLETE (missing %v); provisional grade: %v\n", pair.uname,
+					strings.Join(g.Incomplete, ", "), grade)
+			} else {
+				fmt.Printf("%v: %v\n", pair.uname, grade)
+			}
+			if showCategoriesFlag {
+				for i, c := range g.Categories {
+					name := ctx.Course.Grading.Categories[i].Name
+					if name == "" {
+						name = c.Code
+					}
+					switch {
+					case c.Empty:
+						fmt.Printf("\t%v: no grades\n", name)
+					case len(c.Dropped) > 0:
+						fmt.Printf("\t%v: %v%% (dropped %v)\n", name, formatFloat(c.Percent), strings.Join(c.Dropped, ", "))
+					default:
+						fmt.Printf("\t%v: %v%%\n", name, formatFloat(c.Percent))
+					}
+				}
+			}
+		}
+		if incomplete > 0 {
+			ctx.Warn.Printf("warning: %v student(s) have incomplete grades\n", incomplete)
+		}
+
+		closeDB(ctx)
+	}
+	cmdFinalGrades.Run = f
+	addAllGlobalFlagsTo(cmdFinalGrades.Flags())
+	cmdFinalGrades.Flags().StringVarP(&studentFlag, "student", "", "", "only compute this student's final grade")
+	cmdFinalGrades.Flags().BoolVarP(&showCategoriesFlag, "show-categories", "", false, "show the grade for each category")
+	cmdFinalGrades.Flags().Uint8VarP(&precisionFlag, "precision", "", 2, "the maximum number of digits of precision to use when formatting floating point values")
+	cmdMain.AddCommand(cmdFinalGrades)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"os/user"
-	"strconv"
 
 	"github.com/joshlf/kudos/lib/dev"
 	"github.com/joshlf/kudos/lib/kudos"
@@ -14,13 +13,17 @@ import (
 var cmdGrade = &cobra.Command{
 	Use:   "grade <assignment> <problem> <student> [<grade>]",
 	Short: "Assign a grade to a student on a problem",
-	// TODO(joshlf): long description
+	Long: "Assign a grade to a student on a problem. For group assignments, " +
+		"--group grades a whole group: the third argument is a group code, and the " +
+		"grade is given to each of the group's members except those who have been " +
+		"given an individual grade for the problem, which overrides the group's.",
 }
 
 func init() {
 	var commentFlag string
 	var deleteFlag bool
 	var forceFlag bool
+	var groupFlag bool
 	f := func(cmd *cobra.Command, args []string) {
 		switch {
 		case len(args) != 3 && len(args) != 4:
@@ -46,17 +49,6 @@ func init() {
 			exitUsage()
 		}
 		student := args[2]
-
-		// only used if --delete is not specified
-		var grade float64
-		if !deleteFlag {
-			var err error
-			grade, err = strconv.ParseFloat(args[3], 64)
-			if err != nil {
-				ctx.Error.Printf("could not parse grade %q: %v\n", args[3], err)
-				exitUsage()
-			}
-		}
 		addCourseConfig(ctx)
 
 		openDB(ctx)
@@ -69,102 +61,367 @@ func init() {
 			ctx.Error.Printf("assignment %v has no problem with the code %v\n", acode, pcode)
 			exitLogic()
 		}
-		u := lookupStudent(ctx, student)
-
-		if deleteFlag {
-			if _, ok := ctx.DB.Grades[acode][u.usr.Uid]; !ok {
-				ctx.Error.Println("grade does not exist")
+		// exactly one of uid and group is set
+		var uid string
+		var group *kudos.Group
+		if groupFlag {
+			if !asgn.Groups {
+				ctx.Error.Printf("assignment %v is not a group assignment\n", acode)
 				exitLogic()
 			}
-			gradesMap := ctx.DB.Grades[acode][u.usr.Uid].Grades
-			if _, ok := gradesMap[pcode]; !ok {
-				ctx.Error.Println("grade does not exist")
+			group, ok = ctx.DB.Groups[acode][student]
+			if !ok {
+				ctx.Error.Printf("no such group: %v\n", student)
 				exitLogic()
 			}
-			delete(gradesMap, pcode)
 		} else {
-			cur, err := user.Current()
+			uid = lookupStudent(ctx, student).usr.Uid
+		}
+
+		// only used if --delete is not specified
+		var grade kudos.Decimal
+		var symbol string
+		if !deleteFlag {
+			var err error
+			grade, symbol, err = prob.ParseGrade(args[3])
 			if err != nil {
-				ctx.Error.Printf("could not get current user: %v\n", err)
-				dev.Fail()
-			}
-
-			// create this student's assignment grade
-			// if it doesn't already exist
-			if _, ok := ctx.DB.Grades[acode][u.usr.Uid]; !ok {
-				ctx.DB.Grades[acode][u.usr.Uid] = &kudos.AssignmentGrade{
-					make(map[string]kudos.ProblemGrade)}
-			}
-			gradesMap := ctx.DB.Grades[acode][u.usr.Uid].Grades
-
-			// we don't need to worry about the "ok" return value;
-			// we've already made sure the problem exists
-			path, _ := asgn.FindProblemPathByCode(pcode)
-
-			// it doesn't matter what order we traverse the path
-			// in because (assuming the database is valid), at
-			// most one parent can have a grade assigned to it
-			// (if more than one did, that would constitute the
-			// same error we're looking for here, and we assume
-			// that the database is valid)
-			for _, elem := range path {
-				if _, ok := gradesMap[elem]; ok {
-					ctx.Error.Printf("grade already assigned to parent problem %v\n", elem)
-					exitLogic()
-				}
+				ctx.Error.Printf("could not parse grade %q: %v\n", args[3], err)
+				exitUsage()
+			}
+			if err := ctx.Course.ValidateGrade(grade); err != nil {
+				ctx.Error.Println(err)
+				exitUsage()
 			}
+		}
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+		editor := kudos.GradeEditor{UID: cur.Uid, Command: cmd.CommandPath()}
+
+		// the students whose grades may change
+		var uids []string
+		if groupFlag {
+			uids = group.MemberUIDs
+		} else {
+			uids = []string{uid}
+		}
 
-			if _, ok := gradesMap[pcode]; ok && !forceFlag {
-				ctx.Error.Println("grade already assigned; use --force to overwrite")
+		if deleteFlag {
+			var ok bool
+			if groupFlag {
+				ok = ctx.DB.DeleteGroupGrade(asgn, group.Code, pcode, editor)
+			} else {
+				ok = ctx.DB.DeleteGrade(asgn, uid, pcode, editor)
+			}
+			if !ok {
+				ctx.Error.Println("grade does not exist")
 				exitLogic()
 			}
-
-			// define walkFn even if we don't use it (because --force
-			// was passed) since we ust it later
-			var walkFn func(p kudos.Problem)
-			walkFn = func(p kudos.Problem) {
-				for _, pp := range p.Subproblems {
-					if _, ok := gradesMap[pp.Code]; ok {
-						ctx.Error.Printf("grade already assigned to subproblem %v; use --force to overwrite all subproblem grades\n", pp.Code)
-						exitLogic()
-					}
-					walkFn(pp)
+		} else {
+			g := kudos.ProblemGrade{
+				Grade:  grade,
+				Symbol: symbol,
+				// the zero value of commentFlag is the empty
+				// string, so we can just blindly use it
+				Comment:   commentFlag,
+				GraderUID: cur.Uid,
+			}
+			if groupFlag {
+				var overrides []string
+				overrides, err = ctx.DB.SetGroupGrade(asgn, group.Code, pcode, g, forceFlag, editor)
+				for _, o := range overrides {
+					ctx.Warn.Printf("warning: %v has an individual grade for this problem, which overrides the group's grade\n",
+						lookupUsernameForUID(ctx, o))
 				}
+			} else {
+				err = ctx.DB.SetGrade(asgn, uid, pcode, g, forceFlag, editor)
 			}
-			if !forceFlag {
-				walkFn(prob)
+			if err != nil {
+				if _, ok := err.(*kudos.SubproblemGradedError); ok {
+					ctx.Error.Printf("%v; use --force to overwrite all subproblem grades\n", err)
+				} else if err == kudos.ErrGradeExists {
+					ctx.Error.Printf("%v; use --force to overwrite\n", err)
+				} else {
+					ctx.Error.Println(err)
+				}
+				exitLogic()
 			}
 
-			if grade > prob.Points {
+			if grade.Cmp(prob.Points) > 0 {
 				ctx.Warn.Printf("warning: grade is higher than the maximum for this problem (%v points)\n", prob.Points)
 			}
-
 			if forceFlag {
 				ctx.Warn.Println("warning: overwriting any previous grades for this problem or subproblems")
-				walkFn = func(p kudos.Problem) {
-					delete(gradesMap, p.Code)
-					for _, pp := range p.Subproblems {
-						walkFn(pp)
-					}
-				}
-				walkFn(prob)
-			}
-
-			gradesMap[pcode] = kudos.ProblemGrade{
-				Grade: grade,
-				// the zero value of commentFlag is the empty
-				// string, so we can just blindly use it
-				Comment:   commentFlag,
-				GraderUID: cur.Uid,
 			}
 		}
 
+		pubs := releasedPubs(ctx, acode, uids...)
 		commitDB(ctx)
+		publishStudents(ctx, pubs)
 	}
 	cmdGrade.Run = f
 	addAllGlobalFlagsTo(cmdGrade.Flags())
 	cmdGrade.Flags().StringVarP(&commentFlag, "comment", "", "", "the comment associated with this grade")
 	cmdGrade.Flags().BoolVarP(&deleteFlag, "delete", "", false, "delete a grade")
 	cmdGrade.Flags().BoolVarP(&forceFlag, "force", "f", false, "overwrite previous grade or grades of subproblems")
+	cmdGrade.Flags().BoolVarP(&groupFlag, "group", "", false, "grade a group (given in place of the student) on a group assignment")
 	cmdMain.AddCommand(cmdGrade)
 }
+
+var cmdGradeHistory = &cobra.Command{
+	Use:   "history",
+	Short: "Show the history of changes to a student's grades",
+}
+
+func init() {
+	var studentFlag string
+	var assignmentFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		switch {
+		case len(args) != 0:
+			cmd.Usage()
+			exitUsage()
+		case !cmd.Flag("student").Changed:
+			fmt.Fprintln(os.Stderr, "must specify --student")
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var acode string
+		if cmd.Flag("assignment").Changed {
+			if err := kudos.ValidateCode(assignmentFlag); err != nil {
+				ctx.Error.Printf("bad assignment code %q: %v\n", assignmentFlag, err)
+				exitUsage()
+			}
+			// don't use getAssignment since the history
+			// is kept even for deleted assignments
+			acode = assignmentFlag
+		}
+		// the history is kept even for students who
+		// have been removed from the course, so don't
+		// require the student to be in the database
+		uid := studentFlag
+		if usr, err := findUser(studentFlag); err == nil {
+			uid = usr.Uid
+		} else if !isNumeric(studentFlag) {
+			// a UID may belong to a user who no
+			// longer exists, but a username can't
+			ctx.Error.Println(err)
+			dev.Fail()
+		}
+
+		formatGrade := func(g *kudos.ProblemGrade) string {
+			if g == nil {
+				return "none"
+			}
+			if g.Symbol != "" {
+				return fmt.Sprintf("%v (%v)", g.Symbol, g.Grade)
+			}
+			return fmt.Sprint(g.Grade)
+		}
+		comment := func(g *kudos.ProblemGrade) string {
+			if g == nil {
+				return ""
+			}
+			return g.Comment
+		}
+
+		// maps uids to usernames
+		editorUnames := make(map[string]string)
+		for _, c := range ctx.DB.GradeHistoryFor(uid, acode) {
+			uname, ok := editorUnames[c.EditorUID]
+			if !ok {
+				uname = lookupUsernameForUID(ctx, c.EditorUID)
+				editorUnames[c.EditorUID] = uname
+			}
+			fmt.Printf("%v: %v %v: %v -> %v by %v (%v)\n", c.Time.Format(kudos.DateFormat), c.Assignment,
+				c.Problem, formatGrade(c.Old), formatGrade(c.New), uname, c.Command)
+			if comment(c.Old) != comment(c.New) {
+				fmt.Printf("\told comment: %q\n\tnew comment: %q\n", comment(c.Old), comment(c.New))
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdGradeHistory.Run = f
+	addAllGlobalFlagsTo(cmdGradeHistory.Flags())
+	cmdGradeHistory.Flags().StringVarP(&studentFlag, "student", "", "", "the student whose grade history to show")
+	cmdGradeHistory.Flags().StringVarP(&assignmentFlag, "assignment", "", "", "only show changes to this assignment's grades")
+	cmdGrade.AddCommand(cmdGradeHistory)
+}
+
+var cmdGradeImport = &cobra.Command{
+	Use:   "import <assignment> <file>",
+	Short: "Import grades from a CSV file",
+	Long: "Import grades for an assignment from a CSV file. The first row must be a " +
+		"header. The column headed \"student\" gives each row's student (by username " +
+		"or UID), and every other column is headed by either a problem code (holding " +
+		"grades for that problem) or <problem>:comment (holding comments for that " +
+		"problem's grades). Empty cells are ignored. Either all of the grades are " +
+		"imported or, if any of them cannot be, none are. For group assignments, " +
+		"each row's grades are given to its student's whole group (see --group), " +
+		"unless the student has an individual grade for the problem, so each group " +
+		"may only appear once.",
+}
+
+func init() {
+	var dryRunFlag bool
+	var forceFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		acode := args[0]
+		if err := kudos.ValidateCode(acode); err != nil {
+			ctx.Error.Printf("bad assignment code %q: %v\n", acode, err)
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, acode, false)
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			ctx.Error.Printf("could not open grades file: %v\n", err)
+			exitLogic()
+		}
+		rows, err := kudos.ParseGradeCSV(file, asgn)
+		file.Close()
+		if err != nil {
+			ctx.Error.Printf("could not parse grades file: %v\n", err)
+			exitLogic()
+		}
+
+		editor := kudos.GradeEditor{UID: cur.Uid, Command: cmd.CommandPath()}
+		// used to compute the changes made by the import
+		historyStart := len(ctx.DB.GradeHistory)
+		// maps handin owners (see kudos.DB.HandinOwners)
+		// to the lines on which they appeared; since
+		// grades for a group assignment are given to
+		// whole groups, each group may only appear once
+		lines := make(map[string]int)
+		// the students whose grades may change
+		var uids []string
+		seenUIDs := make(map[string]bool)
+		failed := 0
+		for _, row := range rows {
+			s, err := findStudent(ctx, row.Student)
+			if err != nil {
+				ctx.Error.Printf("line %v: %v\n", row.Line, err)
+				failed++
+				continue
+			}
+			uid := s.usr.Uid
+			owner := uid
+			if g, ok := ctx.DB.StudentGroup(asgn.Code, uid); ok && asgn.Groups {
+				owner = g.Code
+			}
+			if line, ok := lines[owner]; ok {
+				if owner != uid {
+					ctx.Error.Printf("line %v: student %v is in group %v, which already appeared on line %v\n",
+						row.Line, s, owner, line)
+				} else {
+					ctx.Error.Printf("line %v: student %v already appeared on line %v\n", row.Line, s, line)
+				}
+				failed++
+				continue
+			}
+			lines[owner] = row.Line
+
+			for _, g := range row.Grades {
+				if err := ctx.Course.ValidateGrade(g.Grade); err != nil {
+					ctx.Error.Printf("line %v: problem %v: %v\n", row.Line, g.Problem, err)
+					failed++
+					continue
+				}
+				changed, overrides, err := ctx.DB.SetHandinGrade(asgn, uid, g.Problem, kudos.ProblemGrade{
+					Grade:     g.Grade,
+					Symbol:    g.Symbol,
+					Comment:   g.Comment,
+					GraderUID: cur.Uid,
+				}, forceFlag, editor)
+				if err != nil {
+					if _, ok := err.(*kudos.SubproblemGradedError); ok {
+						err = fmt.Errorf("%v; use --force to overwrite all subproblem grades", err)
+					} else if err == kudos.ErrGradeExists {
+						err = fmt.Errorf("%v; use --force to overwrite", err)
+					}
+					ctx.Error.Printf("line %v: problem %v: %v\n", row.Line, g.Problem, err)
+					failed++
+					continue
+				}
+				for _, u := range changed {
+					if !seenUIDs[u] {
+						seenUIDs[u] = true
+						uids = append(uids, u)
+					}
+				}
+				for _, o := range overrides {
+					ctx.Warn.Printf("warning: line %v: %v has an individual grade for problem %v, which overrides the group's grade\n",
+						row.Line, lookupUsernameForUID(ctx, o), g.Problem)
+				}
+				prob, _ := asgn.FindProblemByCode(g.Problem)
+				if g.Grade.Cmp(prob.Points) > 0 {
+					ctx.Warn.Printf("warning: line %v: grade for problem %v is higher than the maximum (%v points)\n",
+						row.Line, g.Problem, prob.Points)
+				}
+			}
+		}
+		if failed > 0 {
+			ctx.Error.Printf("found %v error(s); aborting (no changes saved)\n", failed)
+			closeDB(ctx)
+			exitLogic()
+		}
+
+		changes := ctx.DB.GradeHistory[historyStart:]
+		if dryRunFlag {
+			formatGrade := func(g *kudos.ProblemGrade) string {
+				if g == nil {
+					return "none"
+				}
+				if g.Comment != "" {
+					return fmt.Sprintf("%v (comment: %q)", g.Grade, g.Comment)
+				}
+				return fmt.Sprint(g.Grade)
+			}
+			for _, c := range changes {
+				fmt.Printf("%v %v: %v -> %v\n", lookupUsernameForUID(ctx, c.StudentUID), c.Problem,
+					formatGrade(c.Old), formatGrade(c.New))
+			}
+			ctx.Info.Printf("dry run: would change %v grade(s) for %v student(s)\n", len(changes), len(uids))
+			closeDB(ctx)
+			return
+		}
+
+		if forceFlag {
+			ctx.Warn.Println("warning: overwriting any previous grades for imported problems or subproblems")
+		}
+		pubs := releasedPubs(ctx, asgn.Code, uids...)
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+		ctx.Info.Printf("changed %v grade(s) for %v student(s)\n", len(changes), len(uids))
+	}
+	cmdGradeImport.Run = f
+	addAllGlobalFlagsTo(cmdGradeImport.Flags())
+	cmdGradeImport.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false, "show the changes which would be made without making them")
+	cmdGradeImport.Flags().BoolVarP(&forceFlag, "force", "f", false, "overwrite previous grades or grades of subproblems")
+	cmdGrade.AddCommand(cmdGradeImport)
+}
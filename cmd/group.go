@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdGroup = &cobra.Command{
+	Use:   "group",
+	Short: "Manage groups for group assignments",
+	Long: "Students work on group assignments (those whose config sets \"groups\" " +
+		"to true) in groups. Each group shares a single handin, and grades given to " +
+		"a group (with grade --group) apply to each of its members unless a member " +
+		"has been given an individual grade for the same problem. Groups must be " +
+		"formed before the assignment's handins are initialized with handin init.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		exitUsage()
+	}
+	cmdGroup.Run = f
+	addAllGlobalFlagsTo(cmdGroup.Flags())
+	cmdMain.AddCommand(cmdGroup)
+}
+
+// Looks up the group assignment with the given code
+// and checks that its groups can still be changed.
+// If either check fails, an error is logged and
+// the process exits.
+func getGroupAssignment(ctx *kudos.Context, acode string) *kudos.Assignment {
+	asgn := getAssignment(ctx, acode, false)
+	if !asgn.Groups {
+		ctx.Error.Printf("assignment %v is not a group assignment\n", acode)
+		exitLogic()
+	}
+	// handin directories are created with
+	// ACLs for each group's members, so
+	// they can't reflect later changes
+	_, err := os.Stat(ctx.AssignmentHandinDir(acode))
+	switch {
+	case err == nil:
+		ctx.Error.Println("handins have already been initialized; groups can no longer be changed")
+		exitLogic()
+	case !os.IsNotExist(err):
+		ctx.Error.Printf("could not stat handin directory: %v\n", err)
+		dev.Fail()
+	}
+	return asgn
+}
+
+// Adds the given students to the given group (or,
+// if remove is true, removes them from it), commits
+// the database, and publishes the change to the
+// students and the group's members. If an error is
+// encountered, it is logged and the process exits.
+func changeGroupMembers(ctx *kudos.Context, cmd *cobra.Command, asgn *kudos.Assignment, gcode string, students []string, remove bool) {
+	cur, err := user.Current()
+	if err != nil {
+		ctx.Error.Printf("could not get current user: %v\n", err)
+		dev.Fail()
+	}
+	editor := kudos.GradeEditor{UID: cur.Uid, Command: cmd.CommandPath()}
+
+	var uids []string
+	for _, arg := range students {
+		s := lookupStudent(ctx, arg)
+		if remove {
+			err = ctx.DB.RemoveGroupMember(asgn, gcode, s.usr.Uid, editor)
+		} else {
+			err = ctx.DB.AddGroupMember(asgn, gcode, s.usr.Uid, editor)
+		}
+		if err != nil {
+			verb := "add"
+			if remove {
+				verb = "remove"
+			}
+			ctx.Error.Printf("could not %v %v: %v\n", verb, s, err)
+			exitLogic()
+		}
+		uids = append(uids, s.usr.Uid)
+	}
+	if remove {
+		uids = append(uids, ctx.DB.Groups[asgn.Code][gcode].MemberUIDs...)
+	} else {
+		uids = ctx.DB.Groups[asgn.Code][gcode].MemberUIDs
+	}
+	var pubs []*kudos.PubStudent
+	for _, uid := range uids {
+		pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+	}
+	commitDB(ctx)
+	publishStudents(ctx, pubs)
+}
+
+var cmdGroupCreate = &cobra.Command{
+	Use:   "create <assignment> <group> [<student> ...]",
+	Short: "Create a group, optionally with members",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		gcode := args[1]
+		if err := kudos.ValidateCode(gcode); err != nil {
+			ctx.Error.Printf("bad group code %q: %v\n", gcode, err)
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getGroupAssignment(ctx, args[0])
+		if err := ctx.DB.CreateGroup(asgn, gcode); err != nil {
+			ctx.Error.Println(err)
+			exitLogic()
+		}
+		changeGroupMembers(ctx, cmd, asgn, gcode, args[2:], false)
+	}
+	cmdGroupCreate.Run = f
+	addAllGlobalFlagsTo(cmdGroupCreate.Flags())
+	cmdGroup.AddCommand(cmdGroupCreate)
+}
+
+var cmdGroupAdd = &cobra.Command{
+	Use:   "add <assignment> <group> <student> [...]",
+	Short: "Add students to a group",
+	Long: "Add students to a group. The new members are given the grades already " +
+		"given to the group, except for problems on which they have individual grades.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) < 3 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getGroupAssignment(ctx, args[0])
+		if _, ok := ctx.DB.Groups[asgn.Code][args[1]]; !ok {
+			ctx.Error.Printf("no such group: %v\n", args[1])
+			exitLogic()
+		}
+		changeGroupMembers(ctx, cmd, asgn, args[1], args[2:], false)
+	}
+	cmdGroupAdd.Run = f
+	addAllGlobalFlagsTo(cmdGroupAdd.Flags())
+	cmdGroup.AddCommand(cmdGroupAdd)
+}
+
+var cmdGroupRemove = &cobra.Command{
+	Use:   "remove <assignment> <group> <student> [...]",
+	Short: "Remove students from a group",
+	Long: "Remove students from a group. The grades given to the students as members " +
+		"of the group are deleted; their individual grades are kept.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) < 3 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getGroupAssignment(ctx, args[0])
+		if _, ok := ctx.DB.Groups[asgn.Code][args[1]]; !ok {
+			ctx.Error.Printf("no such group: %v\n", args[1])
+			exitLogic()
+		}
+		changeGroupMembers(ctx, cmd, asgn, args[1], args[2:], true)
+	}
+	cmdGroupRemove.Run = f
+	addAllGlobalFlagsTo(cmdGroupRemove.Flags())
+	cmdGroup.AddCommand(cmdGroupRemove)
+}
+
+var cmdGroupList = &cobra.Command{
+	Use:   "list <assignment>",
+	Short: "List an assignment's groups and their members",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		if !asgn.Groups {
+			ctx.Error.Printf("assignment %v is not a group assignment\n", asgn.Code)
+			exitLogic()
+		}
+
+		for _, g := range ctx.DB.SortedGroups(asgn.Code) {
+			var unames []string
+			for _, uid := range g.MemberUIDs {
+				unames = append(unames, lookupUsernameForUID(ctx, uid))
+			}
+			fmt.Printf("%v: %v\n", g.Code, strings.Join(unames, ", "))
+		}
+
+		var uids []string
+		for uid, s := range ctx.DB.Students {
+			if _, ok := ctx.DB.StudentGroup(asgn.Code, uid); !ok && !s.Dropped() {
+				uids = append(uids, uid)
+			}
+		}
+		// sort so that the order of any warning
+		// messages from lookupUsernameForUID is
+		// consistent
+		sort.Strings(uids)
+		var ungrouped []string
+		for _, uid := range uids {
+			ungrouped = append(ungrouped, lookupUsernameForUID(ctx, uid))
+		}
+		sort.Strings(ungrouped)
+		if len(ungrouped) > 0 {
+			fmt.Printf("not in a group: %v\n", strings.Join(ungrouped, ", "))
+		}
+
+		closeDB(ctx)
+	}
+	cmdGroupList.Run = f
+	addAllGlobalFlagsTo(cmdGroupList.Flags())
+	cmdGroup.AddCommand(cmdGroupList)
+}
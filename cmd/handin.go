@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"os/user"
 	"sort"
+	"time"
 
 	"github.com/joshlf/kudos/lib/dev"
 	"github.com/joshlf/kudos/lib/handin"
@@ -22,14 +23,49 @@ var cmdHandin = &cobra.Command{
 	// output.
 	Use:   "handin <assignment> [<handin>]",
 	Short: "Hand in an assignment",
+	Long: "Hand in the current directory. Files matching the gitignore-style patterns " +
+		"in the course's ignore file (.kudos/hooks/kudosignore) or in the assignment's " +
+		"ignore file (.kudos/hooks/<assignment>.kudosignore) are not handed in; patterns " +
+		"in the assignment's file take precedence. Special files and symlinks which " +
+		"point outside of the current directory cannot be handed in. If the handin " +
+		"is missing files which the assignment requires, contains files which it " +
+		"forbids, or exceeds its limits on size or number of files, nothing is " +
+		"handed in, and the problems are listed. Use --dry-run to check a handin " +
+		"without handing it in.",
 }
 
 func init() {
+	var dryRunFlag bool
 	f := func(cmd *cobra.Command, args []string) {
 		ctx := getContext()
 		addCourseConfig(ctx)
 
+		u, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+		// used to display due dates which take
+		// extensions into account
+		pub, err := ctx.ReadPubStudent(u.Uid)
+		if err != nil {
+			ctx.Debug.Printf("could not read published information: %v\n", err)
+			pub = &kudos.PubStudent{UID: u.Uid}
+		}
+
+		// the name of the student's handin, which
+		// is their group's code for group assignments
+		// (see DB.HandinOwners)
+		handinName := func(acode string) string {
+			if g, ok := pub.Groups[acode]; ok {
+				return g
+			}
+			return u.Uid
+		}
+
 		var handinFile string
+		var due time.Time
+		var h kudos.Handin
 		switch len(args) {
 		case 0:
 			ctx.Info.Printf("Usage: %v\n\n", cmd.Use)
@@ -41,7 +77,7 @@ func init() {
 			ctx.Info.Println("Available handins:")
 			for _, a := range asgns {
 				if len(a.Handins) == 1 {
-					ctx.Info.Printf("  %v\n", a.Code)
+					ctx.Info.Printf("  %v (due %v)\n", a.Code, pub.DueDate(a, a.Handins[0]).Format(kudos.DateFormat))
 				} else {
 					// TODO(joshlf): maybe change the output
 					// format? This works for now, but we
@@ -52,6 +88,9 @@ func init() {
 						ctx.Info.Printf("%v | ", hh.Code)
 					}
 					ctx.Info.Printf("%v]\n", h[len(h)-1].Code)
+					for _, hh := range h {
+						ctx.Info.Printf("    %v: due %v\n", hh.Code, pub.DueDate(a, hh).Format(kudos.DateFormat))
+					}
 				}
 			}
 			exitClean()
@@ -72,12 +111,9 @@ func init() {
 				ctx.Error.Printf("assignment has multiple handins; please specify one\n")
 				exitUsage()
 			}
-			u, err := user.Current()
-			if err != nil {
-				ctx.Error.Printf("could not get current user: %v\n", err)
-				dev.Fail()
-			}
-			handinFile = ctx.UserAssignmentHandinFile(args[0], u.Uid)
+			h = a.Handins[0]
+			handinFile = ctx.UserAssignmentHandinFile(args[0], handinName(args[0]))
+			due = pub.DueDate(a, h)
 		case 2:
 			asgns, err := kudos.ParseAllAssignmentFiles(ctx)
 			if err != nil {
@@ -89,17 +125,13 @@ func init() {
 				ctx.Error.Printf("no such assignment: %v\n", args[0])
 				exitLogic()
 			}
-			_, ok = a.FindHandinByCode(args[1])
+			h, ok = a.FindHandinByCode(args[1])
 			if !ok {
 				ctx.Error.Printf("no such handin: %v\n", args[1])
 				exitLogic()
 			}
-			u, err := user.Current()
-			if err != nil {
-				ctx.Error.Printf("could not get current user: %v\n", err)
-				dev.Fail()
-			}
-			handinFile = ctx.UserHandinHandinFile(args[0], args[1], u.Uid)
+			handinFile = ctx.UserHandinHandinFile(args[0], args[1], handinName(args[0]))
+			due = pub.DueDate(a, h)
 		default:
 			cmd.Usage()
 			exitUsage()
@@ -111,7 +143,7 @@ func init() {
 
 		hook := ctx.PreHandinHookFile()
 		doHook := true
-		_, err := os.Stat(hook)
+		_, err = os.Stat(hook)
 		if err != nil {
 			if os.IsNotExist(err) {
 				doHook = false
@@ -138,19 +170,63 @@ func init() {
 			}
 		}
 
+		ignore, err := handin.ReadIgnoreFiles(ctx.CourseIgnoreFile(), ctx.AssignmentIgnoreFile(args[0]))
+		if err != nil {
+			ctx.Error.Printf("could not read ignore file: %v\n", err)
+			dev.Fail()
+		}
+
+		limits := handin.Limits{
+			MaxSize:     h.MaxSize,
+			MaxFileSize: h.MaxFileSize,
+			MaxFiles:    h.MaxFiles,
+		}
+
+		reqs := handin.Requirements{Required: h.Required, Forbidden: h.Forbidden}
+
+		if dryRunFlag {
+			files, err := handin.CollectHandin(ignore)
+			if err != nil {
+				ctx.Error.Printf("could not hand in: %v\n", err)
+				exitLogic()
+			}
+			var size int64
+			var count int
+			for _, f := range files {
+				if f.Dir {
+					fmt.Printf("%8v  %v\n", "-", f.Name)
+					continue
+				}
+				fmt.Printf("%8v  %v\n", handin.FormatSize(f.Size), f.Name)
+				size += f.Size
+				count++
+			}
+			fmt.Printf("%v file(s), %v total\n", count, handin.FormatSize(size))
+			if err = handin.CheckHandin(files, reqs, limits); err != nil {
+				ctx.Error.Printf("could not hand in: %v\n", err)
+				exitLogic()
+			}
+			ctx.Info.Println("Dry run; nothing was handed in.")
+			exitClean()
+		}
+
 		printFiles := ctx.Logger.GetLevel() <= log.Info
 		if printFiles {
 			ctx.Info.Println("Handing in the following files:")
 		}
-		err = handin.PerformFaclHandin(handinFile, printFiles)
+		err = handin.PerformFaclHandin(handinFile, ignore, reqs, limits, printFiles)
 		if err != nil {
 			ctx.Error.Printf("could not hand in: %v\n", err)
-			dev.Fail()
+			exitLogic()
 		}
 		ctx.Info.Println("Handin successful.")
+		if time.Now().After(due) {
+			ctx.Warn.Printf("warning: handin is late (due %v)\n", due.Format(kudos.DateFormat))
+		}
 	}
 	cmdHandin.Run = f
 	addAllGlobalFlagsTo(cmdHandin.Flags())
+	cmdHandin.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false, "list the files which would be handed in and their sizes, but do not hand in")
 	cmdMain.AddCommand(cmdHandin)
 }
 
@@ -160,6 +236,7 @@ var cmdHandinInit = &cobra.Command{
 }
 
 func init() {
+	var includeDroppedFlag bool
 	f := func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			cmd.Usage()
@@ -179,8 +256,14 @@ func init() {
 		defer cleanupDB(ctx)
 		var uids []string
 		for _, s := range ctx.DB.Students {
+			if s.Dropped() && !includeDroppedFlag {
+				continue
+			}
 			uids = append(uids, s.UID)
 		}
+		// for group assignments, each group
+		// shares a single handin directory
+		owners := ctx.DB.HandinOwners(asgn, uids)
 		closeDB(ctx)
 
 		// If there is a single handin, initialize the handin
@@ -189,7 +272,7 @@ func init() {
 		// one at a time.
 		if len(asgn.Handins) == 1 {
 			dir := ctx.AssignmentHandinDir(asgn.Code)
-			err := handin.InitFaclHandin(dir, uids)
+			err := handin.InitFaclGroupHandin(dir, owners)
 			if err != nil {
 				ctx.Error.Printf("initialization failed: %v", err)
 				dev.Fail()
@@ -213,7 +296,7 @@ func init() {
 			}
 			for _, h := range asgn.Handins {
 				dir := ctx.HandinHandinDir(asgn.Code, h.Code)
-				err := handin.InitFaclHandin(dir, uids)
+				err := handin.InitFaclGroupHandin(dir, owners)
 				if err != nil {
 					ctx.Error.Printf("could not initialize handin %v: %v", h.Code, err)
 					dev.Fail()
@@ -223,6 +306,7 @@ func init() {
 	}
 	cmdHandinInit.Run = f
 	addAllGlobalFlagsTo(cmdHandinInit.Flags())
+	cmdHandinInit.Flags().BoolVarP(&includeDroppedFlag, "include-dropped", "", false, "create handin directories for students who have dropped the course")
 	cmdHandin.AddCommand(cmdHandinInit)
 }
 
@@ -236,6 +320,7 @@ func init() {
 	var studentFlag string
 	var allHandinsFlag bool
 	var forceFlag bool
+	var includeDroppedFlag bool
 	f := func(cmd *cobra.Command, args []string) {
 		switch {
 		case len(args) < 1 || len(args) > 2:
@@ -289,6 +374,9 @@ func init() {
 			students = []*student{lookupStudent(ctx, studentFlag)}
 		} else {
 			for _, s := range ctx.DB.Students {
+				if s.Dropped() && !includeDroppedFlag {
+					continue
+				}
 				ss := &student{
 					student: s,
 					str:     lookupUsernameForUID(ctx, s.UID),
@@ -298,6 +386,29 @@ func init() {
 			sort.Sort(sortableStudents(students))
 		}
 
+		// the handins to ingest; for group assignments,
+		// the members of each group share a single handin
+		type owner struct {
+			// the name of the handin (see DB.HandinOwners)
+			name string
+			// used in log messages
+			str  string
+			uids []string
+		}
+		var owners []owner
+		seenGroups := make(map[string]bool)
+		for _, s := range students {
+			o := owner{s.student.UID, s.str, []string{s.student.UID}}
+			if g, ok := ctx.DB.StudentGroup(asgn.Code, s.student.UID); ok && asgn.Groups {
+				if seenGroups[g.Code] {
+					continue
+				}
+				seenGroups[g.Code] = true
+				o = owner{g.Code, "group " + g.Code, g.MemberUIDs}
+			}
+			owners = append(owners, o)
+		}
+
 		// these will be executed after the database
 		// changes have been successfully committed
 		var postCommitFuncs []func()
@@ -316,13 +427,13 @@ func init() {
 				continue
 			}
 
-			for _, s := range students {
+			for _, o := range owners {
 				// use !oneStudent instead of len(students) > 1
 				// since there could actually be only one student
 				// in the class, but the --student flag was not
 				// given
 				if !oneStudent {
-					ctx.Verbose.Printf("\t%v\n", s)
+					ctx.Verbose.Printf("\t%v\n", o.str)
 				}
 
 				// logPrefix is of one of the following forms:
@@ -330,19 +441,20 @@ func init() {
 				//  handin first
 				//  handin for bob
 				//  handin first for bob
+				//  handin for group team1
 				logPrefix := fmt.Sprintf("handin %v", h.handin.Code)
 				if len(handins) == 1 {
 					logPrefix = "handin"
 				}
 				if !oneStudent {
-					logPrefix = fmt.Sprintf("%v for %v", logPrefix, s)
+					logPrefix = fmt.Sprintf("%v for %v", logPrefix, o.str)
 				}
 
 				hcode := h.handin.Code
 				if len(asgn.Handins) == 1 {
 					hcode = ""
 				}
-				if _, ok := ctx.DB.Handins[asgn.Code][hcode][s.student.UID]; ok {
+				if _, ok := ctx.DB.Handins[asgn.Code][hcode][o.uids[0]]; ok {
 					if forceFlag {
 						ctx.Warn.Printf("warning: %v already ingested; overwriting\n", logPrefix)
 					} else {
@@ -351,7 +463,7 @@ func init() {
 					}
 				}
 
-				ok, err := handin.HandedIn(h.handinDir, s.student.UID)
+				ok, err := handin.HandedIn(h.handinDir, o.name)
 				if err != nil {
 					ctx.Error.Printf("could not save %v: %v; skipping\n", logPrefix, err)
 					exitErr = true
@@ -362,14 +474,26 @@ func init() {
 					continue
 				}
 
-				t, err := handin.HandinTime(h.handinDir, s.student.UID)
+				t, err := handin.HandinTime(h.handinDir, o.name)
 				if err != nil {
 					ctx.Error.Printf("could not get handin time for %v: %v; skipping\n", logPrefix, err)
 					exitErr = true
 					continue
 				}
 
-				ctx.DB.Handins[asgn.Code][hcode][s.student.UID] = t
+				// every member of a group is recorded as
+				// having handed in at the same time, but
+				// they may have different due dates
+				for _, uid := range o.uids {
+					ctx.DB.Handins[asgn.Code][hcode][uid] = t
+					if due := ctx.DB.DueDate(asgn, h.handin, uid); t.After(due) {
+						prefix := logPrefix
+						if len(o.uids) > 1 {
+							prefix = fmt.Sprintf("%v (member %v)", logPrefix, lookupUsernameForUID(ctx, uid))
+						}
+						ctx.Warn.Printf("warning: %v is late by %v (due %v)\n", prefix, t.Sub(due), due.Format(kudos.DateFormat))
+					}
+				}
 				changed = true
 
 				// make sure that all variables used
@@ -380,9 +504,9 @@ func init() {
 				// environment)
 				handinDir := h.handinDir
 				savedDir := h.savedDir
-				uid := s.student.UID
+				name, uids := o.name, o.uids
 				postCommitFuncs = append(postCommitFuncs, func() {
-					err := handin.SaveFaclHandin(handinDir, savedDir, uid)
+					err := handin.SaveFaclGroupHandin(handinDir, savedDir, name, uids)
 					if err != nil {
 						ctx.Error.Printf("could not save %v: %v\n", logPrefix, err)
 						exitErr = true
@@ -391,16 +515,31 @@ func init() {
 			}
 		}
 
+		var pubs []*kudos.PubStudent
 		if changed {
+			var uids []string
+			for _, o := range owners {
+				uids = append(uids, o.uids...)
+			}
+			ctx.DB.UpdateLateDays(ctx.Course, uids...)
+			for _, uid := range uids {
+				pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+			}
 			commitDB(ctx)
 		} else {
 			closeDB(ctx)
 		}
 		ctx.Verbose.Println("handin times successfully committed to database; moving handins to permanent storage")
-
+		// move the handins before publishing so that a
+		// failure to publish can't leave handins which
+		// have been recorded as ingested in place to be
+		// overwritten by later handins
 		for _, f := range postCommitFuncs {
 			f()
 		}
+		if !tryPublishStudents(ctx, pubs) {
+			exitErr = true
+		}
 
 		if exitErr {
 			dev.Fail()
@@ -411,5 +550,6 @@ func init() {
 	cmdHandinIngest.Flags().StringVarP(&studentFlag, "student", "", "", "only ingest this student's handin")
 	cmdHandinIngest.Flags().BoolVarP(&allHandinsFlag, "all-handins", "", false, "if the assignment has multiple handins, ingest them all")
 	cmdHandinIngest.Flags().BoolVarP(&forceFlag, "force", "", false, "overwrite previously-ingested handins")
+	cmdHandinIngest.Flags().BoolVarP(&includeDroppedFlag, "include-dropped", "", false, "ingest handins of students who have dropped the course")
 	cmdHandin.AddCommand(cmdHandinIngest)
 }
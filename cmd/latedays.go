@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/handin"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdLateDays = &cobra.Command{
+	Use:   "latedays",
+	Short: "Report students' late day usage",
+	Long: "Report the number of late days each student has used out of the course's " +
+		"late day budget, along with the handins on which they were spent. Late day " +
+		"choices submitted by students with latedays choose are not reflected until " +
+		"they have been imported with latedays ingest.",
+}
+
+func init() {
+	var studentFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var acodes []string
+		for acode := range ctx.DB.Assignments {
+			acodes = append(acodes, acode)
+		}
+		sort.Strings(acodes)
+
+		var pairs unameUIDPairs
+		if cmd.Flag("student").Changed {
+			s := lookupStudent(ctx, studentFlag)
+			pairs = unameUIDPairs{{lookupUsernameForUID(ctx, s.student.UID), s.student.UID}}
+		} else {
+			for uid := range ctx.DB.Students {
+				pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+			}
+			sort.Sort(pairs)
+		}
+
+		for _, pair := range pairs {
+			fmt.Printf("%v: %v/%v late days used\n", pair.uname, ctx.DB.LateDaysUsed(pair.uid), ctx.Course.LateDays)
+			for _, acode := range acodes {
+				asgn := ctx.DB.Assignments[acode]
+				for _, h := range asgn.Handins {
+					n := ctx.DB.LateDays[asgn.Code][h.Code][pair.uid]
+					_, chosen := ctx.DB.LateDayChoices[asgn.Code][h.Code][pair.uid]
+					if n == 0 && !chosen {
+						continue
+					}
+					name := asgn.Code
+					if h.Code != "" {
+						name += " " + h.Code
+					}
+					var choice string
+					if chosen {
+						choice = fmt.Sprintf(" (chose %v)", ctx.DB.LateDayChoices[asgn.Code][h.Code][pair.uid])
+					}
+					fmt.Printf("\t%v: %v%v\n", name, n, choice)
+				}
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdLateDays.Run = f
+	addAllGlobalFlagsTo(cmdLateDays.Flags())
+	cmdLateDays.Flags().StringVarP(&studentFlag, "student", "", "", "only report this student's late days")
+	cmdMain.AddCommand(cmdLateDays)
+}
+
+var cmdLateDaysSet = &cobra.Command{
+	Use:   "set <assignment> [<handin>] <student> <days>",
+	Short: "Choose how many late days a student spends on a handin",
+	Long: "Record a student's choice to spend the given number of late days on a handin. " +
+		"Chosen late days are spent before any late days are allocated automatically, " +
+		"but never more than are needed to make the handin on time or than remain " +
+		"in the student's budget.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 3 && len(args) != 4 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		days, err := strconv.Atoi(args[len(args)-1])
+		if err != nil || days < 0 {
+			ctx.Error.Printf("bad number of late days: %v\n", args[len(args)-1])
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		var hcode string
+		if len(args) == 4 {
+			hcode = args[1]
+		}
+		h := getHandin(ctx, asgn, hcode)
+		s := lookupStudent(ctx, args[len(args)-2])
+
+		ctx.DB.SetLateDayChoice(asgn.Code, h.Code, s.student.UID, days)
+		ctx.DB.UpdateLateDays(ctx.Course, s.student.UID)
+		if n := ctx.DB.LateDays[asgn.Code][h.Code][s.student.UID]; n < days {
+			ctx.Warn.Printf("warning: only %v late days will be spent on this handin\n", n)
+		}
+		pub := ctx.DB.PubStudent(ctx.Course, s.student.UID)
+		commitDB(ctx)
+		publishStudents(ctx, []*kudos.PubStudent{pub})
+	}
+	cmdLateDaysSet.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysSet.Flags())
+	cmdLateDays.AddCommand(cmdLateDaysSet)
+}
+
+var cmdLateDaysClear = &cobra.Command{
+	Use:   "clear <assignment> [<handin>] <student>",
+	Short: "Clear a student's late day choice for a handin",
+	Long: "Clear a student's late day choice for a handin so that late days " +
+		"are allocated to it automatically.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 && len(args) != 3 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		var hcode string
+		if len(args) == 3 {
+			hcode = args[1]
+		}
+		h := getHandin(ctx, asgn, hcode)
+		s := lookupStudent(ctx, args[len(args)-1])
+
+		if !ctx.DB.DeleteLateDayChoice(asgn.Code, h.Code, s.student.UID) {
+			ctx.Error.Println("late day choice does not exist")
+			exitLogic()
+		}
+		ctx.DB.UpdateLateDays(ctx.Course, s.student.UID)
+		pub := ctx.DB.PubStudent(ctx.Course, s.student.UID)
+		commitDB(ctx)
+		publishStudents(ctx, []*kudos.PubStudent{pub})
+	}
+	cmdLateDaysClear.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysClear.Flags())
+	cmdLateDays.AddCommand(cmdLateDaysClear)
+}
+
+var cmdLateDaysInit = &cobra.Command{
+	Use:   "init",
+	Short: "Initialize students' late day choice directories",
+	Long: "Create a directory for each student who does not already have one into " +
+		"which they can submit late day choices with latedays choose. This must " +
+		"be run again after adding students.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+		var uids []string
+		for _, s := range ctx.DB.Students {
+			uids = append(uids, s.UID)
+		}
+		closeDB(ctx)
+
+		err := handin.InitFaclDropDir(ctx.CourseLateDaysDir(), uids)
+		if err != nil {
+			ctx.Error.Printf("initialization failed: %v\n", err)
+			dev.Fail()
+		}
+	}
+	cmdLateDaysInit.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysInit.Flags())
+	cmdLateDays.AddCommand(cmdLateDaysInit)
+}
+
+var cmdLateDaysChoose = &cobra.Command{
+	Use:   "choose <assignment> [<handin>] [<days>]",
+	Short: "Choose how many of your late days to spend on a handin",
+	Long: "Choose how many of your late days to spend on a handin (for students), " +
+		"or, with --clear, clear your choice so that late days are allocated to it " +
+		"automatically. Your choice takes effect once the course staff have " +
+		"imported it, after which it is shown by latedays mine; see latedays set " +
+		"for how choices are applied.",
+}
+
+func init() {
+	var clearFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		// the number of arguments other than <days>
+		n := len(args)
+		if !clearFlag {
+			n--
+		}
+		if n != 1 && n != 2 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		acode := args[0]
+		if err := kudos.ValidateCode(acode); err != nil {
+			ctx.Error.Printf("bad assignment code %q: %v\n", acode, err)
+			exitUsage()
+		}
+		var hcode string
+		if n == 2 {
+			hcode = args[1]
+			if err := kudos.ValidateCode(hcode); err != nil {
+				ctx.Error.Printf("bad handin code %q: %v\n", hcode, err)
+				exitUsage()
+			}
+		}
+		var days int
+		if !clearFlag {
+			var err error
+			days, err = strconv.Atoi(args[len(args)-1])
+			if err != nil || days < 0 {
+				ctx.Error.Printf("bad number of late days: %v\n", args[len(args)-1])
+				exitUsage()
+			}
+		}
+		addCourseConfig(ctx)
+
+		u, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+		err = ctx.WriteLateDayChoice(u.Uid, &kudos.LateDayChoiceFile{
+			Assignment: acode,
+			Handin:     hcode,
+			Days:       days,
+			Clear:      clearFlag,
+			Time:       time.Now(),
+		})
+		if err != nil {
+			if os.IsNotExist(err) {
+				ctx.Error.Println("late day choices have not been set up for you; please contact the course staff")
+			} else {
+				ctx.Error.Printf("could not submit late day choice: %v\n", err)
+			}
+			exitLogic()
+		}
+		ctx.Info.Println("late day choice submitted; it will be shown by kudos latedays mine once it has been imported")
+	}
+	cmdLateDaysChoose.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysChoose.Flags())
+	cmdLateDaysChoose.Flags().BoolVarP(&clearFlag, "clear", "", false, "clear your choice instead of making one")
+	cmdLateDays.AddCommand(cmdLateDaysChoose)
+}
+
+var cmdLateDaysIngest = &cobra.Command{
+	Use:   "ingest",
+	Short: "Import students' late day choices",
+	Long: "Import the late day choices which students have submitted with latedays " +
+		"choose, and recompute the late days of the students whose choices were " +
+		"imported. Invalid choices are skipped with a warning and left in place.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var all []string
+		for uid := range ctx.DB.Students {
+			all = append(all, uid)
+		}
+		sort.Strings(all)
+
+		// files to remove once the imported
+		// choices have been committed
+		var imported []string
+		// students with at least one imported choice
+		var uids []string
+		for _, uid := range all {
+			paths, choices, err := ctx.ReadLateDayChoices(uid)
+			if err != nil {
+				ctx.Error.Printf("could not read late day choices: %v\n", err)
+				dev.Fail()
+			}
+			n := len(imported)
+			for i, c := range choices {
+				if err := ctx.DB.ApplyLateDayChoiceFile(uid, c); err != nil {
+					ctx.Warn.Printf("warning: skipping %v: %v\n", paths[i], err)
+					continue
+				}
+				imported = append(imported, paths[i])
+			}
+			if len(imported) > n {
+				uids = append(uids, uid)
+			}
+		}
+		if len(imported) == 0 {
+			ctx.Verbose.Println("no new late day choices")
+			closeDB(ctx)
+			return
+		}
+		ctx.Verbose.Printf("imported %v late day choice(s)\n", len(imported))
+
+		ctx.DB.UpdateLateDays(ctx.Course, uids...)
+		var pubs []*kudos.PubStudent
+		for _, uid := range uids {
+			pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+		}
+		commitDB(ctx)
+		for _, path := range imported {
+			if err := os.Remove(path); err != nil {
+				ctx.Warn.Printf("warning: could not remove imported late day choice: %v\n", err)
+			}
+		}
+		publishStudents(ctx, pubs)
+	}
+	cmdLateDaysIngest.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysIngest.Flags())
+	cmdLateDays.AddCommand(cmdLateDaysIngest)
+}
+
+var cmdLateDaysUpdate = &cobra.Command{
+	Use:   "update",
+	Short: "Recompute all students' late days",
+	Long: "Recompute the late day ledger for all students and republish it. " +
+		"This is necessary after changing the course's late day budget " +
+		"or an assignment's due dates or late policy.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var uids []string
+		for uid := range ctx.DB.Students {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+		ctx.DB.UpdateLateDays(ctx.Course, uids...)
+		var pubs []*kudos.PubStudent
+		for _, uid := range uids {
+			pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+		}
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+	}
+	cmdLateDaysUpdate.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysUpdate.Flags())
+	cmdLateDays.AddCommand(cmdLateDaysUpdate)
+}
+
+var cmdLateDaysMine = &cobra.Command{
+	Use:   "mine",
+	Short: "Show your own late day usage",
+	Long:  "Show the late days you have used in this course (for students).",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		u, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+		pub, err := ctx.ReadPubStudent(u.Uid)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				ctx.Error.Printf("could not read late days: %v\n", err)
+				exitLogic()
+			}
+			ctx.Debug.Printf("no published information for current user: %v\n", err)
+			pub = &kudos.PubStudent{UID: u.Uid, LateDayBudget: ctx.Course.LateDays}
+		}
+
+		var acodes []string
+		used := 0
+		for acode, handins := range pub.LateDays {
+			acodes = append(acodes, acode)
+			for _, n := range handins {
+				used += n
+			}
+		}
+		sort.Strings(acodes)
+		fmt.Printf("%v/%v late days used\n", used, pub.LateDayBudget)
+		for _, acode := range acodes {
+			var hcodes []string
+			for hcode := range pub.LateDays[acode] {
+				hcodes = append(hcodes, hcode)
+			}
+			sort.Strings(hcodes)
+			for _, hcode := range hcodes {
+				name := acode
+				if hcode != "" {
+					name += " " + hcode
+				}
+				fmt.Printf("\t%v: %v\n", name, pub.LateDays[acode][hcode])
+			}
+		}
+	}
+	cmdLateDaysMine.Run = f
+	addAllGlobalFlagsTo(cmdLateDaysMine.Flags())
+	cmdLateDays.AddCommand(cmdLateDaysMine)
+}
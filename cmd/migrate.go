@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"sort"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdMigrate = &cobra.Command{
+	Use:   "migrate",
+	Short: "Convert the database to exact decimal grades",
+	Long: "Convert a database created when grades were stored as floating point " +
+		"numbers, which accumulate rounding error (for example, 49.99999999 instead " +
+		"of 50). Grades are now stored as exact decimals. Floating point values are " +
+		"converted whenever the database is read; this command additionally rounds " +
+		"every grade to the course's grade precision (grade_precision in the course " +
+		"config), records each change in the grade history, and rewrites the database " +
+		"in the new format.",
+}
+
+func init() {
+	var dryRunFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		historyStart := len(ctx.DB.GradeHistory)
+		n := ctx.DB.RoundGrades(ctx.Course.GradePrecision, kudos.GradeEditor{UID: cur.Uid, Command: cmd.CommandPath()})
+		changes := ctx.DB.GradeHistory[historyStart:]
+
+		// students whose grades have been modified on
+		// assignments which have already been released,
+		// and who thus need to be republished
+		republish := make(map[string]bool)
+		for _, c := range changes {
+			fmt.Printf("%v %v for %v: %v -> %v\n", c.Assignment, c.Problem,
+				lookupUsernameForUID(ctx, c.StudentUID), c.Old.Grade, c.New.Grade)
+			if _, ok := ctx.DB.Releases[c.Assignment]; ok {
+				republish[c.StudentUID] = true
+			}
+		}
+		if dryRunFlag {
+			ctx.Info.Printf("dry run: would round %v grade(s)\n", n)
+			closeDB(ctx)
+			return
+		}
+		if n == 0 {
+			ctx.Info.Println("no grades need rounding")
+			closeDB(ctx)
+			return
+		}
+
+		var uids []string
+		for uid := range republish {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+		var pubs []*kudos.PubStudent
+		for _, uid := range uids {
+			pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+		}
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+		ctx.Info.Printf("rounded %v grade(s)\n", n)
+	}
+	cmdMigrate.Run = f
+	addAllGlobalFlagsTo(cmdMigrate.Flags())
+	cmdMigrate.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false, "show the changes which would be made without making them")
+	cmdMain.AddCommand(cmdMigrate)
+}
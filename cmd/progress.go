@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdProgress = &cobra.Command{
+	Use:   "progress <assignment>",
+	Short: "Show how much of an assignment has been graded",
+	Long: "Show the fraction of (student, problem) pairs which have been graded, " +
+		"and list the students who have not yet been graded on each problem, " +
+		"grouped by the grader they were assigned to (see rubric distribute). " +
+		"Only problems without subproblems are considered; a grade on a parent " +
+		"problem counts as a grade for all of its subproblems. Students who have " +
+		"not handed in the assignment are labeled, or, with --exclude-missing, " +
+		"left out entirely. Students who have dropped the course are left out unless " +
+		"--include-dropped is given.",
+}
+
+func init() {
+	var excludeMissingFlag bool
+	var includeDroppedFlag bool
+	var sectionFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		if cmd.Flag("section").Changed {
+			getSection(ctx, sectionFlag)
+		}
+
+		unames := make(map[string]string)
+		uname := func(uid string) string {
+			u, ok := unames[uid]
+			if !ok {
+				u = lookupUsernameForUID(ctx, uid)
+				unames[uid] = u
+			}
+			return u
+		}
+
+		var pairs unameUIDPairs
+		for uid, s := range ctx.DB.Students {
+			if (s.Dropped() && !includeDroppedFlag) || !ctx.DB.InSection(uid, sectionFlag) {
+				continue
+			}
+			pairs = append(pairs, unameUIDPair{uname(uid), uid})
+		}
+		sort.Sort(pairs)
+		var uids []string
+		excluded := 0
+		for _, pair := range pairs {
+			if excludeMissingFlag && !ctx.DB.HandedIn(asgn, pair.uid) {
+				excluded++
+				continue
+			}
+			uids = append(uids, pair.uid)
+		}
+
+		graded, total, ungraded := ctx.DB.GradingProgress(asgn, uids)
+		if total == 0 {
+			fmt.Println("nothing to grade")
+		} else {
+			fmt.Printf("graded %v/%v (%.1f%%)\n", graded, total, 100*float64(graded)/float64(total))
+		}
+		if excluded > 0 {
+			ctx.Info.Printf("excluded %v student(s) who did not hand in\n", excluded)
+		}
+
+		// maps grader UIDs to their ungraded
+		// problems (in the order given by
+		// GradingProgress)
+		byGrader := make(map[string][]kudos.UngradedProblem)
+		var graders unameUIDPairs
+		for _, u := range ungraded {
+			if _, ok := byGrader[u.GraderUID]; !ok && u.GraderUID != "" {
+				graders = append(graders, unameUIDPair{uname(u.GraderUID), u.GraderUID})
+			}
+			byGrader[u.GraderUID] = append(byGrader[u.GraderUID], u)
+		}
+		sort.Sort(graders)
+		// list unassigned problems last
+		if _, ok := byGrader[""]; ok {
+			graders = append(graders, unameUIDPair{"", ""})
+		}
+
+		for _, g := range graders {
+			us := byGrader[g.uid]
+			if g.uid == "" {
+				fmt.Printf("unassigned: %v ungraded\n", len(us))
+			} else {
+				fmt.Printf("grader %v: %v ungraded\n", g.uname, len(us))
+			}
+			for i := 0; i < len(us); {
+				// collect the students for this problem
+				var students []string
+				j := i
+				for ; j < len(us) && us[j].Problem == us[i].Problem; j++ {
+					s := uname(us[j].StudentUID)
+					if !ctx.DB.HandedIn(asgn, us[j].StudentUID) {
+						s += " (no handin)"
+					}
+					students = append(students, s)
+				}
+				fmt.Printf("\t%v: %v\n", us[i].Problem, strings.Join(students, ", "))
+				i = j
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdProgress.Run = f
+	addAllGlobalFlagsTo(cmdProgress.Flags())
+	cmdProgress.Flags().BoolVarP(&excludeMissingFlag, "exclude-missing", "", false, "leave out students who have not handed in the assignment")
+	cmdProgress.Flags().BoolVarP(&includeDroppedFlag, "include-dropped", "", false, "include students who have dropped the course")
+	cmdProgress.Flags().StringVarP(&sectionFlag, "section", "", "", "only include students in this section")
+	cmdMain.AddCommand(cmdProgress)
+}
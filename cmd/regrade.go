@@ -0,0 +1,492 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/handin"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdRegrade = &cobra.Command{
+	Use:   "regrade",
+	Short: "Request and review regrades",
+	Long: "Students request regrades of released grades with regrade request. " +
+		"Requests are imported into the database with regrade ingest, listed with " +
+		"regrade list, and then reviewed with regrade show and regrade resolve.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		exitUsage()
+	}
+	cmdRegrade.Run = f
+	addAllGlobalFlagsTo(cmdRegrade.Flags())
+	cmdMain.AddCommand(cmdRegrade)
+}
+
+var cmdRegradeInit = &cobra.Command{
+	Use:   "init",
+	Short: "Initialize students' regrade request directories",
+	Long: "Create a regrade request directory for each student who does not " +
+		"already have one. This must be run again after adding students.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+		var uids []string
+		for _, s := range ctx.DB.Students {
+			uids = append(uids, s.UID)
+		}
+		closeDB(ctx)
+
+		err := handin.InitFaclDropDir(ctx.CourseRegradeDir(), uids)
+		if err != nil {
+			ctx.Error.Printf("initialization failed: %v\n", err)
+			dev.Fail()
+		}
+	}
+	cmdRegradeInit.Run = f
+	addAllGlobalFlagsTo(cmdRegradeInit.Flags())
+	cmdRegrade.AddCommand(cmdRegradeInit)
+}
+
+var cmdRegradeRequest = &cobra.Command{
+	Use:   "request <assignment> <problem> <reason>",
+	Short: "Request a regrade of a problem",
+	Long: "Request that your grade on a problem be reconsidered (for students). " +
+		"The grades for the assignment must have been released, and the reason " +
+		"should be quoted so that it is passed as a single argument.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 3 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		acode, pcode, reason := args[0], args[1], args[2]
+		if err := kudos.ValidateCode(acode); err != nil {
+			ctx.Error.Printf("bad assignment code %q: %v\n", acode, err)
+			exitUsage()
+		}
+		if err := kudos.ValidateCode(pcode); err != nil {
+			ctx.Error.Printf("bad problem code %q: %v\n", pcode, err)
+			exitUsage()
+		}
+		if reason == "" {
+			ctx.Error.Println("must give reason")
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		u, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+		pub, err := ctx.ReadPubStudent(u.Uid)
+		if err != nil && !os.IsNotExist(err) {
+			ctx.Error.Printf("could not read grades: %v\n", err)
+			exitLogic()
+		}
+		var g *kudos.PubGrade
+		if pub != nil {
+			g = pub.Grades[acode]
+		}
+		if g == nil {
+			ctx.Error.Printf("no grades released for assignment %v\n", acode)
+			exitLogic()
+		}
+		found := false
+		for _, p := range g.Problems {
+			if p.Code == pcode {
+				found = true
+			}
+		}
+		if !found {
+			ctx.Error.Printf("assignment %v has no problem with the code %v\n", acode, pcode)
+			exitLogic()
+		}
+
+		err = ctx.WriteRegradeRequest(u.Uid, &kudos.RegradeRequestFile{
+			Assignment: acode,
+			Problem:    pcode,
+			Reason:     reason,
+			Time:       time.Now(),
+		})
+		if err != nil {
+			if os.IsNotExist(err) {
+				ctx.Error.Println("regrade requests have not been set up for you; please contact the course staff")
+			} else {
+				ctx.Error.Printf("could not submit regrade request: %v\n", err)
+			}
+			exitLogic()
+		}
+		ctx.Info.Println("regrade request submitted; its status will be shown by kudos grades once it has been reviewed")
+	}
+	cmdRegradeRequest.Run = f
+	addAllGlobalFlagsTo(cmdRegradeRequest.Flags())
+	cmdRegrade.AddCommand(cmdRegradeRequest)
+}
+
+var cmdRegradeIngest = &cobra.Command{
+	Use:   "ingest",
+	Short: "Import regrade requests",
+	Long: "Import any newly-submitted regrade requests into the database. Requests " +
+		"for assignments whose grades have not been released are skipped. Imported " +
+		"requests are then listed with regrade list.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var uids []string
+		for uid := range ctx.DB.Students {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+
+		// students can write arbitrary files, so
+		// the times in them can't be trusted
+		now := time.Now()
+		// files to remove once the imported
+		// requests have been committed
+		var imported []string
+		var pubs []*kudos.PubStudent
+		for _, uid := range uids {
+			paths, requests, err := ctx.ReadRegradeRequests(uid)
+			if err != nil {
+				ctx.Error.Printf("could not read regrade requests: %v\n", err)
+				dev.Fail()
+			}
+			n := len(imported)
+			for i, r := range requests {
+				asgn, ok := ctx.DB.Assignments[r.Assignment]
+				if !ok {
+					ctx.Warn.Printf("warning: skipping %v: no such assignment: %v\n", paths[i], r.Assignment)
+					continue
+				}
+				if _, ok := ctx.DB.Releases[r.Assignment]; !ok {
+					ctx.Warn.Printf("warning: skipping %v: grades not released for assignment %v\n", paths[i], r.Assignment)
+					continue
+				}
+				if kudos.ValidateCode(r.Problem) != nil {
+					ctx.Warn.Printf("warning: skipping %v: bad problem code: %q\n", paths[i], r.Problem)
+					continue
+				}
+				if _, ok := asgn.FindProblemByCode(r.Problem); !ok {
+					ctx.Warn.Printf("warning: skipping %v: no such problem: %v\n", paths[i], r.Problem)
+					continue
+				}
+				ctx.DB.AddRegradeRequest(&kudos.RegradeRequest{
+					Assignment: r.Assignment,
+					Problem:    r.Problem,
+					StudentUID: uid,
+					Reason:     r.Reason,
+					Time:       now,
+				})
+				imported = append(imported, paths[i])
+			}
+			if len(imported) > n {
+				pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+			}
+		}
+		if len(imported) == 0 {
+			ctx.Verbose.Println("no new regrade requests")
+			closeDB(ctx)
+			return
+		}
+		ctx.Verbose.Printf("imported %v regrade request(s)\n", len(imported))
+		commitDB(ctx)
+		for _, path := range imported {
+			if err := os.Remove(path); err != nil {
+				ctx.Warn.Printf("warning: could not remove imported regrade request: %v\n", err)
+			}
+		}
+		publishStudents(ctx, pubs)
+	}
+	cmdRegradeIngest.Run = f
+	addAllGlobalFlagsTo(cmdRegradeIngest.Flags())
+	cmdRegrade.AddCommand(cmdRegradeIngest)
+}
+
+var cmdRegradeList = &cobra.Command{
+	Use:   "list",
+	Short: "List regrade requests",
+	Long: "List the regrade requests which have been imported with regrade ingest " +
+		"(by default, only those which are open).",
+}
+
+func init() {
+	var allFlag bool
+	var studentFlag string
+	var assignmentFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var acode string
+		if cmd.Flag("assignment").Changed {
+			acode = getAssignment(ctx, assignmentFlag, false).Code
+		}
+		var stud *student
+		if cmd.Flag("student").Changed {
+			stud = lookupStudent(ctx, studentFlag)
+		}
+
+		// maps uids to usernames
+		unames := make(map[string]string)
+		for _, r := range ctx.DB.Regrades {
+			if (!allFlag && r.State != kudos.RegradeOpen) || (acode != "" && r.Assignment != acode) ||
+				(stud != nil && r.StudentUID != stud.student.UID) {
+				continue
+			}
+			uname, ok := unames[r.StudentUID]
+			if !ok {
+				uname = lookupUsernameForUID(ctx, r.StudentUID)
+				unames[r.StudentUID] = uname
+			}
+			fmt.Printf("%v: [%v] %v %v for %v (requested %v)\n", r.ID, r.State, r.Assignment,
+				r.Problem, uname, r.Time.Format(kudos.DateFormat))
+		}
+		closeDB(ctx)
+	}
+	cmdRegradeList.Run = f
+	addAllGlobalFlagsTo(cmdRegradeList.Flags())
+	cmdRegradeList.Flags().BoolVarP(&allFlag, "all", "", false, "list resolved requests as well as open ones")
+	cmdRegradeList.Flags().StringVarP(&studentFlag, "student", "", "", "only list this student's requests")
+	cmdRegradeList.Flags().StringVarP(&assignmentFlag, "assignment", "", "", "only list requests for this assignment")
+	cmdRegrade.AddCommand(cmdRegradeList)
+}
+
+// Parses the given regrade request ID and looks up
+// the request. Assumes that the database has been
+// opened. If an error is encountered, it is logged
+// to ctx.Error, and the process exits.
+func getRegradeRequest(ctx *kudos.Context, id string) *kudos.RegradeRequest {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		ctx.Error.Printf("bad regrade request ID: %v\n", id)
+		exitUsage()
+	}
+	r, ok := ctx.DB.FindRegradeRequest(n)
+	if !ok {
+		ctx.Error.Printf("no such regrade request: %v\n", n)
+		exitLogic()
+	}
+	return r
+}
+
+var cmdRegradeShow = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a regrade request",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		r := getRegradeRequest(ctx, args[0])
+		fmt.Printf("request %v (%v)\n", r.ID, r.State)
+		fmt.Printf("student: %v\n", lookupUsernameForUID(ctx, r.StudentUID))
+		fmt.Printf("problem: %v %v\n", r.Assignment, r.Problem)
+		fmt.Printf("requested: %v\n", r.Time.Format(kudos.DateFormat))
+		fmt.Printf("reason: %v\n", r.Reason)
+		if asgn, ok := ctx.DB.Assignments[r.Assignment]; ok {
+			grade := "none"
+			if g, ok := ctx.DB.Grades[r.Assignment][r.StudentUID]; ok {
+				if total, ok := g.ProblemTotal(asgn, r.Problem); ok {
+					grade = fmt.Sprint(total)
+				}
+				if c := g.Grades[r.Problem].Comment; c != "" {
+					grade += fmt.Sprintf(" (comment: %v)", c)
+				}
+			}
+			fmt.Printf("current grade: %v\n", grade)
+		}
+		if r.State != kudos.RegradeOpen {
+			fmt.Printf("resolved: %v by %v\n", r.Resolved.Format(kudos.DateFormat), lookupUsernameForUID(ctx, r.ResolverUID))
+			fmt.Printf("response: %v\n", r.Response)
+		}
+
+		closeDB(ctx)
+	}
+	cmdRegradeShow.Run = f
+	addAllGlobalFlagsTo(cmdRegradeShow.Flags())
+	cmdRegrade.AddCommand(cmdRegradeShow)
+}
+
+var cmdRegradeResolve = &cobra.Command{
+	Use:   "resolve <id>",
+	Short: "Accept or reject a regrade request",
+	Long: "Accept or reject a regrade request, leaving a response for the student. " +
+		"When accepting, a new grade for the problem may be given with --grade; it " +
+		"is recorded just as it would be by kudos grade, replacing the previous grade " +
+		"for the problem. For group assignments, the new grade is given to the " +
+		"student's whole group (as with kudos grade --group) unless the student " +
+		"has an individual grade for the problem.",
+}
+
+func init() {
+	var acceptFlag bool
+	var rejectFlag bool
+	var responseFlag string
+	var gradeFlag string
+	var commentFlag string
+	var forceFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		gradeFlagSet := cmd.Flag("grade").Changed
+		switch {
+		case len(args) != 1:
+			cmd.Usage()
+			exitUsage()
+		case acceptFlag == rejectFlag:
+			fmt.Fprintln(os.Stderr, "must specify exactly one of --accept or --reject")
+			exitUsage()
+		case responseFlag == "":
+			fmt.Fprintln(os.Stderr, "must specify response with --response")
+			exitUsage()
+		case rejectFlag && (gradeFlagSet || cmd.Flag("comment").Changed):
+			fmt.Fprintln(os.Stderr, "cannot specify grade or comment when rejecting")
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		r := getRegradeRequest(ctx, args[0])
+		// the students whose grades may change
+		uids := []string{r.StudentUID}
+		state := kudos.RegradeRejected
+		if acceptFlag {
+			state = kudos.RegradeAccepted
+		}
+		if err := ctx.DB.ResolveRegradeRequest(r, state, responseFlag, cur.Uid); err != nil {
+			ctx.Error.Println(err)
+			exitLogic()
+		}
+
+		if gradeFlagSet {
+			asgn := getAssignment(ctx, r.Assignment, false)
+			prob, _ := asgn.FindProblemByCode(r.Problem)
+			grade, symbol, err := prob.ParseGrade(gradeFlag)
+			if err != nil {
+				ctx.Error.Printf("could not parse grade %q: %v\n", gradeFlag, err)
+				exitUsage()
+			}
+			if err := ctx.Course.ValidateGrade(grade); err != nil {
+				ctx.Error.Println(err)
+				exitUsage()
+			}
+			var old kudos.ProblemGrade
+			var hasOld bool
+			if g, ok := ctx.DB.Grades[asgn.Code][r.StudentUID]; ok {
+				old, hasOld = g.Grades[r.Problem]
+			}
+			comment := commentFlag
+			if !cmd.Flag("comment").Changed {
+				comment = old.Comment
+			}
+			// the problem's own grade is always replaced;
+			// --force is only needed to replace the grades
+			// of its subproblems (if the problem itself has
+			// a grade, none of its subproblems can)
+			var overrides []string
+			uids, overrides, err = ctx.DB.SetHandinGrade(asgn, r.StudentUID, r.Problem, kudos.ProblemGrade{
+				Grade:     grade,
+				Symbol:    symbol,
+				Comment:   comment,
+				GraderUID: cur.Uid,
+			}, forceFlag || hasOld, kudos.GradeEditor{UID: cur.Uid, Command: cmd.CommandPath()})
+			if err != nil {
+				if _, ok := err.(*kudos.SubproblemGradedError); ok {
+					ctx.Error.Printf("%v; use --force to overwrite all subproblem grades\n", err)
+				} else {
+					ctx.Error.Println(err)
+				}
+				exitLogic()
+			}
+			for _, o := range overrides {
+				ctx.Warn.Printf("warning: %v has an individual grade for this problem, which overrides the group's grade\n",
+					lookupUsernameForUID(ctx, o))
+			}
+			if grade.Cmp(prob.Points) > 0 {
+				ctx.Warn.Printf("warning: grade is higher than the maximum for this problem (%v points)\n", prob.Points)
+			}
+		} else if acceptFlag {
+			ctx.Warn.Println("warning: accepting without changing the grade (use --grade to change it)")
+		}
+
+		// the requesting student is always republished
+		// so that they can see the response; other
+		// members of their group only if their grades
+		// may have changed and have been released
+		pubs := []*kudos.PubStudent{ctx.DB.PubStudent(ctx.Course, r.StudentUID)}
+		for _, uid := range uids {
+			if uid != r.StudentUID {
+				pubs = append(pubs, releasedPubs(ctx, r.Assignment, uid)...)
+			}
+		}
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+	}
+	cmdRegradeResolve.Run = f
+	addAllGlobalFlagsTo(cmdRegradeResolve.Flags())
+	cmdRegradeResolve.Flags().BoolVarP(&acceptFlag, "accept", "", false, "accept the request")
+	cmdRegradeResolve.Flags().BoolVarP(&rejectFlag, "reject", "", false, "reject the request")
+	cmdRegradeResolve.Flags().StringVarP(&responseFlag, "response", "", "", "the response to the student")
+	cmdRegradeResolve.Flags().StringVarP(&gradeFlag, "grade", "", "", "the new grade for the problem (only when accepting)")
+	cmdRegradeResolve.Flags().StringVarP(&commentFlag, "comment", "", "", "the new comment for the problem (by default, the previous comment is kept)")
+	cmdRegradeResolve.Flags().BoolVarP(&forceFlag, "force", "f", false, "overwrite grades of subproblems")
+	cmdRegrade.AddCommand(cmdRegradeResolve)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdRelease = &cobra.Command{
+	Use:   "release <assignment>",
+	Short: "Release an assignment's grades to students",
+	Long: "Release publishes each student's grades and comments for the given " +
+		"assignment so that the student (and only the student) can view them " +
+		"using kudos grades. Grades which are changed after they have been " +
+		"released are published automatically.",
+}
+
+func init() {
+	var withdrawFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+		openPubDB(ctx)
+		defer cleanupPubDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		if withdrawFlag {
+			if !ctx.DB.WithdrawGrades(asgn.Code) {
+				ctx.Error.Println("grades for this assignment have not been released")
+				exitLogic()
+			}
+		} else {
+			if _, ok := ctx.DB.Releases[asgn.Code]; ok {
+				ctx.Warn.Println("warning: grades for this assignment have already been released; re-releasing")
+			}
+			incomplete := 0
+			for uid := range ctx.DB.Students {
+				g, ok := ctx.DB.Grades[asgn.Code][uid]
+				if !ok {
+					incomplete++
+				} else if _, ok := g.Total(asgn); !ok {
+					incomplete++
+				}
+			}
+			if incomplete > 0 {
+				ctx.Warn.Printf("warning: %v student(s) have missing or incomplete grades\n", incomplete)
+			}
+			ctx.DB.ReleaseGrades(asgn.Code, &kudos.Release{
+				ReleaserUID: cur.Uid,
+				Time:        time.Now(),
+			})
+		}
+		ctx.PubDB.Assignments[asgn.Code] = ctx.DB.PubAssignment(asgn)
+
+		var pubs []*kudos.PubStudent
+		for uid := range ctx.DB.Students {
+			pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+		}
+		commitDB(ctx)
+		commitPubDB(ctx)
+		publishStudents(ctx, pubs)
+	}
+	cmdRelease.Run = f
+	addAllGlobalFlagsTo(cmdRelease.Flags())
+	cmdRelease.Flags().BoolVarP(&withdrawFlag, "withdraw", "", false, "withdraw previously-released grades")
+	cmdMain.AddCommand(cmdRelease)
+}
+
+// Computes the per-student information to publish
+// for each of the given students if the given
+// assignment's grades have been released, or nil
+// otherwise. Assumes that the database has been
+// opened.
+func releasedPubs(ctx *kudos.Context, assignment string, uids ...string) []*kudos.PubStudent {
+	if _, ok := ctx.DB.Releases[assignment]; !ok {
+		return nil
+	}
+	var pubs []*kudos.PubStudent
+	for _, uid := range uids {
+		pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+	}
+	return pubs
+}
+
+var cmdGrades = &cobra.Command{
+	Use:   "grades [<assignment>]",
+	Short: "Show your released grades",
+	Long:  "Show the grades and comments you have received on assignments whose grades have been released (for students).",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		u, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+		pub, err := ctx.ReadPubStudent(u.Uid)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				ctx.Error.Printf("could not read grades: %v\n", err)
+				exitLogic()
+			}
+			ctx.Debug.Printf("no published information for current user: %v\n", err)
+			pub = &kudos.PubStudent{UID: u.Uid}
+		}
+
+		var acodes []string
+		if len(args) == 1 {
+			if _, ok := pub.Grades[args[0]]; !ok {
+				ctx.Error.Printf("no grades released for assignment %v\n", args[0])
+				exitLogic()
+			}
+			acodes = []string{args[0]}
+		} else {
+			for acode := range pub.Grades {
+				acodes = append(acodes, acode)
+			}
+			sort.Strings(acodes)
+			if len(acodes) == 0 {
+				ctx.Info.Println("no grades have been released")
+			}
+		}
+
+		for _, acode := range acodes {
+			g := pub.Grades[acode]
+			name := g.Assignment
+			if g.Name != "" {
+				name += " (" + g.Name + ")"
+			}
+			switch {
+			case g.Excused:
+				fmt.Printf("%v: excused\n", name)
+			case !g.Complete:
+				fmt.Printf("%v: incomplete\n", name)
+			case g.AdjustedTotal != g.Total:
+				fmt.Printf("%v: %v/%v (adjusted for lateness: %v/%v)\n", name, g.Total, g.OutOf, g.AdjustedTotal, g.OutOf)
+			default:
+				fmt.Printf("%v: %v/%v\n", name, g.Total, g.OutOf)
+			}
+			for _, p := range g.Problems {
+				indent := strings.Repeat("\t", p.Depth+1)
+				pname := p.Code
+				if p.Name != "" {
+					pname = p.Name
+				}
+				if p.ExtraCredit {
+					pname += " (extra credit)"
+				}
+				if p.Aggregate != "" {
+					pname += fmt.Sprintf(" (%v)", p.Aggregate)
+				}
+				switch {
+				case p.Excused:
+					fmt.Printf("%v%v: excused\n", indent, pname)
+				case p.Graded && p.Symbol != "":
+					fmt.Printf("%v%v: %v (%v/%v)\n", indent, pname, p.Symbol, p.Grade, p.Points)
+				case p.Graded:
+					fmt.Printf("%v%v: %v/%v\n", indent, pname, p.Grade, p.Points)
+				default:
+					fmt.Printf("%v%v: ungraded (out of %v)\n", indent, pname, p.Points)
+				}
+				if p.Comment != "" {
+					fmt.Printf("%v\tcomment: %v\n", indent, p.Comment)
+				}
+			}
+			for _, r := range pub.Regrades {
+				if r.Assignment != acode {
+					continue
+				}
+				fmt.Printf("\tregrade request for %v (%v): %v\n", r.Problem, r.Time.Format(kudos.DateFormat), r.State)
+				if r.State != kudos.RegradeOpen {
+					fmt.Printf("\t\tresponse: %v\n", r.Response)
+				}
+			}
+		}
+	}
+	cmdGrades.Run = f
+	addAllGlobalFlagsTo(cmdGrades.Flags())
+	cmdMain.AddCommand(cmdGrades)
+}
@@ -23,6 +23,7 @@ func init() {
 	var showGraderFlag bool
 	var showTotalsFlag bool
 	var precisionFlag uint8
+	var sectionFlag string
 
 	stripRegex := regexp.MustCompile(`\.?0*$`)
 	formatFloat := func(f float64) string {
@@ -33,6 +34,15 @@ func init() {
 		return string(stripRegex.ReplaceAll(a, b))
 	}
 
+	formatDecimal := func(d kudos.Decimal) string {
+		return d.Round(int(precisionFlag)).String()
+	}
+
+	formatTotal := func(total, outOf kudos.Decimal) string {
+		percent := formatFloat(100 * (total.Float64() / outOf.Float64()))
+		return fmt.Sprintf("%v/%v (%v%%)", formatDecimal(total), formatDecimal(outOf), percent)
+	}
+
 	f := func(cmd *cobra.Command, args []string) {
 		studentFlagSet := cmdShowGrade.Flags().Lookup("student").Changed
 		assignmentFlagSet := cmdShowGrade.Flags().Lookup("assignment").Changed
@@ -43,6 +53,9 @@ func init() {
 		case !studentFlagSet && !assignmentFlagSet:
 			fmt.Fprintln(os.Stderr, "must specify --student or --assignment")
 			exitUsage()
+		case studentFlagSet && cmd.Flag("section").Changed:
+			fmt.Fprintln(os.Stderr, "cannot specify --student and --section")
+			exitUsage()
 		}
 
 		ctx := getContext()
@@ -73,26 +86,57 @@ func init() {
 		// grades
 		printGrade := func(uid, assignment, prefix string) {
 			grade, ok := ctx.DB.Grades[assignment][uid]
-			if !ok || len(grade.Grades) == 0 {
+			if !ok || (len(grade.Grades) == 0 && len(grade.Excused) == 0) {
 				fmt.Println("missing")
 				return
 			}
 			asgn := ctx.DB.Assignments[assignment]
+			if e, ok := grade.Excusal(asgn, ""); ok {
+				fmt.Printf("excused (by %v: %v)\n", lookupUsernameForUID(ctx, e.GranterUID), e.Reason)
+				return
+			}
+			lateness := ctx.DB.Lateness(asgn, uid, asgn.EffectiveLatePolicy(ctx.Course))
+			penalized := false
+			for _, l := range lateness {
+				if l.Penalty > 0 {
+					penalized = true
+				}
+			}
 			total, ok := grade.Total(asgn)
 			if ok {
+				var totalStr string
 				if showTotalsFlag {
-					outOf := asgn.TotalPoints()
-					// TODO(joshlf): make the precision variable
-					// so that fewer digits are used if they're
-					// not needed (ie, trailing 0s removed)
-					percent := formatFloat(100 * (total / outOf))
-					fmt.Printf("%v/%v (%v%%)\n", formatFloat(total), formatFloat(outOf), percent)
+					totalStr = formatTotal(total, grade.OutOf(asgn))
 				} else {
-					fmt.Println(total)
+					totalStr = fmt.Sprint(total)
+				}
+				if penalized {
+					adjusted, _ := grade.AdjustedTotal(asgn, lateness)
+					if showTotalsFlag {
+						totalStr += "; adjusted for lateness: " + formatTotal(adjusted, grade.OutOf(asgn))
+					} else {
+						totalStr += fmt.Sprintf(" (adjusted for lateness: %v)", formatDecimal(adjusted))
+					}
 				}
+				fmt.Println(totalStr)
 			} else {
 				fmt.Println("incomplete")
 			}
+			for _, l := range lateness {
+				if l.Late <= 0 && l.LateDays == 0 {
+					continue
+				}
+				name := "handin"
+				if len(asgn.Handins) > 1 {
+					name += " " + l.Handin
+				}
+				var lateDays string
+				if l.LateDays > 0 {
+					lateDays = fmt.Sprintf("; late days used: %v", l.LateDays)
+				}
+				fmt.Printf("%v\t%v late by %v (due %v; handed in %v%v); penalty: %v%%\n", prefix, name, l.Late,
+					l.Due.Format(kudos.DateFormat), l.HandedIn.Format(kudos.DateFormat), lateDays, formatFloat(100*l.Penalty))
+			}
 			if showProblemsFlag {
 				var walkFn func(p kudos.Problem, prefix string)
 				walkFn = func(p kudos.Problem, prefix string) {
@@ -102,6 +146,12 @@ func init() {
 					// grade (a problem in which not all subproblems
 					// have grades) and give different output.
 
+					if e, ok := grade.Excusal(asgn, p.Code); ok {
+						fmt.Printf("%v%v: excused (by %v: %v)\n", prefix, p.Code,
+							lookupUsernameForUID(ctx, e.GranterUID), e.Reason)
+						return
+					}
+
 					total, ok := grade.ProblemTotal(asgn, p.Code)
 					// whether this total was calculated from
 					// subproblems (as opposed to assigned
@@ -114,13 +164,18 @@ func init() {
 					var totalStr string
 					if ok {
 						if showTotalsFlag {
-							// TODO(joshlf): make the precision variable
-							// so that fewer digits are used if they're
-							// not needed (ie, trailing 0s removed)
-							percent := formatFloat(100 * (total / p.Points))
-							totalStr = fmt.Sprintf("%v/%v (%v%%)", formatFloat(total), formatFloat(p.Points), percent)
+							outOf := grade.ProblemOutOf(asgn, p.Code)
+							if p.ExtraCredit {
+								// extra credit problems count toward
+								// no total, so show their own points
+								outOf = p.Points
+							}
+							totalStr = formatTotal(total, outOf)
 						} else {
-							totalStr = formatFloat(total)
+							totalStr = formatDecimal(total)
+						}
+						if sym := grade.Grades[p.Code].Symbol; !calculated && sym != "" {
+							totalStr = fmt.Sprintf("%v = %v", sym, totalStr)
 						}
 						if !calculated && showGraderFlag {
 							guid := grade.Grades[p.Code].GraderUID
@@ -139,10 +194,15 @@ func init() {
 						pointsStr = "missing"
 					case ok && !calculated:
 						pointsStr = fmt.Sprint(totalStr)
+					case ok && calculated && p.DescribeAggregate() != "":
+						pointsStr = fmt.Sprintf("%v (calculated from subproblems: %v)", totalStr, p.DescribeAggregate())
 					case ok && calculated:
 						pointsStr = fmt.Sprintf("%v (calculated from subproblems)", totalStr)
 					}
 
+					if p.ExtraCredit {
+						pointsStr += " (extra credit)"
+					}
 					fmt.Printf("%v%v: %v\n", prefix, p.Code, pointsStr)
 
 					newPrefix := prefix + "\t"
@@ -172,8 +232,14 @@ func init() {
 				printGrade(stud.usr.Uid, code, "")
 			}
 		case assignmentFlagSet:
+			if cmd.Flag("section").Changed {
+				getSection(ctx, sectionFlag)
+			}
 			var uids []string
 			for _, s := range ctx.DB.Students {
+				if !ctx.DB.InSection(s.UID, sectionFlag) {
+					continue
+				}
 				uids = append(uids, s.UID)
 			}
 			sort.Strings(uids)
@@ -215,6 +281,7 @@ func init() {
 	cmdShowGrade.Flags().BoolVarP(&showGraderFlag, "show-grader", "", false, "show grader for each problem; implies --show-problems")
 	cmdShowGrade.Flags().BoolVarP(&showTotalsFlag, "show-totals", "", false, "show total number of points grades are out of")
 	cmdShowGrade.Flags().Uint8VarP(&precisionFlag, "precision", "", 2, "the maximum number of digits of precision to use when formatting floating point values")
+	cmdShowGrade.Flags().StringVarP(&sectionFlag, "section", "", "", "with --assignment, only show grades for students in this section")
 	cmdMain.AddCommand(cmdShowGrade)
 }
 
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/joshlf/kudos/lib/config"
 	"github.com/joshlf/kudos/lib/dev"
 	"github.com/joshlf/kudos/lib/kudos"
 	"github.com/spf13/cobra"
@@ -48,43 +55,7 @@ func init() {
 
 		pcodes := args[2:]
 
-		// maps problems to the most recent
-		// problem that they are an ancestor
-		// of
-		seenParents := make(map[string]string)
-		seenCodes := make(map[string]bool)
-		for _, code := range pcodes {
-			validateProblemCode(ctx, code, true)
-			if seenCodes[code] {
-				ctx.Error.Printf("duplicate problem code: %v\n", code)
-				exitUsage()
-			}
-			seenCodes[code] = true
-			if child, ok := seenParents[code]; ok {
-				ctx.Error.Printf("problem %v conflicts with previous problem %v "+
-					"(%v is a child of %v)\n", code, child, child, code)
-				exitLogic()
-			}
-			path, ok := asgn.FindProblemPathByCode(code)
-			if !ok {
-				ctx.Error.Printf("no such problem: %v\n", code)
-				exitLogic()
-			}
-			// it doesn't matter what order we traverse the path
-			// in because (assuming the database is valid), at
-			// most one parent can have a grade assigned to it
-			// (if more than one did, that would constitute the
-			// same error we're looking for here, and we assume
-			// that the database is valid)
-			for _, p := range path {
-				if seenCodes[p] {
-					ctx.Error.Printf("problem %v conflicts with previous problem %v "+
-						"(%v is a child of %v)\n", code, p, code, p)
-					exitLogic()
-				}
-				seenParents[p] = code
-			}
-		}
+		validateRubricProblems(ctx, asgn, pcodes)
 
 		out := os.Stdout
 		if cmd.Flag("output").Changed {
@@ -127,3 +98,447 @@ func init() {
 	cmdRubricGenerate.Flags().BoolVarP(&anonymousFlag, "anonymous", "", false, "store an anonymous token instead of a uid in the rubric")
 	cmdRubric.AddCommand(cmdRubricGenerate)
 }
+
+// Validates that the given problem codes can be
+// used together to generate a rubric for asgn: each
+// must be a valid code of a problem in asgn, there
+// must be no duplicates, and no problem may be the
+// ancestor of another. If validation fails, an error
+// is logged and the process exits.
+func validateRubricProblems(ctx *kudos.Context, asgn *kudos.Assignment, pcodes []string) {
+	// maps problems to the most recent
+	// problem that they are an ancestor
+	// of
+	seenParents := make(map[string]string)
+	seenCodes := make(map[string]bool)
+	for _, code := range pcodes {
+		validateProblemCode(ctx, code, true)
+		if seenCodes[code] {
+			ctx.Error.Printf("duplicate problem code: %v\n", code)
+			exitUsage()
+		}
+		seenCodes[code] = true
+		if child, ok := seenParents[code]; ok {
+			ctx.Error.Printf("problem %v conflicts with previous problem %v "+
+				"(%v is a child of %v)\n", code, child, child, code)
+			exitLogic()
+		}
+		path, ok := asgn.FindProblemPathByCode(code)
+		if !ok {
+			ctx.Error.Printf("no such problem: %v\n", code)
+			exitLogic()
+		}
+		for _, p := range path {
+			if seenCodes[p] {
+				ctx.Error.Printf("problem %v conflicts with previous problem %v "+
+					"(%v is a child of %v)\n", code, p, code, p)
+				exitLogic()
+			}
+			seenParents[p] = code
+		}
+	}
+}
+
+var cmdRubricIngest = &cobra.Command{
+	Use:   "ingest <file | directory> [<file | directory> [...]]",
+	Short: "Record the grades in completed rubrics in the database",
+	Long: "Ingest reads completed rubrics and records their grades in the database. " +
+		"Directories are searched recursively for rubrics. If any rubric cannot be " +
+		"ingested, each error is reported and no changes are saved. For group " +
+		"assignments, a rubric's grades are given to its student's whole group " +
+		"(see kudos grade --group), unless the student has an individual grade " +
+		"for the problem.",
+}
+
+func init() {
+	var forceFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		var paths []string
+		for _, arg := range args {
+			fi, err := os.Stat(arg)
+			if err != nil {
+				ctx.Error.Printf("could not stat %v: %v\n", arg, err)
+				exitUsage()
+			}
+			if !fi.IsDir() {
+				paths = append(paths, arg)
+				continue
+			}
+			err = filepath.Walk(arg, func(path string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if fi.Mode().IsRegular() && !config.IgnoreFileAndLog(ctx.Debug.Printf, path) {
+					paths = append(paths, path)
+				}
+				return nil
+			})
+			if err != nil {
+				ctx.Error.Printf("could not read directory %v: %v\n", arg, err)
+				dev.Fail()
+			}
+		}
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		editor := kudos.GradeEditor{UID: cur.Uid, Command: cmd.CommandPath()}
+		// students whose grades have been modified on
+		// assignments which have already been released,
+		// and who thus need to be republished
+		republish := make(map[string]bool)
+
+		// ingest a single rubric into the database;
+		// if any rubric cannot be ingested, the
+		// database is not committed, so it does
+		// not matter if some of a failed rubric's
+		// grades have already been recorded
+		ingest := func(path string) error {
+			r, err := kudos.ParseRubricFile(path)
+			if err != nil {
+				return err
+			}
+			uid, ok := r.GetUID(ctx)
+			if !ok {
+				return fmt.Errorf("unknown anonymous token: %v", r.AnonymousToken)
+			}
+			if _, ok := ctx.DB.Students[uid]; !ok {
+				return fmt.Errorf("no such student: %v", uid)
+			}
+			asgn, ok := ctx.DB.Assignments[r.Assignment]
+			if !ok {
+				return fmt.Errorf("no such assignment in database: %v", r.Assignment)
+			}
+			if err := r.CheckAssignment(asgn); err != nil {
+				return err
+			}
+
+			for _, g := range r.Grades {
+				if err := ctx.Course.ValidateGrade(g.Grade); err != nil {
+					return fmt.Errorf("problem %v: %v", g.Problem, err)
+				}
+				uids, overrides, err := ctx.DB.SetHandinGrade(asgn, uid, g.Problem, kudos.ProblemGrade{
+					Grade:     g.Grade,
+					Symbol:    g.Symbol,
+					Comment:   g.Comment,
+					GraderUID: cur.Uid,
+				}, forceFlag, editor)
+				if err != nil {
+					if _, ok := err.(*kudos.SubproblemGradedError); ok {
+						return fmt.Errorf("%v; use --force to overwrite all subproblem grades", err)
+					} else if err == kudos.ErrGradeExists {
+						return fmt.Errorf("problem %v: %v; use --force to overwrite", g.Problem, err)
+					}
+					return fmt.Errorf("problem %v: %v", g.Problem, err)
+				}
+				for _, o := range overrides {
+					ctx.Warn.Printf("warning: %v: %v has an individual grade for problem %v, which overrides the group's grade\n",
+						path, lookupUsernameForUID(ctx, o), g.Problem)
+				}
+				if _, ok := ctx.DB.Releases[asgn.Code]; ok {
+					for _, u := range uids {
+						republish[u] = true
+					}
+				}
+			}
+			return nil
+		}
+
+		failed := 0
+		for _, path := range paths {
+			ctx.Verbose.Printf("ingesting %v\n", path)
+			if err := ingest(path); err != nil {
+				ctx.Error.Printf("%v: %v\n", path, err)
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			ctx.Error.Printf("could not ingest %v of %v rubrics; aborting (no changes saved)\n", failed, len(paths))
+			closeDB(ctx)
+			exitLogic()
+		}
+
+		if forceFlag {
+			ctx.Warn.Println("warning: overwriting any previous grades for ingested problems or subproblems")
+		}
+		var pubs []*kudos.PubStudent
+		for uid := range republish {
+			pubs = append(pubs, ctx.DB.PubStudent(ctx.Course, uid))
+		}
+		commitDB(ctx)
+		publishStudents(ctx, pubs)
+		ctx.Info.Printf("ingested %v rubrics\n", len(paths))
+	}
+	cmdRubricIngest.Run = f
+	addAllGlobalFlagsTo(cmdRubricIngest.Flags())
+	cmdRubricIngest.Flags().BoolVarP(&forceFlag, "force", "f", false, "overwrite previous grades or grades of subproblems")
+	cmdRubric.AddCommand(cmdRubricIngest)
+}
+
+var cmdRubricDistribute = &cobra.Command{
+	Use:   "distribute <assignment> [<problem> [...]]",
+	Short: "Generate rubrics for all students and distribute them among graders",
+	Long: "Distribute generates a rubric for every student (except those who have " +
+		"dropped the course) for the given problems " +
+		"(or all top-level problems if none are given), and assigns each rubric to " +
+		"one of the given graders, balancing the number of rubrics per grader. No " +
+		"student is assigned to a grader who is on the student's blacklist, or " +
+		"whose blacklist contains the student. Rubrics are written to a directory " +
+		"per grader inside the output directory, and the assignment of students to " +
+		"graders is recorded in the database. With --section, only the students in " +
+		"that section are distributed, and if no graders are given, the section's " +
+		"leader grades all of them. For group assignments, each group gets a single " +
+		"rubric (named group-<group> unless --anonymous is given), whose grades are " +
+		"given to every member of the group when it is ingested.",
+}
+
+func init() {
+	var gradersFlag []string
+	var outputFlag string
+	var anonymousFlag bool
+	var sectionFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		switch {
+		case len(args) == 0:
+			cmd.Usage()
+			exitUsage()
+		case len(gradersFlag) == 0 && !cmd.Flag("section").Changed:
+			fmt.Fprintln(os.Stderr, "must specify at least one grader with --graders (or a section with --section)")
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		cur, err := user.Current()
+		if err != nil {
+			ctx.Error.Printf("could not get current user: %v\n", err)
+			dev.Fail()
+		}
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		pcodes := args[1:]
+		if len(pcodes) == 0 {
+			for _, p := range asgn.Problems {
+				pcodes = append(pcodes, p.Code)
+			}
+		}
+		validateRubricProblems(ctx, asgn, pcodes)
+
+		if cmd.Flag("section").Changed {
+			section := getSection(ctx, sectionFlag)
+			if len(gradersFlag) == 0 {
+				if section.LeaderUID == "" {
+					ctx.Error.Printf("section %v has no leader; specify graders with --graders\n", section.Code)
+					exitLogic()
+				}
+				gradersFlag = []string{section.LeaderUID}
+			}
+		}
+
+		var graders []*user.User
+		seenGraders := make(map[string]bool)
+		for _, g := range gradersFlag {
+			usr := lookupUser(ctx, g)
+			if seenGraders[usr.Uid] {
+				ctx.Error.Printf("duplicate grader: %v\n", g)
+				exitUsage()
+			}
+			seenGraders[usr.Uid] = true
+			graders = append(graders, usr)
+		}
+
+		// maps student UIDs to the set of
+		// grader UIDs they may not be given to
+		conflicts := make(map[string]map[string]bool)
+		var students []string
+		for uid, s := range ctx.DB.Students {
+			// dropped students have nothing to grade
+			if s.Dropped() || !ctx.DB.InSection(uid, sectionFlag) {
+				continue
+			}
+			students = append(students, uid)
+			conflicts[uid] = make(map[string]bool)
+		}
+		sort.Strings(students)
+
+		var graderUIDs []string
+		graderUnames := make(map[string]string)
+		for _, g := range graders {
+			graderUIDs = append(graderUIDs, g.Uid)
+			graderUnames[g.Uid] = g.Username
+			for _, uid := range readUserBlacklist(ctx, g) {
+				if c, ok := conflicts[uid]; ok {
+					c[g.Uid] = true
+				}
+			}
+		}
+		studentUnames := make(map[string]string)
+		for _, uid := range students {
+			usr, err := user.LookupId(uid)
+			if err != nil {
+				ctx.Warn.Printf("warning: could not look up user with uid %v: %v; ignoring blacklist\n", uid, err)
+				studentUnames[uid] = uid
+				continue
+			}
+			studentUnames[uid] = usr.Username
+			for _, g := range readUserBlacklist(ctx, usr) {
+				conflicts[uid][g] = true
+			}
+		}
+
+		assigned, err := ctx.DB.AssignHandinGraders(asgn, students, graderUIDs, func(s, g string) bool {
+			return conflicts[s][g]
+		})
+		if err != nil {
+			ctx.Error.Printf("could not assign graders: %v\n", err)
+			exitLogic()
+		}
+
+		ga := &kudos.GraderAssignment{
+			Problems:    pcodes,
+			Graders:     assigned,
+			AssignerUID: cur.Uid,
+			Time:        time.Now(),
+		}
+		if anonymousFlag {
+			ga.Tokens = make(map[string]string)
+		}
+
+		// students who share a handin share a rubric,
+		// which is written for the first of them (the
+		// rubric's grades are given to all of them when
+		// it is ingested)
+		owners := ctx.DB.HandinOwners(asgn, students)
+		var names []string
+		for name := range owners {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		// maps grader UIDs to numbers of rubrics
+		counts := make(map[string]int)
+		for _, owner := range names {
+			uid := owners[owner][0]
+			counts[assigned[uid]]++
+			dir := filepath.Join(outputFlag, graderUnames[assigned[uid]])
+			err := os.MkdirAll(dir, 0770)
+			if err != nil {
+				ctx.Error.Printf("could not create output directory: %v\n", err)
+				dev.Fail()
+			}
+
+			var token, suid, name string
+			if anonymousFlag {
+				token, err = ctx.DB.Anonymizer.NewToken(uid)
+				if err != nil {
+					ctx.Error.Printf("could not generate anonymous token: %v\n", err)
+					dev.Fail()
+				}
+				ga.Tokens[uid] = token
+				name = token
+			} else {
+				suid = uid
+				name = studentUnames[uid]
+				if owner != uid {
+					name = "group-" + owner
+				}
+			}
+
+			path := filepath.Join(dir, name)
+			ctx.Verbose.Printf("writing %v\n", path)
+			out, err := os.Create(path)
+			if err != nil {
+				ctx.Error.Printf("could not create rubric: %v\n", err)
+				dev.Fail()
+			}
+			err = kudos.GenerateRubric(out, asgn, suid, token, pcodes...)
+			if err == nil {
+				err = out.Sync()
+			}
+			out.Close()
+			if err != nil {
+				ctx.Error.Printf("could not write rubric %v: %v\n", path, err)
+				dev.Fail()
+			}
+		}
+
+		ctx.DB.AddGraderAssignment(asgn.Code, ga)
+		commitDB(ctx)
+
+		for _, g := range graderUIDs {
+			ctx.Info.Printf("%v: %v rubrics\n", graderUnames[g], counts[g])
+		}
+	}
+	cmdRubricDistribute.Run = f
+	addAllGlobalFlagsTo(cmdRubricDistribute.Flags())
+	cmdRubricDistribute.Flags().StringSliceVarP(&gradersFlag, "graders", "", nil, "comma-separated list of graders (usernames or uids)")
+	cmdRubricDistribute.Flags().StringVarP(&outputFlag, "output", "o", ".", "the directory to write per-grader rubric directories to")
+	cmdRubricDistribute.Flags().BoolVarP(&anonymousFlag, "anonymous", "", false, "store anonymous tokens instead of uids in the rubrics")
+	cmdRubricDistribute.Flags().StringVarP(&sectionFlag, "section", "", "", "only distribute rubrics for students in this section")
+	cmdRubric.AddCommand(cmdRubricDistribute)
+}
+
+var cmdRubricGraders = &cobra.Command{
+	Use:   "graders <assignment>",
+	Short: "Show how rubrics for an assignment were distributed among graders",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+
+		for i, ga := range ctx.DB.GraderAssignments[asgn.Code] {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("problems %v (distributed by %v on %v):\n",
+				strings.Join(ga.Problems, ", "), lookupUsernameForUID(ctx, ga.AssignerUID),
+				ga.Time.Format(kudos.DateFormat))
+
+			var pairs unameUIDPairs
+			for uid := range ga.Graders {
+				pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+			}
+			sort.Sort(pairs)
+			for _, pair := range pairs {
+				grader := lookupUsernameForUID(ctx, ga.Graders[pair.uid])
+				if token, ok := ga.Tokens[pair.uid]; ok {
+					fmt.Printf("\t%v (%v): %v\n", pair.uname, token, grader)
+				} else {
+					fmt.Printf("\t%v: %v\n", pair.uname, grader)
+				}
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdRubricGraders.Run = f
+	addAllGlobalFlagsTo(cmdRubricGraders.Flags())
+	cmdRubric.AddCommand(cmdRubricGraders)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdSection = &cobra.Command{
+	Use:   "section",
+	Short: "Manage the course's sections",
+	Long: "Sections group students (for example, into discussion sections), and " +
+		"may each be led by a TA. Commands which operate on many students, such as " +
+		"show-grade, progress, stats, export grades, and rubric distribute, accept " +
+		"--section to operate only on the students in one section.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+		exitUsage()
+	}
+	cmdSection.Run = f
+	addAllGlobalFlagsTo(cmdSection.Flags())
+	cmdMain.AddCommand(cmdSection)
+}
+
+var cmdSectionCreate = &cobra.Command{
+	Use:   "create <section>",
+	Short: "Create a section",
+}
+
+func init() {
+	var nameFlag string
+	var leaderFlag string
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		code := args[0]
+		if err := kudos.ValidateCode(code); err != nil {
+			ctx.Error.Printf("bad section code %q: %v\n", code, err)
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		s := &kudos.Section{Code: code, Name: nameFlag}
+		if cmd.Flag("leader").Changed {
+			s.LeaderUID = lookupUser(ctx, leaderFlag).Uid
+		}
+		if !ctx.DB.AddSection(s) {
+			ctx.Error.Println("section already exists")
+			exitLogic()
+		}
+		commitDB(ctx)
+	}
+	cmdSectionCreate.Run = f
+	addAllGlobalFlagsTo(cmdSectionCreate.Flags())
+	cmdSectionCreate.Flags().StringVarP(&nameFlag, "name", "", "", "the section's name")
+	cmdSectionCreate.Flags().StringVarP(&leaderFlag, "leader", "", "", "the TA who leads the section")
+	cmdSection.AddCommand(cmdSectionCreate)
+}
+
+var cmdSectionLeader = &cobra.Command{
+	Use:   "leader <section> [<ta>]",
+	Short: "Set the TA who leads a section",
+	Long:  "Set the TA who leads a section, or, if no TA is given, remove its leader.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 && len(args) != 2 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		s := getSection(ctx, args[0])
+		s.LeaderUID = ""
+		if len(args) == 2 {
+			s.LeaderUID = lookupUser(ctx, args[1]).Uid
+		}
+		commitDB(ctx)
+	}
+	cmdSectionLeader.Run = f
+	addAllGlobalFlagsTo(cmdSectionLeader.Flags())
+	cmdSection.AddCommand(cmdSectionLeader)
+}
+
+var cmdSectionAssign = &cobra.Command{
+	Use:   "assign <section> <student> [...]",
+	Short: "Put students in a section",
+	Long:  "Put students in a section, replacing any section they were previously in.",
+}
+
+func init() {
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		section := getSection(ctx, args[0])
+		for _, arg := range args[1:] {
+			s := lookupStudent(ctx, arg)
+			if old := s.student.Section; old != "" && old != section.Code {
+				ctx.Info.Printf("moving student %v from section %v\n", s, old)
+			}
+			// the student and section are
+			// known to exist, so this can't fail
+			ctx.DB.AssignSection(s.usr.Uid, section.Code)
+		}
+		commitDB(ctx)
+	}
+	cmdSectionAssign.Run = f
+	addAllGlobalFlagsTo(cmdSectionAssign.Flags())
+	cmdSection.AddCommand(cmdSectionAssign)
+}
+
+var cmdSectionList = &cobra.Command{
+	Use:   "list",
+	Short: "List the course's sections",
+}
+
+func init() {
+	var showStudentsFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		var codes []string
+		for code := range ctx.DB.Sections {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			s := ctx.DB.Sections[code]
+			uids := ctx.DB.SectionStudents(code)
+			name := code
+			if s.Name != "" {
+				name = fmt.Sprintf("%v (%v)", code, s.Name)
+			}
+			leader := "no leader"
+			if s.LeaderUID != "" {
+				leader = "led by " + lookupUsernameForUID(ctx, s.LeaderUID)
+			}
+			fmt.Printf("%v: %v, %v student(s)\n", name, leader, len(uids))
+
+			if showStudentsFlag {
+				var pairs unameUIDPairs
+				for _, uid := range uids {
+					pairs = append(pairs, unameUIDPair{lookupUsernameForUID(ctx, uid), uid})
+				}
+				sort.Sort(pairs)
+				for _, p := range pairs {
+					fmt.Printf("\t%v\n", p.uname)
+				}
+			}
+		}
+
+		closeDB(ctx)
+	}
+	cmdSectionList.Run = f
+	addAllGlobalFlagsTo(cmdSectionList.Flags())
+	cmdSectionList.Flags().BoolVarP(&showStudentsFlag, "show-students", "", false, "list the students in each section")
+	cmdSection.AddCommand(cmdSectionList)
+}
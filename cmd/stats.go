@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joshlf/kudos/lib/kudos"
+	"github.com/spf13/cobra"
+)
+
+var cmdStats = &cobra.Command{
+	Use:   "stats <assignment>",
+	Short: "Show grade statistics for an assignment",
+	Long: "Show statistics about the grades given on an assignment, both for the " +
+		"assignment's total and for each problem and subproblem. Only complete " +
+		"grades are included, and totals are not adjusted for lateness. With " +
+		"--by-grader, the grades given directly to each problem are also broken " +
+		"down by grader, along with how each grader's mean compares to the mean " +
+		"of all grades given directly to that problem.",
+}
+
+func init() {
+	var byGraderFlag bool
+	var showHistogramsFlag bool
+	var binsFlag int
+	var precisionFlag uint8
+	var sectionFlag string
+
+	stripRegex := regexp.MustCompile(`\.?0*$`)
+	formatFloat := func(f float64) string {
+		s := fmt.Sprintf("%.*f", int(precisionFlag), f)
+		if precisionFlag == 0 {
+			return s
+		}
+		// truncate trailing 0s (and optionally, trailing period)
+		return stripRegex.ReplaceAllString(s, "")
+	}
+
+	formatStats := func(s kudos.Sample) string {
+		if len(s) == 0 {
+			return "no grades"
+		}
+		return fmt.Sprintf("n=%v mean=%v median=%v stddev=%v min=%v max=%v",
+			len(s), formatFloat(s.Mean()), formatFloat(s.Median()), formatFloat(s.StdDev()),
+			formatFloat(s.Min()), formatFloat(s.Max()))
+	}
+
+	formatQuantiles := func(s kudos.Sample) string {
+		var parts []string
+		for _, q := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+			parts = append(parts, fmt.Sprintf("%v%%=%v", 100*q, formatFloat(s.Quantile(q))))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	// the maximum width of a histogram bar
+	const maxBar = 40
+	printHistogram := func(s kudos.Sample, outOf float64, indent string) {
+		counts := s.Histogram(outOf, binsFlag)
+		max := 0
+		for _, c := range counts {
+			if c > max {
+				max = c
+			}
+		}
+		var labels []string
+		width := 0
+		for i := range counts {
+			lo := outOf * float64(i) / float64(binsFlag)
+			hi := outOf * float64(i+1) / float64(binsFlag)
+			l := fmt.Sprintf("[%v, %v)", formatFloat(lo), formatFloat(hi))
+			if i == len(counts)-1 {
+				l = fmt.Sprintf("[%v, %v]", formatFloat(lo), formatFloat(hi))
+			}
+			labels = append(labels, l)
+			if len(l) > width {
+				width = len(l)
+			}
+		}
+		for i, c := range counts {
+			bar := c
+			if max > maxBar {
+				bar = c * maxBar / max
+			}
+			fmt.Printf("%v%-*v | %v %v\n", indent, width, labels[i], strings.Repeat("#", bar), c)
+		}
+	}
+
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		if binsFlag < 1 {
+			ctx.Error.Println("--bins must be positive")
+			exitUsage()
+		}
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		asgn := getAssignment(ctx, args[0], false)
+		if cmd.Flag("section").Changed {
+			getSection(ctx, sectionFlag)
+		}
+		samples := ctx.DB.AssignmentSamples(asgn, sectionFlag)
+
+		// maps uids to usernames
+		graderUnames := make(map[string]string)
+
+		outOf := "out of " + formatFloat(asgn.TotalPoints().Float64())
+		if ec := asgn.ExtraCreditPoints(); ec.Sign() > 0 {
+			outOf += fmt.Sprintf(" plus %v extra credit", formatFloat(ec.Float64()))
+		}
+		fmt.Printf("%v total (%v): %v\n", asgn.Code, outOf, formatStats(samples.Total))
+		if len(samples.Total) > 0 {
+			fmt.Printf("\tquantiles: %v\n", formatQuantiles(samples.Total))
+			printHistogram(samples.Total, asgn.TotalPoints().Float64(), "\t")
+		}
+
+		for _, p := range asgn.Problems {
+			p.TraversePreOrder(func(p kudos.Problem) {
+				path, _ := asgn.FindProblemPathByCode(p.Code)
+				indent := strings.Repeat("\t", len(path))
+				s := samples.Problems[p.Code]
+				outOf := "out of " + formatFloat(p.Points.Float64())
+				if p.ExtraCredit {
+					outOf = "extra credit; " + outOf
+				}
+				fmt.Printf("%v%v (%v): %v\n", indent, p.Code, outOf, formatStats(s))
+				if len(s) > 0 {
+					fmt.Printf("%v\tquantiles: %v\n", indent, formatQuantiles(s))
+					if showHistogramsFlag {
+						printHistogram(s, p.Points.Float64(), indent+"\t")
+					}
+				}
+				if !byGraderFlag || len(samples.Graders[p.Code]) == 0 {
+					return
+				}
+
+				// the mean of all grades given directly to
+				// this problem (which may differ from the
+				// problem's overall mean if some of its
+				// grades were computed from subproblems)
+				var all []float64
+				var pairs unameUIDPairs
+				for uid, gs := range samples.Graders[p.Code] {
+					all = append(all, gs...)
+					g, ok := graderUnames[uid]
+					if !ok {
+						g = lookupUsernameForUID(ctx, uid)
+						graderUnames[uid] = g
+					}
+					pairs = append(pairs, unameUIDPair{g, uid})
+				}
+				sort.Sort(pairs)
+				mean := kudos.NewSample(all).Mean()
+				for _, pair := range pairs {
+					gs := samples.Graders[p.Code][pair.uid]
+					diff := gs.Mean() - mean
+					sign := "+"
+					if diff < 0 {
+						sign = "-"
+						diff = -diff
+					}
+					fmt.Printf("%v\tgrader %v: %v (mean %v%v relative to all graders)\n",
+						indent, pair.uname, formatStats(gs), sign, formatFloat(diff))
+				}
+			})
+		}
+
+		closeDB(ctx)
+	}
+	cmdStats.Run = f
+	addAllGlobalFlagsTo(cmdStats.Flags())
+	cmdStats.Flags().BoolVarP(&byGraderFlag, "by-grader", "", false, "break down each problem's grades by grader")
+	cmdStats.Flags().BoolVarP(&showHistogramsFlag, "show-histograms", "", false, "show a histogram for each problem (a histogram is always shown for the total)")
+	cmdStats.Flags().IntVarP(&binsFlag, "bins", "", 10, "the number of bins to use in histograms")
+	cmdStats.Flags().Uint8VarP(&precisionFlag, "precision", "", 2, "the maximum number of digits of precision to use when formatting floating point values")
+	cmdStats.Flags().StringVarP(&sectionFlag, "section", "", "", "only include students in this section")
+	cmdMain.AddCommand(cmdStats)
+}
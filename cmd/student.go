@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"os/user"
 	"sort"
+	"strings"
 
 	"github.com/joshlf/kudos/lib/dev"
+	"github.com/joshlf/kudos/lib/kudos"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +19,7 @@ var cmdStudent = &cobra.Command{
 }
 
 func init() {
+	var longFlag bool
 	f := func(cmd *cobra.Command, args []string) {
 		if len(args) != 0 {
 			cmd.Usage()
@@ -40,21 +44,31 @@ func init() {
 		// but it would be nice if these were in numerical
 		// as opposed to alphabetical order
 
-		var unames []string
+		var pairs unameUIDPairs
 		for _, u := range uids {
 			uname := lookupUsernameForUID(ctx, u)
-			unames = append(unames, uname)
+			pairs = append(pairs, unameUIDPair{uname, u})
 		}
 
-		sort.Strings(unames)
+		sort.Sort(pairs)
 
-		for _, u := range unames {
-			fmt.Println(u)
+		for _, p := range pairs {
+			s := ctx.DB.Students[p.uid]
+			switch {
+			case longFlag:
+				fmt.Printf("%v\t%v\t%v\t%v\t%v\t%v\n", p.uname, s.Name, s.Email,
+					s.ExternalID, s.Section, s.Enrollment())
+			case s.Enrollment() != kudos.EnrollmentActive:
+				fmt.Printf("%v (%v)\n", p.uname, s.Enrollment())
+			default:
+				fmt.Println(p.uname)
+			}
 		}
 
 	}
 	cmdStudent.Run = f
 	addAllGlobalFlagsTo(cmdStudent.Flags())
+	cmdStudent.Flags().BoolVarP(&longFlag, "long", "l", false, "show each student's name, email, id, section, and enrollment status (tab-separated)")
 	cmdMain.AddCommand(cmdStudent)
 }
 
@@ -147,3 +161,240 @@ func init() {
 	cmdStudentAdd.Flags().BoolVarP(&strictFlag, "strict", "", false, "if any user is not found, the entire operation is aborted")
 	cmdStudent.AddCommand(cmdStudentAdd)
 }
+
+var cmdStudentImport = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Reconcile the students in the course with a roster CSV file",
+	Long: "Import the course roster from a CSV file. The first row must be a header. " +
+		"The column headed \"student\" gives each row's student (by username or UID); " +
+		"the optional columns \"name\", \"email\", \"id\" (for example, a registrar " +
+		"number), \"section\" (which must already exist; see section create), and " +
+		"\"status\" (active, dropped, or auditing; empty means active) give the " +
+		"student's information. Students on the roster who " +
+		"are not in the course are added, and students in the course who are not on " +
+		"the roster are marked as dropped; dropped students' grades and handins are " +
+		"kept. Information which differs from what is already recorded is reported " +
+		"as a conflict and left unchanged unless --force is given. Either the whole " +
+		"roster is imported or, if any row cannot be, nothing is.",
+}
+
+func init() {
+	var dryRunFlag bool
+	var forceFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			ctx.Error.Printf("could not open roster file: %v\n", err)
+			exitLogic()
+		}
+		rows, err := kudos.ParseRosterCSV(file)
+		file.Close()
+		if err != nil {
+			ctx.Error.Printf("could not parse roster file: %v\n", err)
+			exitLogic()
+		}
+
+		// maps uids to the lines on which they appeared
+		lines := make(map[string]int)
+		unames := make(map[string]string)
+		var roster []*kudos.Student
+		failed := 0
+		for _, row := range rows {
+			usr, err := findUser(row.Student)
+			if err != nil {
+				ctx.Error.Printf("line %v: %v\n", row.Line, err)
+				failed++
+				continue
+			}
+			if line, ok := lines[usr.Uid]; ok {
+				ctx.Error.Printf("line %v: student %v already appeared on line %v\n", row.Line, row.Student, line)
+				failed++
+				continue
+			}
+			if sec := row.Info.Section; sec != "" {
+				if _, ok := ctx.DB.Sections[sec]; !ok {
+					ctx.Error.Printf("line %v: no such section: %v\n", row.Line, sec)
+					failed++
+					continue
+				}
+			}
+			lines[usr.Uid] = row.Line
+			unames[usr.Uid] = usr.Username
+			s := row.Info
+			s.UID = usr.Uid
+			roster = append(roster, &s)
+		}
+		if failed > 0 {
+			ctx.Error.Printf("found %v error(s); aborting (no changes saved)\n", failed)
+			closeDB(ctx)
+			exitLogic()
+		}
+
+		uname := func(uid string) string {
+			u, ok := unames[uid]
+			if !ok {
+				u = lookupUsernameForUID(ctx, uid)
+				unames[uid] = u
+			}
+			return u
+		}
+		list := func(uids []string) string {
+			var us []string
+			for _, uid := range uids {
+				us = append(us, uname(uid))
+			}
+			return strings.Join(us, ", ")
+		}
+
+		imp, err := ctx.DB.ImportRoster(roster, forceFlag)
+		if err != nil {
+			// sections were checked above,
+			// so this shouldn't happen
+			ctx.Error.Printf("could not import roster: %v\n", err)
+			exitLogic()
+		}
+		for _, c := range imp.Conflicts {
+			msg := fmt.Sprintf("conflict: %v's %v is %q, but the roster gives %q", uname(c.UID), c.Field, c.Old, c.New)
+			if forceFlag {
+				ctx.Warn.Printf("%v; overwriting\n", msg)
+			} else {
+				ctx.Warn.Printf("%v; use --force to overwrite\n", msg)
+			}
+		}
+		for _, l := range []struct {
+			verb string
+			uids []string
+		}{
+			{"added", imp.Added},
+			{"updated", imp.Updated},
+			{"dropped", imp.Dropped},
+			{"re-enrolled", imp.Reenrolled},
+		} {
+			if len(l.uids) > 0 {
+				fmt.Printf("%v: %v\n", l.verb, list(l.uids))
+			}
+		}
+
+		changed := len(imp.Added) + len(imp.Updated) + len(imp.Dropped) + len(imp.Reenrolled)
+		if dryRunFlag {
+			ctx.Info.Printf("dry run: would change %v student(s)\n", changed)
+			closeDB(ctx)
+			return
+		}
+		if changed == 0 {
+			closeDB(ctx)
+			return
+		}
+		commitDB(ctx)
+		ctx.Info.Printf("changed %v student(s)\n", changed)
+	}
+	cmdStudentImport.Run = f
+	addAllGlobalFlagsTo(cmdStudentImport.Flags())
+	cmdStudentImport.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false, "show the changes which would be made without making them")
+	cmdStudentImport.Flags().BoolVarP(&forceFlag, "force", "f", false, "overwrite conflicting student information")
+	cmdStudent.AddCommand(cmdStudentImport)
+}
+
+var cmdStudentDrop = &cobra.Command{
+	Use:   "drop <student> [...]",
+	Short: "Mark students as having dropped the course",
+	Long: "Mark students as having dropped the course. Their grades and handins " +
+		"are kept, but they are left out of handin init, handin ingest, progress, " +
+		"rubric distribution, and grade exports unless --include-dropped is given " +
+		"(or, for handin ingest, they are named with --student). Use --undo to " +
+		"re-enroll students who were dropped.",
+}
+
+func init() {
+	var undoFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		changed := false
+		for _, arg := range args {
+			s := lookupStudent(ctx, arg)
+			switch {
+			case undoFlag && ctx.DB.ReenrollStudent(s.usr.Uid):
+				changed = true
+			case undoFlag:
+				ctx.Warn.Printf("student %v has not dropped the course\n", s)
+			case ctx.DB.DropStudent(s.usr.Uid):
+				changed = true
+			default:
+				ctx.Warn.Printf("student %v has already dropped the course\n", s)
+			}
+		}
+
+		if changed {
+			commitDB(ctx)
+		} else {
+			closeDB(ctx)
+		}
+	}
+	cmdStudentDrop.Run = f
+	addAllGlobalFlagsTo(cmdStudentDrop.Flags())
+	cmdStudentDrop.Flags().BoolVarP(&undoFlag, "undo", "", false, "re-enroll students who were dropped")
+	cmdStudent.AddCommand(cmdStudentDrop)
+}
+
+var cmdStudentRemove = &cobra.Command{
+	Use:   "remove <student>",
+	Short: "Remove a student and all of their records from the course",
+	Long: "Remove a student from the course, permanently deleting their grades, " +
+		"handin times, extensions, late days, grader assignments, anonymous rubric " +
+		"tokens, and published information. The grade history and regrade requests " +
+		"are kept, as are saved handin files. This cannot be undone; to keep a " +
+		"student's records, use student drop instead.",
+}
+
+func init() {
+	var yesFlag bool
+	f := func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Usage()
+			exitUsage()
+		}
+		ctx := getContext()
+		addCourseConfig(ctx)
+
+		openDB(ctx)
+		defer cleanupDB(ctx)
+
+		s := lookupStudent(ctx, args[0])
+		if !yesFlag && !confirm(fmt.Sprintf("permanently remove student %v and all of their grades and handins?", s)) {
+			ctx.Info.Println("aborting (no changes saved)")
+			closeDB(ctx)
+			return
+		}
+		ctx.DB.RemoveStudent(s.usr.Uid)
+		commitDB(ctx)
+
+		err := ctx.RemovePubStudent(s.usr.Uid)
+		if err != nil {
+			ctx.Error.Printf("could not remove published information: %v\n", err)
+			dev.Fail()
+		}
+	}
+	cmdStudentRemove.Run = f
+	addAllGlobalFlagsTo(cmdStudentRemove.Flags())
+	cmdStudentRemove.Flags().BoolVarP(&yesFlag, "yes", "y", false, "do not ask for confirmation")
+	cmdStudent.AddCommand(cmdStudentRemove)
+}
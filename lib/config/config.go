@@ -23,6 +23,10 @@ var (
 	AssignmentDirPerms    = perm.Parse("rwxrwx---")
 	HooksDirName          = "hooks"
 	HooksDirPerms         = perm.Parse("rwxrwxr-x")
+	RegradeDirName        = "regrade"
+	RegradeDirPerms       = perm.Parse("rwxrwxr-x")
+	LateDaysDirName       = "latedays"
+	LateDaysDirPerms      = perm.Parse("rwxrwxr-x")
 
 	UserConfigFileName    = ".kudosconfig"
 	UserConfigFilePerms   = perm.Parse("rw-r--r--")
@@ -33,6 +37,12 @@ var (
 
 	PreHandinHookFileName  = "pre-handin"
 	PreHandinHookFilePerms = perm.Parse("rw-rw-r--")
+	// the course-wide ignore file, and the suffix
+	// of per-assignment ignore files (named
+	// <assignment>.kudosignore), both of which
+	// live in the hooks directory
+	IgnoreFileName  = "kudosignore"
+	IgnoreFilePerms = perm.Parse("rw-rw-r--")
 
 	DBDirName      = "db"
 	DBDirPerms     = perm.Parse("rwxrwx---")
@@ -41,6 +51,11 @@ var (
 	DBFileName     = "db"
 	DBTempFileName = "db.tmp"
 	DBLockFileName = "lock"
+
+	// students can access their own files,
+	// but cannot list the directory
+	PubStudentsDirName  = "students"
+	PubStudentsDirPerms = perm.Parse("rwxrwx--x")
 )
 
 func IgnoreFile(path string) bool {
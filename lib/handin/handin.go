@@ -1,11 +1,14 @@
 package handin
 
 import (
+	"archive/tar"
 	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	acl "github.com/joshlf/go-acl"
@@ -15,19 +18,195 @@ import (
 
 // PerformFaclHandin performs a handin of the current
 // directory, writing a tar'd and gzip'd version of
-// it to target. If verbose is true, the "-v" flag
-// will be passed to tar, causing it to be verbose.
-func PerformFaclHandin(target string, verbose bool) (err error) {
-	// just in case, since we're passing it to a subcommand
-	target = filepath.Clean(target)
-	flags := "-czf"
-	if verbose {
-		flags = "-cvzf"
+// it to target. The files to hand in are collected
+// as with CollectHandin and checked as with
+// CheckHandin; if either fails, an error is returned
+// and target is left untouched. If verbose is true,
+// the name of each file is printed to stdout as it
+// is archived (in the same format as "tar -v").
+func PerformFaclHandin(target string, ignore *Ignore, reqs Requirements, limits Limits, verbose bool) error {
+	files, err := CollectHandin(ignore)
+	if err != nil {
+		return err
 	}
-	cmd := exec.Command("tar", flags, target, ".")
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+	if err = CheckHandin(files, reqs, limits); err != nil {
+		return err
+	}
+	return WriteHandin(target, files, verbose)
+}
+
+// CheckHandin checks files (which must have been
+// returned by CollectHandin) first against reqs
+// and then against limits, returning the first
+// *RequirementError or *LimitError encountered.
+func CheckHandin(files []File, reqs Requirements, limits Limits) error {
+	if err := reqs.Check(files); err != nil {
+		return err
+	}
+	return limits.Check(files)
+}
+
+// A File is a file, directory, or symlink
+// which is to be handed in.
+type File struct {
+	// the file's name in the handin archive
+	// (for example, "./src/main.c")
+	Name string
+	// the file's size in bytes, which is 0
+	// for directories and symlinks
+	Size int64
+	// whether the file is a directory
+	Dir bool
+
+	path string
+	hdr  *tar.Header
+}
+
+// CollectHandin returns the files in the current
+// directory (including the directory itself) which
+// would be handed in, in lexical order. Files and
+// directories excluded by ignore (which may be nil)
+// are omitted. Special files (such as devices and
+// named pipes) and symlinks which point outside of
+// the current directory cannot be handed in; if any
+// are found, an error is returned.
+func CollectHandin(ignore *Ignore) ([]File, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// resolve symlinks so that we can tell whether
+	// those in the handin point outside of root
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && ignore.Ignored(rel, fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		name := "./"
+		if rel != "." {
+			name += rel
+		}
+		var link string
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			if rel != "." {
+				name += "/"
+			}
+		case mode&os.ModeSymlink != 0:
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if !symlinkWithin(realRoot, path, link) {
+				return fmt.Errorf("%v: symlink points outside of the handin directory", name)
+			}
+		case !mode.IsRegular():
+			return fmt.Errorf("%v: cannot hand in special file", name)
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+		hdr.Name = name
+		files = append(files, File{
+			Name: name,
+			Size: hdr.Size,
+			Dir:  fi.IsDir(),
+			path: path,
+			hdr:  hdr,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// WriteHandin writes a tar'd and gzip'd archive of
+// files (which must have been returned by
+// CollectHandin) to target, which must already
+// exist. If verbose is true, the name of each file
+// is printed to stdout as it is archived.
+func WriteHandin(target string, files []File, verbose bool) (err error) {
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+	}()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for _, file := range files {
+		if verbose {
+			fmt.Println(file.Name)
+		}
+		if err = tw.WriteHeader(file.hdr); err != nil {
+			return err
+		}
+		if file.hdr.Typeflag == tar.TypeReg {
+			if err = copyFile(tw, file.path); err != nil {
+				return fmt.Errorf("%v: %v", file.Name, err)
+			}
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// symlinkWithin returns whether the symlink at path,
+// whose contents are link, points to a file within
+// root, which must not itself contain symlinks.
+func symlinkWithin(root, path, link string) bool {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// the link is dangling (or a link in its
+		// chain can't be read), so just check
+		// where it points lexically
+		if filepath.IsAbs(link) {
+			target = link
+		} else {
+			dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+			if err != nil {
+				return false
+			}
+			target = filepath.Join(dir, link)
+		}
+	}
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
 }
 
 // ExtractHandin extracts the given handin (which must
@@ -92,6 +271,28 @@ func ExtractHandin(handin, target string) (err error) {
 // students are prevented from learning anything about handins
 // other than their own.
 func InitFaclHandin(dir string, uids []string) (err error) {
+	handins := make(map[string][]string)
+	for _, uid := range uids {
+		handins[uid] = []string{uid}
+	}
+	return InitFaclGroupHandin(dir, handins)
+}
+
+// InitFaclGroupHandin is like InitFaclHandin, except that
+// handins may be shared by groups of users. The keys of
+// handins are the names of the handin folders to create
+// (for individual handins, the user's UID), and each value
+// lists the UIDs of the users who share the handin, each
+// of whom is given the ACL entries given to the single
+// user by InitFaclHandin. An example handin directory
+// structure might look like:
+//
+//  proj1/                  (u::rwx,g::rwx,o::r-x)
+//       team1/             (u::rwx,g::rwx,o::---,u:1234:r-x,u:5678:r-x)
+//            handin.tgz    (u::r--,g::r--,o::---,u:1234:-w-,u:5678:-w-)
+//       9012/              (u::rwx,g::rwx,o::---,u:9012:r-x)
+//            handin.tgz    (u::r--,g::r--,o::---,u:9012:-w-)
+func InitFaclGroupHandin(dir string, handins map[string][]string) (err error) {
 	// need world r-x so students can cd in
 	// and write to their handin files
 	mode := perm.Parse("rwxrwxr-x")
@@ -127,8 +328,8 @@ func InitFaclHandin(dir string, uids []string) (err error) {
 	// (or maybe just make global handin dir
 	// g+s at course init?)
 
-	for _, uid := range uids {
-		path := filepath.Join(dir, uid)
+	for name, uids := range handins {
+		path := filepath.Join(dir, name)
 		filepath := filepath.Join(path, config.HandinFileName)
 
 		// make sure to use global err
@@ -142,17 +343,17 @@ func InitFaclHandin(dir string, uids []string) (err error) {
 		// permissions on path are still set explicitly
 		// (relying on os.Mkdir is not enough - umask
 		// might change the permissions)
-		a := append(
-			acl.FromUnix(perm.Parse("rwxrwx---")),
-			acl.Entry{acl.TagUser, uid, perm.ParseSingle("r-x")},
-			acl.Entry{acl.TagMask, "", perm.ParseSingle("rwx")},
-		)
+		a := acl.FromUnix(perm.Parse("rwxrwx---"))
+		for _, uid := range uids {
+			a = append(a, acl.Entry{acl.TagUser, uid, perm.ParseSingle("r-x")})
+		}
+		a = append(a, acl.Entry{acl.TagMask, "", perm.ParseSingle("rwx")})
 		err = acl.Set(path, a)
 		if err != nil {
 			return err
 		}
 
-		err := makeHandinFile(filepath, uid)
+		err := makeHandinFile(filepath, uids)
 		if err != nil {
 			return err
 		}
@@ -161,16 +362,23 @@ func InitFaclHandin(dir string, uids []string) (err error) {
 }
 
 func SaveFaclHandin(handinDir, saveDir, uid string) error {
-	old := filepath.Join(handinDir, uid, config.HandinFileName)
-	new := filepath.Join(saveDir, uid+".tgz")
+	return SaveFaclGroupHandin(handinDir, saveDir, uid, []string{uid})
+}
+
+// SaveFaclGroupHandin is like SaveFaclHandin, but for a handin
+// with the given name shared by the users with the given UIDs
+// (see InitFaclGroupHandin).
+func SaveFaclGroupHandin(handinDir, saveDir, name string, uids []string) error {
+	old := filepath.Join(handinDir, name, config.HandinFileName)
+	new := filepath.Join(saveDir, name+".tgz")
 	err := os.Rename(old, new)
 	if err != nil {
 		return err
 	}
-	return makeHandinFile(old, uid)
+	return makeHandinFile(old, uids)
 }
 
-func makeHandinFile(path, uid string) error {
+func makeHandinFile(path string, uids []string) error {
 	f, err := os.Create(path)
 	f.Close()
 	if err != nil {
@@ -181,11 +389,11 @@ func makeHandinFile(path, uid string) error {
 	// permissions on filepath are still set explicitly
 	// (relying on os.Mkdir is not enough - umask
 	// might change the permissions)
-	a := append(
-		acl.FromUnix(perm.Parse("r--r-----")),
-		acl.Entry{acl.TagUser, uid, perm.ParseSingle("-w-")},
-		acl.Entry{acl.TagMask, "", perm.ParseSingle("rw-")},
-	)
+	a := acl.FromUnix(perm.Parse("r--r-----"))
+	for _, uid := range uids {
+		a = append(a, acl.Entry{acl.TagUser, uid, perm.ParseSingle("-w-")})
+	}
+	a = append(a, acl.Entry{acl.TagMask, "", perm.ParseSingle("rw-")})
 	return acl.Set(path, a)
 }
 
@@ -227,3 +435,76 @@ func InitSetgidHandin(dir string) (err error) {
 	}
 	return nil
 }
+
+// InitFaclDropDir initializes dir as a drop directory
+// into which each of the given users can write files
+// that only they and the owning group can read. If dir
+// does not exist, it is created with the permissions
+// rwxrwxr-x. Then, for each UID which does not already
+// have one, the folder <UID> is created with the
+// permissions rwxrwx--- and the setgid bit set, with
+// an ACL granting write and execute permissions to the
+// user, and with a default ACL so that files created
+// inside of it are readable and writable by the group.
+// Existing folders are left untouched, so InitFaclDropDir
+// can be called again to add new users. An example drop
+// directory structure might look like:
+//
+//  latedays/   (u::rwx,g::rwx,o::r-x)
+//      1234/   (u::rwx,g::rws,o::---,u:1234:-wx,
+//               default:u::rw-,g::rw-,m::rw-,o::---)
+//
+// Since users have no read permission on their folders,
+// they cannot list the files they have written, and since
+// the folders are setgid, files written by users belong to
+// the same group as the folder itself (so that, so long as
+// dir is owned by the course's TA group, TAs can read them).
+func InitFaclDropDir(dir string, uids []string) (err error) {
+	// need world r-x so users can cd in
+	mode := perm.Parse("rwxrwxr-x")
+	err = os.Mkdir(dir, mode)
+	if err == nil {
+		// set permissions explicitly since original
+		// permissions might be masked (by umask)
+		err = os.Chmod(dir, mode)
+	}
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	for _, uid := range uids {
+		path := filepath.Join(dir, uid)
+		err = os.Mkdir(path, perm.Parse("rwxrwx---"))
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		a := append(
+			acl.FromUnix(perm.Parse("rwxrwx---")),
+			acl.Entry{Tag: acl.TagUser, Qualifier: uid, Perms: perm.ParseSingle("-wx")},
+			acl.Entry{Tag: acl.TagMask, Perms: perm.ParseSingle("rwx")},
+		)
+		err = acl.Set(path, a)
+		if err != nil {
+			return err
+		}
+		err = acl.SetDefault(path, append(
+			acl.FromUnix(perm.Parse("rw-rw----")),
+			acl.Entry{Tag: acl.TagMask, Perms: perm.ParseSingle("rw-")},
+		))
+		if err != nil {
+			return err
+		}
+		// set the setgid bit last since setting the
+		// ACL may clear it; the permission bits set
+		// here are consistent with the ACL
+		err = os.Chmod(path, perm.Parse("rwxrwx---")|os.ModeSetgid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 	"os/user"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 
 	acl "github.com/joshlf/go-acl"
@@ -101,7 +103,7 @@ func TestFaclHandin(t *testing.T) {
 	testutil.Must(t, err)
 	testutil.Must(t, os.Chdir(handinPath))
 	defer os.Chdir(pwd)
-	err = PerformFaclHandin(targetFilePath, false)
+	err = PerformFaclHandin(targetFilePath, nil, Requirements{}, Limits{}, false)
 	testutil.Must(t, err)
 
 	/*
@@ -148,6 +150,85 @@ func TestFaclHandin(t *testing.T) {
 	testutil.Must(t, err)
 }
 
+func TestFaclHandinIgnore(t *testing.T) {
+	testDir := testutil.MustTempDir(t, "", "kudos")
+	defer os.RemoveAll(testDir)
+
+	handinPath := filepath.Join(testDir, "to_handin")
+	for _, dir := range []string{"src", "build", ".git"} {
+		testutil.Must(t, os.MkdirAll(filepath.Join(handinPath, dir), 0700))
+	}
+	for _, file := range []string{"src/main.c", "src/main.o", "build/out", ".git/HEAD"} {
+		testutil.Must(t, ioutil.WriteFile(filepath.Join(handinPath, file), []byte(file), 0600))
+	}
+	testutil.Must(t, os.Symlink("src/main.c", filepath.Join(handinPath, "link")))
+
+	ignore, err := ParseIgnore(strings.NewReader("*.o\nbuild/\n.git/\n"))
+	testutil.Must(t, err)
+	targetFilePath := filepath.Join(testDir, config.HandinFileName)
+	f, err := os.Create(targetFilePath)
+	testutil.Must(t, err)
+	f.Close()
+
+	pwd, err := os.Getwd()
+	testutil.Must(t, err)
+	testutil.Must(t, os.Chdir(handinPath))
+	defer os.Chdir(pwd)
+	testutil.Must(t, PerformFaclHandin(targetFilePath, ignore, Requirements{}, Limits{}, false))
+
+	f, err = os.Open(targetFilePath)
+	testutil.Must(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	testutil.Must(t, err)
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		testutil.Must(t, err)
+		names = append(names, hdr.Name)
+		if hdr.Name == "./link" && (hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "src/main.c") {
+			t.Errorf("unexpected header for symlink: %+v", hdr)
+		}
+	}
+	expect := []string{"./", "./link", "./src/", "./src/main.c"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("unexpected tar contents: want %v; got %v", expect, names)
+	}
+
+	// none of these can be handed in, and
+	// a failed handin shouldn't clobber the
+	// previous one
+	fi, err := os.Stat(targetFilePath)
+	testutil.Must(t, err)
+	size := fi.Size()
+	err = PerformFaclHandin(targetFilePath, ignore, Requirements{}, Limits{MaxFiles: 1}, false)
+	if _, ok := err.(*LimitError); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = PerformFaclHandin(targetFilePath, ignore, Requirements{Required: []string{"Makefile"}}, Limits{}, false)
+	if _, ok := err.(*RequirementError); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = PerformFaclHandin(targetFilePath, ignore, Requirements{Forbidden: []string{"**/*.c"}}, Limits{}, false)
+	if _, ok := err.(*RequirementError); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+	testutil.Must(t, os.Symlink("../..", filepath.Join(handinPath, "escape")))
+	testutil.MustError(t, "./escape: symlink points outside of the handin directory", PerformFaclHandin(targetFilePath, ignore, Requirements{}, Limits{}, false))
+	testutil.Must(t, os.Remove(filepath.Join(handinPath, "escape")))
+	testutil.Must(t, syscall.Mkfifo(filepath.Join(handinPath, "fifo"), 0600))
+	testutil.MustError(t, "./fifo: cannot hand in special file", PerformFaclHandin(targetFilePath, ignore, Requirements{}, Limits{}, false))
+	fi, err = os.Stat(targetFilePath)
+	testutil.Must(t, err)
+	if fi.Size() != size {
+		t.Errorf("failed handin modified handin file")
+	}
+}
+
 func TestSetgidHandin(t *testing.T) {
 	testDir := testutil.MustTempDir(t, "", "kudos")
 	defer os.RemoveAll(testDir)
@@ -162,3 +243,95 @@ func TestSetgidHandin(t *testing.T) {
 		t.Errorf("bad handin directory permissions: want %v; got %v", perm.Parse("rwxrwx---"), acl.ToUnix(a))
 	}
 }
+
+func TestFaclDropDir(t *testing.T) {
+	testDir := testutil.MustTempDir(t, "", "kudos")
+	defer os.RemoveAll(testDir)
+
+	usr, err := user.Current()
+	testutil.Must(t, err)
+
+	dir := filepath.Join(testDir, "regrade")
+	testutil.Must(t, InitFaclDropDir(dir, []string{usr.Uid}))
+	// should be idempotent
+	testutil.Must(t, InitFaclDropDir(dir, []string{usr.Uid}))
+
+	path := filepath.Join(dir, usr.Uid)
+	a, err := acl.Get(path)
+	testutil.Must(t, err)
+	expect := acl.ACL{
+		{acl.TagUserObj, "", perm.ParseSingle("rwx")},
+		{acl.TagUser, usr.Uid, perm.ParseSingle("-wx")},
+		{acl.TagGroupObj, "", perm.ParseSingle("rwx")},
+		{acl.TagMask, "", perm.ParseSingle("rwx")},
+		{acl.TagOther, "", 0},
+	}
+	if !reflect.DeepEqual(a, expect) {
+		t.Fatalf("directory has wrong permissions: want %v; got %v", expect, a)
+	}
+	a, err = acl.GetDefault(path)
+	testutil.Must(t, err)
+	expect = acl.ACL{
+		{acl.TagUserObj, "", perm.ParseSingle("rw-")},
+		{acl.TagGroupObj, "", perm.ParseSingle("rw-")},
+		{acl.TagMask, "", perm.ParseSingle("rw-")},
+		{acl.TagOther, "", 0},
+	}
+	if !reflect.DeepEqual(a, expect) {
+		t.Fatalf("directory has wrong default ACL: want %v; got %v", expect, a)
+	}
+	fi, err := os.Stat(path)
+	testutil.Must(t, err)
+	if fi.Mode()&os.ModeSetgid == 0 {
+		t.Errorf("directory does not have setgid bit set")
+	}
+}
+
+func TestFaclGroupHandin(t *testing.T) {
+	testDir := testutil.MustTempDir(t, "", "kudos")
+	defer os.RemoveAll(testDir)
+
+	// the UIDs need not belong to existing users,
+	// but they must be distinct for the ACL to
+	// be valid
+	uids := []string{"1000001", "1000002"}
+	dir := filepath.Join(testDir, "handin")
+	testutil.Must(t, InitFaclGroupHandin(dir, map[string][]string{"team": uids}))
+
+	path := filepath.Join(dir, "team")
+	a, err := acl.Get(path)
+	testutil.Must(t, err)
+	expect := acl.ACL{
+		{acl.TagUserObj, "", perm.ParseSingle("rwx")},
+		{acl.TagUser, uids[0], perm.ParseSingle("r-x")},
+		{acl.TagUser, uids[1], perm.ParseSingle("r-x")},
+		{acl.TagGroupObj, "", perm.ParseSingle("rwx")},
+		{acl.TagMask, "", perm.ParseSingle("rwx")},
+		{acl.TagOther, "", 0},
+	}
+	if !reflect.DeepEqual(a, expect) {
+		t.Fatalf("directory has wrong permissions: want %v; got %v", expect, a)
+	}
+
+	// saving the handin should recreate
+	// the file with the same permissions
+	saveDir := filepath.Join(testDir, "saved")
+	testutil.Must(t, os.Mkdir(saveDir, 0700))
+	testutil.Must(t, SaveFaclGroupHandin(dir, saveDir, "team", uids))
+	_, err = os.Stat(filepath.Join(saveDir, "team.tgz"))
+	testutil.Must(t, err)
+
+	a, err = acl.Get(filepath.Join(path, config.HandinFileName))
+	testutil.Must(t, err)
+	expect = acl.ACL{
+		{acl.TagUserObj, "", os.FileMode(perm.Read)},
+		{acl.TagUser, uids[0], os.FileMode(perm.Write)},
+		{acl.TagUser, uids[1], os.FileMode(perm.Write)},
+		{acl.TagGroupObj, "", os.FileMode(perm.Read)},
+		{acl.TagMask, "", perm.ParseSingle("rw-")},
+		{acl.TagOther, "", 0},
+	}
+	if !reflect.DeepEqual(a, expect) {
+		t.Fatalf("file has wrong permissions: want %v; got %v", expect, a)
+	}
+}
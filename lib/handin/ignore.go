@@ -0,0 +1,164 @@
+package handin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// An Ignore is a list of gitignore-style rules which
+// determine which files are excluded from a handin.
+// Each non-blank line which does not start with '#'
+// is a pattern, which may use the wildcards supported
+// by path.Match. In addition:
+//
+//   - a pattern ending in '/' only matches directories
+//   - a pattern containing a '/' other than at its end
+//     is matched against the path relative to the root
+//     of the handin; other patterns are matched against
+//     the base name of every file and directory
+//   - a "**" path component matches zero or more
+//     directories
+//   - a pattern starting with '!' re-includes files
+//     excluded by earlier patterns; a file cannot be
+//     re-included if its parent directory is excluded
+//
+// When several patterns match a path, the last wins.
+// The zero value is a valid Ignore which ignores
+// nothing.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	// the pattern's components, split on '/'
+	components []string
+	anchored   bool
+	dirOnly    bool
+	negate     bool
+}
+
+// ReadIgnoreFiles parses each of the given files (see
+// ParseIgnore) and returns an Ignore containing their
+// rules in order, so that rules in later files take
+// precedence. Files which do not exist are skipped.
+func ReadIgnoreFiles(paths ...string) (*Ignore, error) {
+	ig := &Ignore{}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		other, err := ParseIgnore(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", p, err)
+		}
+		ig.rules = append(ig.rules, other.rules...)
+	}
+	return ig, nil
+}
+
+// ParseIgnore parses the rules in r.
+func ParseIgnore(r io.Reader) (*Ignore, error) {
+	ig := &Ignore{}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		pattern := strings.TrimRight(s.Text(), " \t\r")
+		if pattern == "" || pattern[0] == '#' {
+			continue
+		}
+		var rule ignoreRule
+		switch {
+		case pattern[0] == '!':
+			rule.negate = true
+			pattern = pattern[1:]
+		case strings.HasPrefix(pattern, `\!`), strings.HasPrefix(pattern, `\#`):
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			rule.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+		if strings.Contains(pattern, "/") {
+			rule.anchored = true
+			pattern = strings.TrimLeft(pattern, "/")
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("line %v: empty pattern", line)
+		}
+		rule.components = strings.Split(pattern, "/")
+		for _, c := range rule.components {
+			// path.Match only reports bad patterns
+			// when matching, so check them now
+			// rather than when walking the handin
+			if _, err := path.Match(c, ""); err != nil {
+				return nil, fmt.Errorf("line %v: bad pattern %q: %v", line, s.Text(), err)
+			}
+		}
+		ig.rules = append(ig.rules, rule)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return ig, nil
+}
+
+// Ignored returns whether the file or directory at
+// the given slash-separated path, which is relative
+// to the root of the handin, should be excluded.
+// It does not consider whether any of the path's
+// parent directories are excluded.
+func (ig *Ignore) Ignored(p string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	components := strings.Split(p, "/")
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		var match bool
+		if rule.anchored {
+			match = matchComponents(rule.components, components)
+		} else {
+			match = matchComponents(rule.components, components[len(components)-1:])
+		}
+		if match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchComponents matches the path components p against
+// the pattern components pattern, where a "**" component
+// matches zero or more path components.
+func matchComponents(pattern, p []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(p); i++ {
+				if matchComponents(pattern[1:], p[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(p) == 0 {
+			return false
+		}
+		// the pattern was validated in ParseIgnore
+		if ok, _ := path.Match(pattern[0], p[0]); !ok {
+			return false
+		}
+		pattern, p = pattern[1:], p[1:]
+	}
+	return len(p) == 0
+}
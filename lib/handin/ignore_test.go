@@ -0,0 +1,59 @@
+package handin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestIgnore(t *testing.T) {
+	ig, err := ParseIgnore(strings.NewReader(`# build outputs
+*.o
+build/
+/TODO
+docs/*.pdf
+**/tmp/**
+!keep.o
+\#notes
+`))
+	testutil.Must(t, err)
+
+	for _, test := range []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"foo.o", false, true},
+		{"src/foo.o", false, true},
+		{"keep.o", false, false},
+		{"src/keep.o", false, false},
+		{"foo.c", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false},
+		{"TODO", false, true},
+		{"src/TODO", false, false},
+		{"docs/a.pdf", false, true},
+		{"docs/sub/a.pdf", false, false},
+		{"src/docs/a.pdf", false, false},
+		{"tmp/a", false, true},
+		{"src/tmp/a/b", false, true},
+		{"src/tmpx/a", false, false},
+		{"#notes", false, true},
+	} {
+		if ig.Ignored(test.path, test.isDir) != test.ignored {
+			t.Errorf("unexpected result for %v (directory: %v): got %v; want %v",
+				test.path, test.isDir, !test.ignored, test.ignored)
+		}
+	}
+
+	if (*Ignore)(nil).Ignored("foo", false) {
+		t.Errorf("nil Ignore ignored file")
+	}
+
+	_, err = ParseIgnore(strings.NewReader("*.o\n[a-"))
+	testutil.MustError(t, `line 2: bad pattern "[a-": syntax error in pattern`, err)
+	_, err = ParseIgnore(strings.NewReader("/"))
+	testutil.MustError(t, "line 1: empty pattern", err)
+}
@@ -0,0 +1,204 @@
+package handin
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits restricts the contents of a handin.
+// A zero value for any field means that there
+// is no such limit.
+type Limits struct {
+	// the total bytes in all files
+	MaxSize int64
+	// the bytes in any one file
+	MaxFileSize int64
+	// the number of files (not
+	// counting directories)
+	MaxFiles int
+}
+
+// the maximum number of offenders
+// reported in a LimitError
+const maxOffenders = 10
+
+// A LimitError describes how a handin exceeds its Limits.
+type LimitError struct {
+	// a description of each limit which was exceeded
+	Exceeded []string
+	// the files and top-level directories which
+	// contribute most to the limits being exceeded,
+	// from most to least
+	Offenders []Offender
+}
+
+// An Offender is a file or top-level directory
+// which contributes to a LimitError.
+type Offender struct {
+	Name string
+	// for directories, Size and Files
+	// include all of their contents
+	Size  int64
+	Files int
+	Dir   bool
+}
+
+func (e *LimitError) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "handin exceeds limits: %v", strings.Join(e.Exceeded, "; "))
+	if len(e.Offenders) > 0 {
+		buf.WriteString("\nlargest offenders:")
+	}
+	for _, o := range e.Offenders {
+		if o.Dir {
+			fmt.Fprintf(&buf, "\n\t%v (%v in %v file(s))", o.Name, FormatSize(o.Size), o.Files)
+		} else {
+			fmt.Fprintf(&buf, "\n\t%v (%v)", o.Name, FormatSize(o.Size))
+		}
+	}
+	return buf.String()
+}
+
+// Check checks files (which must have been returned by
+// CollectHandin) against l, returning a *LimitError if
+// any limits are exceeded.
+func (l Limits) Check(files []File) error {
+	var size int64
+	var count int
+	var big []Offender
+	for _, f := range files {
+		if f.Dir {
+			continue
+		}
+		size += f.Size
+		count++
+		if l.MaxFileSize > 0 && f.Size > l.MaxFileSize {
+			big = append(big, Offender{Name: f.Name, Size: f.Size, Files: 1})
+		}
+	}
+
+	var e LimitError
+	if len(big) > 0 {
+		e.Exceeded = append(e.Exceeded, fmt.Sprintf("%v file(s) larger than the maximum file size of %v",
+			len(big), FormatSize(l.MaxFileSize)))
+		sort.Sort(offendersBySize(big))
+		e.Offenders = big
+	}
+	tooBig := l.MaxSize > 0 && size > l.MaxSize
+	if tooBig {
+		e.Exceeded = append(e.Exceeded, fmt.Sprintf("total size of %v is larger than the maximum of %v",
+			FormatSize(size), FormatSize(l.MaxSize)))
+	}
+	tooMany := l.MaxFiles > 0 && count > l.MaxFiles
+	if tooMany {
+		e.Exceeded = append(e.Exceeded, fmt.Sprintf("%v files is more than the maximum of %v",
+			count, l.MaxFiles))
+	}
+	if len(e.Exceeded) == 0 {
+		return nil
+	}
+
+	if tooBig || tooMany {
+		// when the handin as a whole is too large,
+		// the culprit is usually a single directory
+		// (such as a build directory), so report
+		// totals for each top-level entry
+		tops := topLevelOffenders(files)
+		if tooBig {
+			sort.Sort(offendersBySize(tops))
+		} else {
+			sort.Sort(offendersByFiles(tops))
+		}
+		// files which are too large are
+		// already reported at the top
+		seen := make(map[string]bool)
+		for _, o := range e.Offenders {
+			seen[o.Name] = true
+		}
+		for _, o := range tops {
+			// entries which contribute nothing to
+			// the exceeded limit aren't offenders
+			if seen[o.Name] || (tooBig && o.Size == 0) || (!tooBig && o.Files == 0) {
+				continue
+			}
+			e.Offenders = append(e.Offenders, o)
+		}
+	}
+	if len(e.Offenders) > maxOffenders {
+		e.Offenders = e.Offenders[:maxOffenders]
+	}
+	return &e
+}
+
+// topLevelOffenders returns an Offender for each file
+// and directory directly inside of the handin's root.
+func topLevelOffenders(files []File) []Offender {
+	var tops []Offender
+	index := make(map[string]int)
+	for _, f := range files {
+		if f.Name == "./" {
+			continue
+		}
+		top := strings.TrimPrefix(f.Name, "./")
+		if i := strings.Index(top, "/"); i >= 0 {
+			top = top[:i+1]
+		}
+		top = "./" + top
+		i, ok := index[top]
+		if !ok {
+			i = len(tops)
+			index[top] = i
+			tops = append(tops, Offender{Name: top, Dir: f.Dir})
+		}
+		if !f.Dir {
+			tops[i].Size += f.Size
+			tops[i].Files++
+		}
+	}
+	return tops
+}
+
+// to make sorting Offenders easier;
+// ties are broken by name so that
+// the order is deterministic
+type offendersBySize []Offender
+
+func (o offendersBySize) Len() int      { return len(o) }
+func (o offendersBySize) Swap(i, j int) { o[i], o[j] = o[j], o[i] }
+func (o offendersBySize) Less(i, j int) bool {
+	if o[i].Size != o[j].Size {
+		return o[i].Size > o[j].Size
+	}
+	return o[i].Name < o[j].Name
+}
+
+type offendersByFiles []Offender
+
+func (o offendersByFiles) Len() int      { return len(o) }
+func (o offendersByFiles) Swap(i, j int) { o[i], o[j] = o[j], o[i] }
+func (o offendersByFiles) Less(i, j int) bool {
+	if o[i].Files != o[j].Files {
+		return o[i].Files > o[j].Files
+	}
+	return o[i].Name < o[j].Name
+}
+
+// FormatSize formats n bytes in the largest
+// unit (B, KB, MB, or GB, each of which is
+// a power of 1024) in which it is at least 1,
+// rounded to one decimal place.
+func FormatSize(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		bytes  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n >= u.bytes {
+			s := strconv.FormatFloat(float64(n)/float64(u.bytes), 'f', 1, 64)
+			return strings.TrimSuffix(s, ".0") + u.suffix
+		}
+	}
+	return fmt.Sprintf("%vB", n)
+}
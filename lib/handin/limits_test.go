@@ -0,0 +1,67 @@
+package handin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	files := []File{
+		{Name: "./", Dir: true},
+		{Name: "./big", Size: 600},
+		{Name: "./build/", Dir: true},
+		{Name: "./build/a", Size: 300},
+		{Name: "./build/b", Size: 300},
+		{Name: "./build/c", Size: 300},
+		{Name: "./main.c", Size: 100},
+	}
+	for i, test := range []struct {
+		limits    Limits
+		exceeded  int
+		offenders []string
+	}{
+		{Limits{}, 0, nil},
+		{Limits{MaxSize: 1600, MaxFileSize: 600, MaxFiles: 5}, 0, nil},
+		{Limits{MaxFileSize: 200}, 1, []string{"./big", "./build/a", "./build/b", "./build/c"}},
+		{Limits{MaxSize: 1000}, 1, []string{"./build/", "./big", "./main.c"}},
+		{Limits{MaxFiles: 4}, 1, []string{"./build/", "./big", "./main.c"}},
+		{Limits{MaxSize: 1000, MaxFileSize: 500, MaxFiles: 4}, 3, []string{"./big", "./build/", "./main.c"}},
+	} {
+		err := test.limits.Check(files)
+		if test.exceeded == 0 {
+			if err != nil {
+				t.Errorf("test case %v: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		e, ok := err.(*LimitError)
+		if !ok {
+			t.Errorf("test case %v: unexpected error: %v", i, err)
+			continue
+		}
+		var offenders []string
+		for _, o := range e.Offenders {
+			offenders = append(offenders, o.Name)
+		}
+		if len(e.Exceeded) != test.exceeded || !reflect.DeepEqual(offenders, test.offenders) {
+			t.Errorf("test case %v: unexpected error: got %v exceeded limits and offenders %v; want %v and %v",
+				i, len(e.Exceeded), offenders, test.exceeded, test.offenders)
+		}
+	}
+
+	err := Limits{MaxSize: 1000}.Check(files)
+	expect := "handin exceeds limits: total size of 1.6KB is larger than the maximum of 1000B\n" +
+		"largest offenders:\n\t./build/ (900B in 3 file(s))\n\t./big (600B)\n\t./main.c (100B)"
+	if err == nil || err.Error() != expect {
+		t.Errorf("unexpected error message: got %q; want %q", err, expect)
+	}
+
+	for n, s := range map[int64]string{
+		0: "0B", 1023: "1023B", 1024: "1KB", 1536: "1.5KB",
+		10 << 20: "10MB", 3 << 29: "1.5GB",
+	} {
+		if FormatSize(n) != s {
+			t.Errorf("unexpected formatting of %v: got %v; want %v", n, FormatSize(n), s)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package handin
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Requirements restricts which files a handin must and
+// must not contain. Each pattern is matched against the
+// slash-separated path of each file and directory
+// relative to the root of the handin; its components may
+// use the wildcards supported by path.Match, and a "**"
+// component matches zero or more directories (so, for
+// example, "**/*.o" matches a file ending in ".o"
+// anywhere in the handin).
+type Requirements struct {
+	// each pattern must match at
+	// least one file or directory
+	Required []string
+	// no file or directory may
+	// match any of these patterns
+	Forbidden []string
+}
+
+// ValidatePattern checks that pattern is a valid
+// pattern for use in Requirements.
+func ValidatePattern(pattern string) error {
+	switch {
+	case pattern == "":
+		return fmt.Errorf("must be non-empty")
+	case strings.HasPrefix(pattern, "/"):
+		return fmt.Errorf("must be relative")
+	}
+	for _, c := range strings.Split(pattern, "/") {
+		if _, err := path.Match(c, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A RequirementError describes how a
+// handin fails to meet its Requirements.
+type RequirementError struct {
+	// the required patterns which
+	// matched no files
+	Missing []string
+	// the names of the files which matched forbidden
+	// patterns, and the patterns they matched
+	Forbidden        []string
+	ForbiddenPattern []string
+}
+
+func (e *RequirementError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString("handin does not meet the assignment's requirements:")
+	for _, p := range e.Missing {
+		fmt.Fprintf(&buf, "\n\tno file matches required pattern %v", p)
+	}
+	for i, name := range e.Forbidden {
+		fmt.Fprintf(&buf, "\n\t%v matches forbidden pattern %v", name, e.ForbiddenPattern[i])
+	}
+	return buf.String()
+}
+
+// Check checks files (which must have been returned by
+// CollectHandin) against r, returning a *RequirementError
+// if any requirements are not met. The patterns in r must
+// be valid (see ValidatePattern).
+func (r Requirements) Check(files []File) error {
+	var e RequirementError
+	matched := make([]bool, len(r.Required))
+	for _, f := range files {
+		if f.Name == "./" {
+			continue
+		}
+		components := strings.Split(strings.TrimSuffix(strings.TrimPrefix(f.Name, "./"), "/"), "/")
+		for i, p := range r.Required {
+			if !matched[i] && matchComponents(strings.Split(p, "/"), components) {
+				matched[i] = true
+			}
+		}
+		for _, p := range r.Forbidden {
+			if matchComponents(strings.Split(p, "/"), components) {
+				e.Forbidden = append(e.Forbidden, f.Name)
+				e.ForbiddenPattern = append(e.ForbiddenPattern, p)
+				break
+			}
+		}
+	}
+	for i, p := range r.Required {
+		if !matched[i] {
+			e.Missing = append(e.Missing, p)
+		}
+	}
+	if len(e.Missing) == 0 && len(e.Forbidden) == 0 {
+		return nil
+	}
+	return &e
+}
@@ -0,0 +1,64 @@
+package handin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestRequirements(t *testing.T) {
+	files := []File{
+		{Name: "./", Dir: true},
+		{Name: "./README"},
+		{Name: "./build/", Dir: true},
+		{Name: "./build/main.o"},
+		{Name: "./src/", Dir: true},
+		{Name: "./src/main.go"},
+		{Name: "./src/util/", Dir: true},
+		{Name: "./src/util/util.o"},
+	}
+	for i, test := range []struct {
+		reqs      Requirements
+		missing   []string
+		forbidden []string
+	}{
+		{Requirements{}, nil, nil},
+		{Requirements{Required: []string{"README", "src/*.go", "src/util"}}, nil, nil},
+		{Requirements{Required: []string{"README", "*.go", "src/*_test.go"}}, []string{"*.go", "src/*_test.go"}, nil},
+		{Requirements{Forbidden: []string{"*.o"}}, nil, nil},
+		{Requirements{Forbidden: []string{"**/*.o"}}, nil, []string{"./build/main.o", "./src/util/util.o"}},
+		{Requirements{Required: []string{"Makefile"}, Forbidden: []string{"build", "**/*.o"}},
+			[]string{"Makefile"}, []string{"./build/", "./build/main.o", "./src/util/util.o"}},
+	} {
+		err := test.reqs.Check(files)
+		if test.missing == nil && test.forbidden == nil {
+			if err != nil {
+				t.Errorf("test case %v: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		e, ok := err.(*RequirementError)
+		if !ok {
+			t.Errorf("test case %v: unexpected error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(e.Missing, test.missing) || !reflect.DeepEqual(e.Forbidden, test.forbidden) {
+			t.Errorf("test case %v: got missing %v and forbidden %v; want %v and %v",
+				i, e.Missing, e.Forbidden, test.missing, test.forbidden)
+		}
+	}
+
+	err := Requirements{Required: []string{"README", "Makefile"}, Forbidden: []string{"**/*.o"}}.Check(files[:4])
+	expect := "handin does not meet the assignment's requirements:\n" +
+		"\tno file matches required pattern Makefile\n" +
+		"\t./build/main.o matches forbidden pattern **/*.o"
+	if err == nil || err.Error() != expect {
+		t.Errorf("unexpected error message: got %q; want %q", err, expect)
+	}
+
+	testutil.Must(t, ValidatePattern("src/**/*.go"))
+	testutil.MustError(t, "must be non-empty", ValidatePattern(""))
+	testutil.MustError(t, "must be relative", ValidatePattern("/README"))
+	testutil.MustError(t, "syntax error in pattern", ValidatePattern("[a-"))
+}
@@ -0,0 +1,181 @@
+package kudos
+
+import (
+	"fmt"
+	"sort"
+)
+
+// An AggregateRule determines how the grades for a
+// problem's subproblems are combined into a grade
+// for the problem.
+type AggregateRule string
+
+const (
+	// The grade is the sum of the subproblems' grades,
+	// and the problem's points are the sum of the
+	// subproblems' points (not including extra credit
+	// subproblems).
+	AggregateSum AggregateRule = "sum"
+	// The grade is the sum of the BestCount highest
+	// subproblem grades (for example, "answer 3 of
+	// the following 5"). The subproblems must all be
+	// worth the same number of points, and the
+	// problem's points are BestCount times that
+	// number.
+	AggregateBest AggregateRule = "best"
+	// The grade is the highest, lowest, or average
+	// subproblem grade. The subproblems and the problem
+	// itself must all be worth the same number of points.
+	AggregateMax     AggregateRule = "max"
+	AggregateMin     AggregateRule = "min"
+	AggregateAverage AggregateRule = "average"
+)
+
+func (r AggregateRule) sum() bool { return r == "" || r == AggregateSum }
+
+func (r AggregateRule) valid() bool {
+	switch r {
+	case AggregateSum, AggregateBest, AggregateMax, AggregateMin, AggregateAverage:
+		return true
+	}
+	return false
+}
+
+// DescribeAggregate returns a human-readable description
+// of how p's subproblems are combined (for example, "best
+// 3 of 5"), or the empty string if they are summed.
+func (p Problem) DescribeAggregate() string {
+	switch {
+	case p.Aggregate.sum():
+		return ""
+	case p.Aggregate == AggregateBest:
+		return fmt.Sprintf("best %v of %v", p.BestCount, len(p.Subproblems))
+	}
+	return string(p.Aggregate)
+}
+
+// aggregateTotal computes p's total given the totals of
+// its graded subproblems (in any order). available is the
+// number of subproblems which could have been graded (that
+// is, which have not been excused). Even for rules which
+// only count some subproblems (such as AggregateBest and
+// AggregateMax), the total is only complete once every
+// available subproblem has been graded, since until then,
+// an ungraded subproblem might turn out to be one of those
+// which counts.
+func (p Problem) aggregateTotal(totals []Decimal, available int) (total Decimal, ok bool) {
+	if available == 0 {
+		return Decimal{}, true
+	}
+	if len(totals) < available {
+		return Decimal{}, false
+	}
+	switch p.Aggregate {
+	case AggregateBest:
+		n := p.BestCount
+		if n > available {
+			n = available
+		}
+		sort.Sort(sort.Reverse(decimals(totals)))
+		for _, t := range totals[:n] {
+			total = total.Add(t)
+		}
+		return total, true
+	case AggregateMax, AggregateMin, AggregateAverage:
+		sort.Sort(decimals(totals))
+		switch p.Aggregate {
+		case AggregateMax:
+			return totals[len(totals)-1], true
+		case AggregateMin:
+			return totals[0], true
+		}
+		for _, t := range totals {
+			total = total.Add(t)
+		}
+		return total.DivInt(int64(len(totals))), true
+	}
+	panic("lib/kudos: Problem.aggregateTotal: bad aggregate rule")
+}
+
+// aggregateOutOf computes the number of points p's total
+// is out of given the number of subproblems which have
+// not been excused (see aggregateTotal).
+func (p Problem) aggregateOutOf(available int) Decimal {
+	if available == 0 {
+		return Decimal{}
+	}
+	if p.Aggregate == AggregateBest && available < p.BestCount {
+		return p.Points.MulInt(int64(available)).DivInt(int64(p.BestCount))
+	}
+	return p.Points
+}
+
+// aggregateProblemTotal computes a's total on p, which
+// must have subproblems and a rule other than AggregateSum.
+// Excused subproblems are left out entirely.
+func (a *AssignmentGrade) aggregateProblemTotal(asgn *Assignment, p Problem) (total Decimal, ok bool) {
+	var totals []Decimal
+	available := 0
+	for _, pp := range p.Subproblems {
+		if _, ok := a.Excusal(asgn, pp.Code); ok {
+			continue
+		}
+		available++
+		if g, ok := a.ProblemTotal(asgn, pp.Code); ok {
+			totals = append(totals, g)
+		}
+	}
+	return p.aggregateTotal(totals, available)
+}
+
+// aggregateProblemOutOf is like aggregateProblemTotal,
+// but computes the number of points a's total on p is
+// out of.
+func (a *AssignmentGrade) aggregateProblemOutOf(asgn *Assignment, p Problem) Decimal {
+	available := 0
+	for _, pp := range p.Subproblems {
+		if _, ok := a.Excusal(asgn, pp.Code); !ok {
+			available++
+		}
+	}
+	return p.aggregateOutOf(available)
+}
+
+// assumes that p has subproblems; extraCredit
+// is whether p is (or is a subproblem of) an
+// extra credit problem
+func validateAggregate(p parseableProblem, extraCredit bool) error {
+	rule := p.aggregate()
+	if !rule.valid() {
+		return fmt.Errorf("problem %v has unknown aggregate rule %q", p.code(), rule)
+	}
+	subs := p.subproblems()
+	switch {
+	case rule != AggregateBest && p.Best != nil:
+		return fmt.Errorf("problem %v specifies best count but aggregate rule is not best", p.code())
+	case rule == AggregateBest && p.Best == nil:
+		return fmt.Errorf("problem %v must specify best count", p.code())
+	case rule == AggregateBest && (*p.Best < 1 || *p.Best > len(subs)):
+		return fmt.Errorf("problem %v's best count must be between 1 and its number of subproblems", p.code())
+	}
+	if rule == AggregateSum {
+		return nil
+	}
+
+	for _, pp := range subs {
+		if pp.extraCredit() && !extraCredit {
+			return fmt.Errorf("problem %v has extra credit subproblems; only sum aggregate rule may be used", p.code())
+		}
+		if pp.points() != subs[0].points() {
+			return fmt.Errorf("problem %v's subproblems must all have the same points value for aggregate rule %v", p.code(), rule)
+		}
+	}
+	expect := subs[0].points()
+	if rule == AggregateBest {
+		expect = expect.MulInt(int64(*p.Best))
+	}
+	if p.points() != expect {
+		return fmt.Errorf("problem %v's points value must be %v for aggregate rule %v", p.code(), expect, rule)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package kudos
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+const aggregateTestAssignment = `{"code":"a",
+	"handins":[{"due":"Jul 4, 2015 at 12:00am (EST)","problems":["best","max","min","avg"]}],
+	"problems":[
+		{"code":"best","points":20,"aggregate":"best","best":2,"subproblems":[
+			{"code":"b1","points":10},
+			{"code":"b2","points":10},
+			{"code":"b3","points":10}
+		]},
+		{"code":"max","points":10,"aggregate":"max","subproblems":[
+			{"code":"x1","points":10},
+			{"code":"x2","points":10}
+		]},
+		{"code":"min","points":10,"aggregate":"min","subproblems":[
+			{"code":"n1","points":10},
+			{"code":"n2","points":10}
+		]},
+		{"code":"avg","points":10,"aggregate":"average","subproblems":[
+			{"code":"v1","points":10},
+			{"code":"v2","points":10}
+		]}
+	]}`
+
+func TestAggregate(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(aggregateTestAssignment))
+	testutil.Must(t, err)
+	if asgn.TotalPoints() != dec(50) {
+		t.Fatalf("unexpected total points: got %v; want 50", asgn.TotalPoints())
+	}
+	if p, _ := asgn.FindProblemByCode("best"); p.DescribeAggregate() != "best 2 of 3" {
+		t.Errorf("unexpected description: got %q; want %q", p.DescribeAggregate(), "best 2 of 3")
+	}
+
+	d := NewDB()
+	d.AddAssignment(asgn)
+	editor := GradeEditor{UID: "100"}
+	set := func(problem string, grade float64) {
+		testutil.Must(t, d.SetGrade(asgn, "0", problem, ProblemGrade{Grade: dec(grade)}, false, editor))
+	}
+	checkProblem := func(problem string, expect float64, expectOK bool) {
+		g := d.Grades[asgn.Code]["0"]
+		total, ok := g.ProblemTotal(asgn, problem)
+		if total != dec(expect) || ok != expectOK {
+			t.Errorf("unexpected total for %v: got %v (%v); want %v (%v)", problem, total, ok, expect, expectOK)
+		}
+	}
+	checkProgress := func(expectGraded, expectTotal int) {
+		graded, total, _ := d.GradingProgress(asgn, []string{"0"})
+		if graded != expectGraded || total != expectTotal {
+			t.Errorf("unexpected progress: got %v/%v; want %v/%v", graded, total, expectGraded, expectTotal)
+		}
+	}
+
+	checkProgress(0, 9)
+	set("b1", 4)
+	checkProblem("best", 0, false)
+	set("b3", 9)
+	// the ungraded subproblem might be
+	// one of the best two, so it's needed
+	checkProblem("best", 0, false)
+	checkProgress(2, 9)
+	set("b2", 6)
+	checkProblem("best", 15, true)
+
+	set("x2", 3)
+	checkProblem("max", 0, false)
+	set("x1", 7)
+	checkProblem("max", 7, true)
+
+	set("n1", 8)
+	checkProblem("min", 0, false)
+	set("n2", 5)
+	checkProblem("min", 5, true)
+
+	set("v1", 8)
+	checkProblem("avg", 0, false)
+	checkProgress(8, 9)
+	set("v2", 5)
+	checkProblem("avg", 6.5, true)
+	checkProgress(9, 9)
+
+	g := d.Grades[asgn.Code]["0"]
+	if total, ok := g.Total(asgn); !ok || total != dec(33.5) {
+		t.Errorf("unexpected total: got %v (%v); want 33.5 (true)", total, ok)
+	}
+
+	// excused subproblems are left out
+	d.Excuse(asgn, "0", "b3", Excusal{})
+	d.Excuse(asgn, "0", "b2", Excusal{})
+	d.Excuse(asgn, "0", "n2", Excusal{})
+	g = d.Grades[asgn.Code]["0"]
+	checkProblem("best", 4, true)
+	checkProblem("min", 8, true)
+	if outOf := g.ProblemOutOf(asgn, "best"); outOf != dec(10) {
+		t.Errorf("unexpected out of for best: got %v; want 10", outOf)
+	}
+	if outOf := g.OutOf(asgn); outOf != dec(40) {
+		t.Errorf("unexpected out of: got %v; want 40", outOf)
+	}
+}
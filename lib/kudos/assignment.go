@@ -9,12 +9,40 @@ type Assignment struct {
 	Handins []Handin
 
 	Problems []Problem
+
+	// LatePolicy is nil if the assignment
+	// uses the course's late policy
+	LatePolicy *LatePolicy
+
+	// ExtraCreditCap is the maximum percentage
+	// by which a student's total may exceed the
+	// total it is out of (for example, 10 means
+	// that totals are capped at 110%); it is
+	// nil if totals are not capped
+	ExtraCreditCap *float64
+
+	// Groups is true if students work on the
+	// assignment in groups (see Group)
+	Groups bool
 }
 
 type Handin struct {
 	Code     string
 	Due      time.Time
 	Problems []string
+
+	// Limits on the contents of a handin, which
+	// are checked when students hand in; each
+	// is 0 if there is no limit
+	MaxSize     int64 // total bytes in all files
+	MaxFileSize int64 // bytes in any one file
+	MaxFiles    int
+
+	// Patterns (see handin.Requirements) which
+	// a handin's files must and must not match,
+	// which are checked when students hand in
+	Required  []string
+	Forbidden []string
 }
 
 type Problem struct {
@@ -24,10 +52,33 @@ type Problem struct {
 	RubricCommentTemplate string
 
 	// If this problem has subproblems,
-	// Points is the sum of the point
-	// values of all subproblems.
-	Points      float64
+	// Points is determined by Aggregate
+	// (see AggregateRule).
+	Points      Decimal
 	Subproblems []Problem
+
+	// Aggregate determines how the grades for
+	// the problem's subproblems are combined into
+	// a grade for the problem; the empty string
+	// is equivalent to AggregateSum. BestCount is
+	// only used if Aggregate is AggregateBest.
+	Aggregate AggregateRule
+	BestCount int
+
+	// If Scale is non-nil, the problem is
+	// graded using Scale's symbols rather
+	// than numeric grades.
+	Scale *Scale
+
+	// Points for extra credit problems count
+	// toward the points a student receives,
+	// but not toward the points they are out
+	// of. Extra credit problems which have not
+	// been graded are treated as worth 0 points
+	// rather than as incomplete. All subproblems
+	// of an extra credit problem are themselves
+	// extra credit.
+	ExtraCredit bool
 }
 
 func FindAssignmentByCode(as []*Assignment, code string) (a *Assignment, ok bool) {
@@ -137,10 +188,45 @@ func (p Problem) TraversePostOrder(f func(p Problem)) {
 	walkFn(p)
 }
 
-func (a *Assignment) TotalPoints() float64 {
-	total := 0.0
+// TotalPoints returns the total number of points
+// the assignment is out of, not including extra
+// credit problems.
+func (a *Assignment) TotalPoints() Decimal {
+	var total Decimal
 	for _, p := range a.Problems {
-		total += p.Points
+		if !p.ExtraCredit {
+			total = total.Add(p.Points)
+		}
+	}
+	return total
+}
+
+// ExtraCreditPoints returns the total number of
+// points available from extra credit problems.
+func (a *Assignment) ExtraCreditPoints() Decimal {
+	var total Decimal
+	var walkFn func(problems []Problem)
+	walkFn = func(problems []Problem) {
+		for _, p := range problems {
+			if p.ExtraCredit {
+				total = total.Add(p.Points)
+			} else {
+				walkFn(p.Subproblems)
+			}
+		}
+	}
+	walkFn(a.Problems)
+	return total
+}
+
+// capTotal caps total at the maximum allowed by
+// a.ExtraCreditCap, given that it is out of outOf.
+func (a *Assignment) capTotal(total, outOf Decimal) Decimal {
+	if a.ExtraCreditCap == nil {
+		return total
+	}
+	if max := outOf.Add(outOf.MulFloat(*a.ExtraCreditCap / 100)); total.Cmp(max) > 0 {
+		return max
 	}
 	return total
 }
@@ -7,9 +7,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/joshlf/kudos/lib/config"
+	"github.com/joshlf/kudos/lib/handin"
 )
 
 // NOTE: All of the convenience methods to retrieve
@@ -28,6 +30,14 @@ type parseableHandin struct {
 	Code     *string  `json:"code"`
 	Due      *date    `json:"due"`
 	Problems []string `json:"problems"`
+	// see Handin.MaxSize, Handin.MaxFileSize,
+	// and Handin.MaxFiles
+	MaxSize     *size `json:"max_size"`
+	MaxFileSize *size `json:"max_file_size"`
+	MaxFiles    *int  `json:"max_files"`
+	// see Handin.Required and Handin.Forbidden
+	Required  []string `json:"required"`
+	Forbidden []string `json:"forbidden"`
 }
 
 // Convert p to an exported Handin type.
@@ -38,6 +48,17 @@ func (p parseableHandin) toHandin() (hh Handin) {
 	hh.Code = p.code()
 	hh.Due = p.due()
 	hh.Problems = p.problems()
+	if p.MaxSize != nil {
+		hh.MaxSize = int64(*p.MaxSize)
+	}
+	if p.MaxFileSize != nil {
+		hh.MaxFileSize = int64(*p.MaxFileSize)
+	}
+	if p.MaxFiles != nil {
+		hh.MaxFiles = *p.MaxFiles
+	}
+	hh.Required = append([]string(nil), p.Required...)
+	hh.Forbidden = append([]string(nil), p.Forbidden...)
 	return
 }
 
@@ -69,25 +90,55 @@ type parseableProblem struct {
 	Code                  *string            `json:"code"`
 	Name                  *string            `json:"name"`
 	RubricCommentTemplate *string            `json:"rubric_comment_template"`
-	Points                *float64           `json:"points"`
+	Points                *Decimal           `json:"points"`
+	ExtraCredit           *bool              `json:"extra_credit"`
 	Subproblems           []parseableProblem `json:"subproblems"`
+	// see Problem.Aggregate and Problem.BestCount
+	Aggregate *string `json:"aggregate"`
+	Best      *int    `json:"best"`
+	// the name of one of the assignment's scales
+	Scale *string `json:"scale"`
 }
 
 // Convert p to an exported Problem type.
 // This function performs no validation,
 // so you must do validation independent
-// of this function.
-func (p parseableProblem) toProblem() (pp Problem) {
+// of this function. scales maps scale
+// names to the assignment's scales.
+func (p parseableProblem) toProblem(scales map[string]*Scale) (pp Problem) {
 	pp.Code = p.code()
 	pp.Name = p.name()
 	pp.RubricCommentTemplate = p.rubricCommentTemplate()
 	pp.Points = p.points()
+	pp.ExtraCredit = p.extraCredit()
+	if p.hasAggregate() {
+		pp.Aggregate = p.aggregate()
+	}
+	if p.Best != nil {
+		pp.BestCount = *p.Best
+	}
+	if p.Scale != nil {
+		pp.Scale = scales[*p.Scale]
+	}
 	for _, ppp := range p.Subproblems {
-		pp.Subproblems = append(pp.Subproblems, ppp.toProblem())
+		sub := ppp.toProblem(scales)
+		// subproblems of extra credit
+		// problems are extra credit
+		if pp.ExtraCredit {
+			setExtraCredit(&sub)
+		}
+		pp.Subproblems = append(pp.Subproblems, sub)
 	}
 	return
 }
 
+func setExtraCredit(p *Problem) {
+	p.ExtraCredit = true
+	for i := range p.Subproblems {
+		setExtraCredit(&p.Subproblems[i])
+	}
+}
+
 func (p parseableProblem) code() string { return *p.Code }
 
 func (p parseableProblem) name() (s string) {
@@ -104,7 +155,16 @@ func (p parseableProblem) rubricCommentTemplate() (s string) {
 	return
 }
 
-func (p parseableProblem) points() float64 { return *p.Points }
+func (p parseableProblem) points() Decimal { return *p.Points }
+
+func (p parseableProblem) extraCredit() bool { return p.ExtraCredit != nil && *p.ExtraCredit }
+
+func (p parseableProblem) aggregate() AggregateRule {
+	if p.Aggregate != nil {
+		return AggregateRule(*p.Aggregate)
+	}
+	return AggregateSum
+}
 
 func (p parseableProblem) subproblems() (probs []parseableProblem) {
 	for _, pp := range p.Subproblems {
@@ -117,11 +177,22 @@ func (p parseableProblem) hasCode() bool   { return p.Code != nil }
 func (p parseableProblem) hasName() bool   { return p.Name != nil }
 func (p parseableProblem) hasPoints() bool { return p.Points != nil }
 
+func (p parseableProblem) hasAggregate() bool { return p.Aggregate != nil || p.Best != nil }
+
 type parseableAssignment struct {
 	Code     *string            `json:"code"`
 	Name     *string            `json:"name"`
 	Handins  []parseableHandin  `json:"handins"`
 	Problems []parseableProblem `json:"problems"`
+
+	LatePolicy *parseableLatePolicy `json:"late_policy"`
+	// a percentage; see Assignment.ExtraCreditCap
+	ExtraCreditCap *float64 `json:"extra_credit_cap"`
+	// maps scale names to maps from symbols
+	// to point values; see Scale
+	Scales map[string]map[string]Decimal `json:"scales"`
+	// see Assignment.Groups
+	Groups *bool `json:"groups"`
 }
 
 func (p parseableAssignment) code() string { return *p.Code }
@@ -200,14 +271,30 @@ func parseAssignment(r io.Reader) (*Assignment, error) {
 	}
 
 	a := &Assignment{
-		Code: asgn.code(),
-		Name: asgn.name(),
+		Code:   asgn.code(),
+		Name:   asgn.name(),
+		Groups: asgn.Groups != nil && *asgn.Groups,
 	}
 	for _, h := range asgn.Handins {
 		a.Handins = append(a.Handins, h.toHandin())
 	}
+	scales := make(map[string]*Scale)
+	for name, values := range asgn.Scales {
+		s := &Scale{Name: name, Values: make(map[string]Decimal)}
+		for sym, v := range values {
+			s.Values[sym] = v
+		}
+		scales[name] = s
+	}
 	for _, p := range asgn.Problems {
-		a.Problems = append(a.Problems, p.toProblem())
+		a.Problems = append(a.Problems, p.toProblem(scales))
+	}
+	if asgn.LatePolicy != nil {
+		a.LatePolicy = asgn.LatePolicy.toLatePolicy()
+	}
+	if asgn.ExtraCreditCap != nil {
+		c := *asgn.ExtraCreditCap
+		a.ExtraCreditCap = &c
 	}
 	return a, nil
 }
@@ -222,7 +309,24 @@ func validateAssignment(asgn parseableAssignment) error {
 	if err := validateProblemTree(asgn.Problems); err != nil {
 		return err
 	}
-	return validateHandins(asgn.Handins, asgn.Problems)
+	if err := validateScales(asgn.Scales); err != nil {
+		return err
+	}
+	if err := validateProblemScales(asgn.Problems, asgn.Scales); err != nil {
+		return err
+	}
+	if err := validateHandins(asgn.Handins, asgn.Problems); err != nil {
+		return err
+	}
+	if asgn.LatePolicy != nil {
+		if err := validateLatePolicy(asgn.LatePolicy); err != nil {
+			return fmt.Errorf("bad late policy: %v", err)
+		}
+	}
+	if asgn.ExtraCreditCap != nil && *asgn.ExtraCreditCap < 0 {
+		return fmt.Errorf("extra credit cap must be non-negative")
+	}
+	return nil
 }
 
 func validateProblemTree(problems []parseableProblem) error {
@@ -257,35 +361,76 @@ func validateProblemTree(problems []parseableProblem) error {
 	}
 
 	// now check that all problems have points and that
-	// they add up properly
+	// they add up properly according to their aggregate
+	// rules (extra credit subproblems do not count toward
+	// their parents' points)
 	//
-	// TODO(joshlf): floating point error?
-	var walkTreePoints func(problems []parseableProblem) (float64, error)
-	walkTreePoints = func(problems []parseableProblem) (float64, error) {
-		var sum float64
+	// parentExtraCredit is whether the problems are
+	// subproblems of an extra credit problem (in which
+	// case they are all extra credit, and all count
+	// toward their parent's points)
+	var walkTreePoints func(problems []parseableProblem, parentExtraCredit bool) (Decimal, error)
+	walkTreePoints = func(problems []parseableProblem, parentExtraCredit bool) (Decimal, error) {
+		var sum Decimal
 		for _, p := range problems {
 			if !p.hasPoints() {
-				return 0, fmt.Errorf("problem %v must have points", p.code())
+				return Decimal{}, fmt.Errorf("problem %v must have points", p.code())
+			}
+			if parentExtraCredit || !p.extraCredit() {
+				sum = sum.Add(p.points())
+			}
+			if len(p.subproblems()) == 0 && p.hasAggregate() {
+				return Decimal{}, fmt.Errorf("problem %v has no subproblems; cannot specify aggregate rule", p.code())
 			}
-			sum += p.points()
 			if len(p.subproblems()) > 0 {
-				subSum, err := walkTreePoints(p.subproblems())
+				subSum, err := walkTreePoints(p.subproblems(), parentExtraCredit || p.extraCredit())
 				if err != nil {
-					return 0, err
+					return Decimal{}, err
 				}
-				if subSum != p.points() {
-					return 0, fmt.Errorf("problem %v's points value is not equal to the sum of all subproblems' points", p.code())
+				if p.hasAggregate() {
+					if err := validateAggregate(p, parentExtraCredit || p.extraCredit()); err != nil {
+						return Decimal{}, err
+					}
+				}
+				if p.aggregate() == AggregateSum && subSum != p.points() {
+					return Decimal{}, fmt.Errorf("problem %v's points value is not equal to the sum of all subproblems' points", p.code())
 				}
 			}
 		}
 		return sum, nil
 	}
-	if _, err := walkTreePoints(problems); err != nil {
+	if _, err := walkTreePoints(problems, false); err != nil {
 		return err
 	}
 	return nil
 }
 
+// assumes problems and scales have already been validated
+func validateProblemScales(problems []parseableProblem, scales map[string]map[string]Decimal) error {
+	for _, p := range problems {
+		if p.Scale != nil {
+			scale, ok := scales[*p.Scale]
+			if !ok {
+				return fmt.Errorf("problem %v uses nonexistent scale: %v", p.code(), *p.Scale)
+			}
+			var syms []string
+			for sym := range scale {
+				syms = append(syms, sym)
+			}
+			sort.Strings(syms)
+			for _, sym := range syms {
+				if scale[sym].Cmp(p.points()) > 0 {
+					return fmt.Errorf("problem %v uses scale %v, whose symbol %v is worth more than the problem's points", p.code(), *p.Scale, sym)
+				}
+			}
+		}
+		if err := validateProblemScales(p.subproblems(), scales); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // assumes problems have already been validated
 func validateHandins(handins []parseableHandin, problems []parseableProblem) error {
 	if len(handins) == 0 {
@@ -339,6 +484,24 @@ func validateHandins(handins []parseableHandin, problems []parseableProblem) err
 		if len(h.problems()) == 0 {
 			return fmt.Errorf("%v must specify at least one problem", handinErrorName)
 		}
+		switch {
+		case h.MaxSize != nil && *h.MaxSize <= 0:
+			return fmt.Errorf("%v's max_size must be positive", handinErrorName)
+		case h.MaxFileSize != nil && *h.MaxFileSize <= 0:
+			return fmt.Errorf("%v's max_file_size must be positive", handinErrorName)
+		case h.MaxFiles != nil && *h.MaxFiles <= 0:
+			return fmt.Errorf("%v's max_files must be positive", handinErrorName)
+		}
+		for _, pattern := range h.Required {
+			if err := handin.ValidatePattern(pattern); err != nil {
+				return fmt.Errorf("%v has bad required pattern %q: %v", handinErrorName, pattern, err)
+			}
+		}
+		for _, pattern := range h.Forbidden {
+			if err := handin.ValidatePattern(pattern); err != nil {
+				return fmt.Errorf("%v has bad forbidden pattern %q: %v", handinErrorName, pattern, err)
+			}
+		}
 		for _, pc := range h.problems() {
 			if err := ValidateCode(pc); err != nil {
 				return fmt.Errorf("%v contains bad problem code %q: %v", handinErrorName, pc, err)
@@ -83,6 +83,96 @@ var assignmentErrTests = []struct {
 	[{"code":"b","points":1},{"code":"c","points":1}]}],
 	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
 		""},
+	{`{"code":"a","problems":[{"code":"a","points":2,"subproblems":
+	[{"code":"b","points":1},{"code":"c","points":1,"extra_credit":true}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a's points value is not equal to the sum of all subproblems' points"},
+	{`{"code":"a","problems":[{"code":"a","points":1,"subproblems":
+	[{"code":"b","points":1},{"code":"c","points":1,"extra_credit":true}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		""},
+	{`{"code":"a","problems":[{"code":"a","points":2,"extra_credit":true,"subproblems":
+	[{"code":"b","points":1},{"code":"c","points":1,"extra_credit":true}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		""},
+	{`{"code":"a","problems":[{"code":"a","points":1}],"extra_credit_cap":-1,
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"extra credit cap must be non-negative"},
+	{`{"code":"a","problems":[{"code":"a","points":1,"aggregate":"max"}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a has no subproblems; cannot specify aggregate rule"},
+	{`{"code":"a","problems":[{"code":"a","points":1,"aggregate":"median","subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a has unknown aggregate rule \"median\""},
+	{`{"code":"a","problems":[{"code":"a","points":2,"best":1,"subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a specifies best count but aggregate rule is not best"},
+	{`{"code":"a","problems":[{"code":"a","points":2,"aggregate":"best","subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a must specify best count"},
+	{`{"code":"a","problems":[{"code":"a","points":3,"aggregate":"best","best":3,"subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a's best count must be between 1 and its number of subproblems"},
+	{`{"code":"a","problems":[{"code":"a","points":2,"aggregate":"best","best":2,"subproblems":[{"code":"b","points":1},{"code":"c","points":1},{"code":"d","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		""},
+	{`{"code":"a","problems":[{"code":"a","points":3,"aggregate":"best","best":2,"subproblems":[{"code":"b","points":1},{"code":"c","points":1},{"code":"d","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a's points value must be 2 for aggregate rule best"},
+	{`{"code":"a","problems":[{"code":"a","points":1,"aggregate":"max","subproblems":[{"code":"b","points":1},{"code":"c","points":2}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a's subproblems must all have the same points value for aggregate rule max"},
+	{`{"code":"a","problems":[{"code":"a","points":2,"aggregate":"average","subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a's points value must be 1 for aggregate rule average"},
+	{`{"code":"a","problems":[{"code":"a","points":1,"aggregate":"min","subproblems":[{"code":"b","points":1},{"code":"c","points":1,"extra_credit":true}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a has extra credit subproblems; only sum aggregate rule may be used"},
+	{`{"code":"a","problems":[{"code":"a","points":1,"aggregate":"min","subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		""},
+	{`{"code":"a","problems":[{"code":"a","points":2,"aggregate":"sum","subproblems":[{"code":"b","points":1},{"code":"c","points":1}]}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		""},
+	{`{"code":"a","scales":{"1":{"x":1}},"problems":[{"code":"a","points":1}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"bad scale name \"1\": contains illegal characters; must be alphanumeric and start with an alphabetic character"},
+	{`{"code":"a","scales":{"s":{}},"problems":[{"code":"a","points":1}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"scale s must have at least one symbol"},
+	{`{"code":"a","scales":{"s":{" x":1}},"problems":[{"code":"a","points":1}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"scale s has bad symbol \" x\": must be non-empty and have no surrounding whitespace"},
+	{`{"code":"a","scales":{"s":{"1.5":1}},"problems":[{"code":"a","points":1}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"scale s has bad symbol \"1.5\": must not be a number"},
+	{`{"code":"a","scales":{"s":{"x":-1}},"problems":[{"code":"a","points":1}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"scale s's symbol x has negative value"},
+	{`{"code":"a","scales":{"s":{"x":1}},"problems":[{"code":"a","points":1,"scale":"t"}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a uses nonexistent scale: t"},
+	{`{"code":"a","scales":{"s":{"x":2,"y":1}},"problems":[{"code":"a","points":1,"scale":"s"}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		"problem a uses scale s, whose symbol x is worth more than the problem's points"},
+	{`{"code":"a","problems":[{"code":"a","points":1}],"handins":
+	[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"],"max_size":"10 GiB"}]}`,
+		"bad size \"10 GiB\": must be a non-negative number with an optional unit of B, KB, MB, or GB"},
+	{`{"code":"a","problems":[{"code":"a","points":1}],"handins":
+	[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"],"max_file_size":"0KB"}]}`,
+		"handin's max_file_size must be positive"},
+	{`{"code":"a","problems":[{"code":"a","points":1}],"handins":
+	[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"],"max_files":0}]}`,
+		"handin's max_files must be positive"},
+	{`{"code":"a","problems":[{"code":"a","points":1}],"handins":
+	[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"],"required":["README","/src"]}]}`,
+		"handin has bad required pattern \"/src\": must be relative"},
+	{`{"code":"a","problems":[{"code":"a","points":1}],"handins":
+	[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"],"forbidden":["src/[a-"]}]}`,
+		"handin has bad forbidden pattern \"src/[a-\": syntax error in pattern"},
+	{`{"code":"a","scales":{"s":{"x":1,"y":0}},"problems":[{"code":"a","points":1,"scale":"s"}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"]}]}`,
+		""},
 }
 
 func TestParseAssignmentError(t *testing.T) {
@@ -97,6 +187,36 @@ func TestParseAssignmentError(t *testing.T) {
 	}
 }
 
+func TestParseHandinLimits(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(`{"code":"a","problems":[{"code":"a","points":1}],
+	"handins":[{"due":"Jan 2, 2006 at 3:04pm (MST)","problems":["a"],
+	"max_size":"1.5MB","max_file_size":"512kb","max_files":100,
+	"required":["README","src/*.go"],"forbidden":["**/*.o"]}]}`))
+	testutil.Must(t, err)
+	h := asgn.Handins[0]
+	if h.MaxSize != 3<<19 || h.MaxFileSize != 512<<10 || h.MaxFiles != 100 {
+		t.Errorf("unexpected limits: %+v", h)
+	}
+	if !reflect.DeepEqual(h.Required, []string{"README", "src/*.go"}) || !reflect.DeepEqual(h.Forbidden, []string{"**/*.o"}) {
+		t.Errorf("unexpected requirements: %+v", h)
+	}
+
+	for _, test := range []struct {
+		text string
+		n    int64
+		ok   bool
+	}{
+		{"512", 512, true}, {"512B", 512, true}, {"2 KB", 2048, true},
+		{"1gb", 1 << 30, true}, {"", 0, false}, {"MB", 0, false},
+		{"-1KB", 0, false}, {"1TB", 0, false},
+	} {
+		n, err := ParseSize(test.text)
+		if (err == nil) != test.ok || n != test.n {
+			t.Errorf("unexpected result parsing %q: got %v, %v", test.text, n, err)
+		}
+	}
+}
+
 func BenchmarkParseAssignmentFromDisk(b *testing.B) {
 	dir, ok := testutil.SrcDir()
 	if !ok {
@@ -144,6 +264,16 @@ const findProblemPathByCodeTestAssignment = `{"code":"a","name":"a",
 				]
 }`
 
+// makeAssignmentTestDB creates a database containing
+// the assignment findProblemPathByCodeTestAssignment.
+func makeAssignmentTestDB(t *testing.T) (*DB, *Assignment) {
+	asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+	testutil.Must(t, err)
+	d := NewDB()
+	d.AddAssignment(asgn)
+	return d, asgn
+}
+
 var findProblemPathByCodeTestCases = []struct {
 	code string
 	path []string
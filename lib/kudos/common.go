@@ -3,9 +3,15 @@ package kudos
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// DateFormat is the format used for dates in
+// configuration files and in kudos' output.
+const DateFormat = "Jan 2, 2006 at 3:04pm (MST)"
+
 var re = regexp.MustCompile("[a-zA-Z][a-zA-Z0-9_]*")
 
 func ValidateCode(code string) error {
@@ -21,7 +27,7 @@ func ValidateCode(code string) error {
 type date time.Time
 
 func (d *date) UnmarshalText(text []byte) error {
-	t, err := timeparse(string(text))
+	t, err := ParseDate(string(text))
 	if err != nil {
 		return err
 	}
@@ -29,6 +35,66 @@ func (d *date) UnmarshalText(text []byte) error {
 	return nil
 }
 
-func timeparse(text string) (time.Time, error) {
-	return time.Parse("Jan 2, 2006 at 3:04pm (MST)", text)
+// ParseDate parses a date in the format given
+// by DateFormat.
+func ParseDate(text string) (time.Time, error) {
+	return time.Parse(DateFormat, text)
+}
+
+// duration is a time.Duration which is
+// unmarshaled from a string such as "1h30m"
+// (in the format accepted by time.ParseDuration)
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	dd, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = duration(dd)
+	return nil
+}
+
+// size is a number of bytes which is unmarshaled
+// from a string such as "10MB" (see ParseSize)
+type size int64
+
+func (s *size) UnmarshalText(text []byte) error {
+	n, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = size(n)
+	return nil
+}
+
+// sizeUnits are ordered so that longer suffixes
+// are tried before their suffixes (eg, "KB"
+// before "B")
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a number of bytes with an optional
+// unit suffix of B, KB, MB, or GB (for example, "512",
+// "100KB", or "1.5GB"). Units are powers of 1024.
+func ParseSize(text string) (int64, error) {
+	num, mult := strings.TrimSpace(text), float64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(num), u.suffix) {
+			num, mult = strings.TrimSpace(num[:len(num)-len(u.suffix)]), u.bytes
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("bad size %q: must be a non-negative number with an optional unit of B, KB, MB, or GB", text)
+	}
+	return int64(f * mult), nil
 }
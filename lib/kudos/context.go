@@ -27,6 +27,7 @@ func (c *Context) OpenDB() error {
 	if err != nil {
 		return err
 	}
+	d.initMaps()
 	c.DB = d
 	c.committer = committer
 	return nil
@@ -154,10 +155,34 @@ func (c *Context) CourseHooksDir() string {
 	return filepath.Join(c.CourseKudosDir(), config.HooksDirName)
 }
 
+func (c *Context) CourseRegradeDir() string {
+	return filepath.Join(c.CourseKudosDir(), config.RegradeDirName)
+}
+
+func (c *Context) UserRegradeDir(uid string) string {
+	return filepath.Join(c.CourseRegradeDir(), uid)
+}
+
+func (c *Context) CourseLateDaysDir() string {
+	return filepath.Join(c.CourseKudosDir(), config.LateDaysDirName)
+}
+
+func (c *Context) UserLateDaysDir(uid string) string {
+	return filepath.Join(c.CourseLateDaysDir(), uid)
+}
+
 func (c *Context) PreHandinHookFile() string {
 	return filepath.Join(c.CourseHooksDir(), config.PreHandinHookFileName)
 }
 
+func (c *Context) CourseIgnoreFile() string {
+	return filepath.Join(c.CourseHooksDir(), config.IgnoreFileName)
+}
+
+func (c *Context) AssignmentIgnoreFile(code string) string {
+	return filepath.Join(c.CourseHooksDir(), code+"."+config.IgnoreFileName)
+}
+
 func (c *Context) CourseDBDir() string {
 	return filepath.Join(c.CourseKudosDir(), config.DBDirName)
 }
@@ -166,6 +191,14 @@ func (c *Context) CoursePubDBDir() string {
 	return filepath.Join(c.CourseKudosDir(), config.PubDBDirName)
 }
 
+func (c *Context) PubStudentsDir() string {
+	return filepath.Join(c.CoursePubDBDir(), config.PubStudentsDirName)
+}
+
+func (c *Context) PubStudentFile(uid string) string {
+	return filepath.Join(c.PubStudentsDir(), uid)
+}
+
 func (c *Context) AssignmentHandinDir(code string) string {
 	return filepath.Join(c.CourseHandinDir(), code)
 }
@@ -20,6 +20,29 @@ type Course struct {
 	Name        string
 	Description string
 	TAGroup     string
+
+	// LatePolicy is nil if the
+	// course has no late policy
+	LatePolicy *LatePolicy
+	// LateDays is the number of late days
+	// each student may spend
+	LateDays int
+	// Grading is nil if the course
+	// has no grading scheme
+	Grading *GradingScheme
+	// GradePrecision is the maximum number of
+	// digits after the decimal point that grades
+	// may have (at most DecimalPlaces)
+	GradePrecision int
+}
+
+// ValidateGrade returns an error if g has more digits
+// after the decimal point than c.GradePrecision allows.
+func (c *Course) ValidateGrade(g Decimal) error {
+	if g.Places() > c.GradePrecision {
+		return fmt.Errorf("grade %v has more than %v digit(s) after the decimal point", g, c.GradePrecision)
+	}
+	return nil
 }
 
 // NOTE: All of the convenience methods to retrieve
@@ -39,6 +62,13 @@ type parseableCourse struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
 	TAGroup     *string `json:"ta_group"`
+
+	LatePolicy *parseableLatePolicy `json:"late_policy"`
+	LateDays   *int                 `json:"late_days"`
+
+	Grading *parseableGradingScheme `json:"grading"`
+
+	GradePrecision *int `json:"grade_precision"`
 }
 
 func (p *parseableCourse) code() string { return *p.Code }
@@ -59,6 +89,20 @@ func (p *parseableCourse) description() (s string) {
 
 func (p *parseableCourse) taGroup() string { return *p.TAGroup }
 
+func (p *parseableCourse) lateDays() (n int) {
+	if p.LateDays != nil {
+		n = *p.LateDays
+	}
+	return
+}
+
+func (p *parseableCourse) gradePrecision() int {
+	if p.GradePrecision != nil {
+		return *p.GradePrecision
+	}
+	return DecimalPlaces
+}
+
 // ParseCourseFileValidateRoot is like ParseCourseFile
 // except that it infers the location of the course
 // config file from the course root's path, and validates
@@ -99,12 +143,22 @@ func parseCourse(r io.Reader) (*Course, error) {
 		return nil, err
 	}
 
-	return &Course{
+	c := &Course{
 		Code:        course.code(),
 		Name:        course.name(),
 		Description: course.description(),
 		TAGroup:     course.taGroup(),
-	}, nil
+		LateDays:    course.lateDays(),
+
+		GradePrecision: course.gradePrecision(),
+	}
+	if course.LatePolicy != nil {
+		c.LatePolicy = course.LatePolicy.toLatePolicy()
+	}
+	if course.Grading != nil {
+		c.Grading = course.Grading.toGradingScheme()
+	}
+	return c, nil
 }
 
 func validateCourse(course parseableCourse) error {
@@ -118,5 +172,21 @@ func validateCourse(course parseableCourse) error {
 		return fmt.Errorf("must have TA group")
 	}
 	// TODO(joshlf): Look up TA group (verify that it exists)
+	if course.LatePolicy != nil {
+		if err := validateLatePolicy(course.LatePolicy); err != nil {
+			return fmt.Errorf("bad late policy: %v", err)
+		}
+	}
+	if course.lateDays() < 0 {
+		return fmt.Errorf("late_days must be non-negative")
+	}
+	if p := course.gradePrecision(); p < 0 || p > DecimalPlaces {
+		return fmt.Errorf("grade_precision must be between 0 and %v", DecimalPlaces)
+	}
+	if course.Grading != nil {
+		if err := validateGradingScheme(course.Grading); err != nil {
+			return fmt.Errorf("bad grading scheme: %v", err)
+		}
+	}
 	return nil
 }
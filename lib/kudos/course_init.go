@@ -79,6 +79,14 @@ func InitCourse(ctx *Context) (err error) {
 	if err != nil {
 		return
 	}
+	err = logAndMkdir(ctx.CourseRegradeDir(), config.RegradeDirPerms)
+	if err != nil {
+		return
+	}
+	err = logAndMkdir(ctx.CourseLateDaysDir(), config.LateDaysDirPerms)
+	if err != nil {
+		return
+	}
 	err = logAndMkdir(ctx.CourseDBDir(), config.DBDirPerms)
 	if err != nil {
 		return
@@ -87,6 +95,10 @@ func InitCourse(ctx *Context) (err error) {
 	if err != nil {
 		return
 	}
+	err = logAndMkdir(ctx.PubStudentsDir(), config.PubStudentsDirPerms)
+	if err != nil {
+		return
+	}
 
 	ctx.Verbose.Println("initializing database")
 	err = db.Init(NewDB(), ctx.CourseDBDir())
@@ -17,6 +17,37 @@ var courseErrTests = []struct {
 	{`{"code":"-"}`, "bad course code \"-\": contains illegal characters;" +
 		" must be alphanumeric and start with an alphabetic character"},
 	{`{"code":"course"}`, "must have TA group"},
+	{`{"code":"course","ta_group":"tas","late_policy":{}}`, "bad late policy: must have penalty"},
+	{`{"code":"course","ta_group":"tas","late_policy":{"penalty":-1}}`,
+		"bad late policy: penalty must be non-negative"},
+	{`{"code":"course","ta_group":"tas","late_policy":{"penalty":10}}`,
+		"bad late policy: must specify penalty period (per)"},
+	{`{"code":"course","ta_group":"tas","late_policy":{"penalty":10,"per":"week"}}`,
+		"bad late policy: bad penalty period \"week\": must be \"hour\" or \"day\""},
+	{`{"code":"course","ta_group":"tas","late_policy":{"penalty":10,"per":"day",
+		"grace_period":"2h","max_lateness":"1h"}}`,
+		"bad late policy: maximum lateness must not be less than grace period"},
+	{`{"code":"course","ta_group":"tas","late_policy":{"penalty":10,"per":"day",
+		"grace_period":"15m","max_lateness":"72h"}}`, ""},
+	{`{"code":"course","ta_group":"tas","late_days":-1}`, "late_days must be non-negative"},
+	{`{"code":"course","ta_group":"tas","grade_precision":7}`, "grade_precision must be between 0 and 6"},
+	{`{"code":"course","ta_group":"tas","grade_precision":2}`, ""},
+	{`{"code":"course","ta_group":"tas","grading":{}}`, "bad grading scheme: must have at least one category"},
+	{`{"code":"course","ta_group":"tas","grading":{"categories":[{"code":"hw","assignments":["hw1"]}]}}`,
+		"bad grading scheme: category hw: must have weight"},
+	{`{"code":"course","ta_group":"tas","grading":{"categories":[{"code":"hw","weight":1,
+		"assignments":["hw1"],"drop_lowest":1}]}}`, "bad grading scheme: category hw: cannot drop all assignments"},
+	{`{"code":"course","ta_group":"tas","grading":{"categories":[{"code":"hw","weight":1,
+		"assignments":["hw1"],"assignment_weights":{"hw2":1}}]}}`,
+		"bad grading scheme: category hw: weight given for assignment not in category: hw2"},
+	{`{"code":"course","ta_group":"tas","grading":{"categories":[{"code":"hw","weight":1,"assignments":["hw1"]},
+		{"code":"exams","weight":1,"assignments":["hw1"]}]}}`,
+		"bad grading scheme: assignment hw1 is in multiple categories (hw and exams)"},
+	{`{"code":"course","ta_group":"tas","grading":{"categories":[{"code":"hw","weight":1,"assignments":["hw1"]}],
+		"letter_grades":[{"letter":"A","cutoff":90},{"letter":"B","cutoff":90}]}}`,
+		"bad grading scheme: letter grade B: duplicate cutoff: 90"},
+	{`{"code":"course","ta_group":"tas","grading":{"categories":[{"code":"hw","weight":1,"assignments":["hw1"]}],
+		"letter_grades":[{"letter":"A","cutoff":90},{"letter":"B","cutoff":80}]}}`, ""},
 }
 
 func TestParseCourseError(t *testing.T) {
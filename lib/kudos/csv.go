@@ -0,0 +1,157 @@
+package kudos
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVStudentColumn is the header of the column
+// which identifies students in grade CSV files.
+const CSVStudentColumn = "student"
+
+// csvCommentSuffix is the suffix which, appended to
+// a problem code, gives the header of the column
+// holding comments for that problem.
+const csvCommentSuffix = ":comment"
+
+// A CSVGradeRow is a single row of a grade CSV file.
+type CSVGradeRow struct {
+	// the line on which the row appeared (the
+	// header is line 1; this assumes that no
+	// quoted field spans multiple lines)
+	Line int
+	// the username or UID identifying the student
+	Student string
+	Grades  []CSVGrade
+}
+
+// A CSVGrade is a single grade in a grade CSV file.
+type CSVGrade struct {
+	Problem string
+	Grade   Decimal
+	// see ProblemGrade.Symbol
+	Symbol  string
+	Comment string
+}
+
+// ParseGradeCSV parses a CSV file of grades for asgn.
+// The first row must be a header. One column, whose
+// header is "student", must give each row's student;
+// every other column's header must either be the code
+// of a problem in asgn, or be of the form <problem>:comment
+// (in which case it holds comments for that problem's
+// grades). Empty cells are ignored, but it is an error
+// for a row to have a comment for a problem without
+// a grade, or to have grades for both a problem and
+// one of its ancestors.
+//
+// Students are not resolved, and the grades are not
+// checked against any existing grades.
+func ParseGradeCSV(r io.Reader, asgn *Assignment) ([]CSVGradeRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	studentCol := -1
+	// maps column indices to problem codes
+	gradeCols := make(map[int]string)
+	commentCols := make(map[int]string)
+	// maps headers to whether they have been seen
+	seen := make(map[string]bool)
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if seen[h] {
+			return nil, fmt.Errorf("duplicate column: %v", h)
+		}
+		seen[h] = true
+
+		code := strings.TrimSuffix(h, csvCommentSuffix)
+		switch {
+		case h == CSVStudentColumn:
+			studentCol = i
+			continue
+		case ValidateCode(code) != nil:
+			return nil, fmt.Errorf("bad column %q: not a problem code", h)
+		}
+		if _, ok := asgn.FindProblemByCode(code); !ok {
+			return nil, fmt.Errorf("bad column %q: no such problem: %v", h, code)
+		}
+		if code == h {
+			gradeCols[i] = code
+		} else {
+			commentCols[i] = code
+		}
+	}
+	if studentCol == -1 {
+		return nil, fmt.Errorf("missing %v column", CSVStudentColumn)
+	}
+	for _, code := range commentCols {
+		if !seen[code] {
+			return nil, fmt.Errorf("comment column for problem %v without grade column", code)
+		}
+	}
+
+	var rows []CSVGradeRow
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := CSVGradeRow{Line: line, Student: strings.TrimSpace(rec[studentCol])}
+		if row.Student == "" {
+			return nil, fmt.Errorf("line %v: missing student", line)
+		}
+
+		// maps problem codes to indices in row.Grades
+		grades := make(map[string]int)
+		// iterate in column order so that
+		// the grades are in a stable order
+		for i := range rec {
+			code, ok := gradeCols[i]
+			if !ok || strings.TrimSpace(rec[i]) == "" {
+				continue
+			}
+			p, _ := asgn.FindProblemByCode(code)
+			g, sym, err := p.ParseGrade(strings.TrimSpace(rec[i]))
+			if err != nil {
+				return nil, fmt.Errorf("line %v: could not parse grade for problem %v: %v", line, code, err)
+			}
+			if g.Sign() < 0 {
+				return nil, fmt.Errorf("line %v: grade for problem %v is negative", line, code)
+			}
+			row.Grades = append(row.Grades, CSVGrade{Problem: code, Grade: g, Symbol: sym})
+			grades[code] = len(row.Grades) - 1
+		}
+		for i := range rec {
+			code, ok := commentCols[i]
+			if !ok || rec[i] == "" {
+				continue
+			}
+			j, ok := grades[code]
+			if !ok {
+				return nil, fmt.Errorf("line %v: comment for problem %v without grade", line, code)
+			}
+			row.Grades[j].Comment = rec[i]
+		}
+		for _, g := range row.Grades {
+			path, _ := asgn.FindProblemPathByCode(g.Problem)
+			for _, parent := range path {
+				if _, ok := grades[parent]; ok {
+					return nil, fmt.Errorf("line %v: grades given for both problem %v and its subproblem %v", line, parent, g.Problem)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,54 @@
+package kudos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+var parseGradeCSVTestCases = []struct {
+	csv  string
+	rows []CSVGradeRow
+	err  string
+}{
+	{"", nil, "missing header"},
+	{"prob1\n1\n", nil, "missing student column"},
+	{"student,prob1,prob1\n", nil, "duplicate column: prob1"},
+	{"student,#\n", nil, `bad column "#": not a problem code`},
+	{"student,prob3\n", nil, `bad column "prob3": no such problem: prob3`},
+	{"student,prob3:comment\n", nil, `bad column "prob3:comment": no such problem: prob3`},
+	{"student,prob1:comment\n", nil, "comment column for problem prob1 without grade column"},
+	{"student,prob1\n,1\n", nil, "line 2: missing student"},
+	{"student,prob1\nfoo,bar\n", nil, `line 2: could not parse grade for problem prob1: could not parse "bar" as a decimal number`},
+	{"student,prob1\nfoo,-1\n", nil, "line 2: grade for problem prob1 is negative"},
+	{"student,prob1,prob1:comment\nfoo,,good\n", nil, "line 2: comment for problem prob1 without grade"},
+	{"student,prob2,a\nfoo,50,25\n", nil, "line 2: grades given for both problem prob2 and its subproblem a"},
+	{"student,prob1\n", nil, ""},
+	{"student,prob1,prob2,prob1:comment\nfoo,10,,good\n 0 ,,50,\n", []CSVGradeRow{
+		{2, "foo", []CSVGrade{{"prob1", dec(10), "", "good"}}},
+		{3, "0", []CSVGrade{{"prob2", dec(50), "", ""}}},
+	}, ""},
+	{"a:comment,b,student,a\nyes,20,bar,25\n", []CSVGradeRow{
+		{2, "bar", []CSVGrade{{"b", dec(20), "", ""}, {"a", dec(25), "", "yes"}}},
+	}, ""},
+}
+
+func TestParseGradeCSV(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+	testutil.Must(t, err)
+	for i, test := range parseGradeCSVTestCases {
+		rows, err := ParseGradeCSV(strings.NewReader(test.csv), asgn)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+		if !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%v: unexpected rows: got %v; want %v", prefix, rows, test.rows)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package kudos
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type DB struct {
 	Students    map[string]*Student    // keys are UIDs
@@ -15,6 +18,45 @@ type DB struct {
 	// will  exist and be initialized iff the assignment itself
 	// is in the Assignments map
 	Handins map[string]map[string]map[string]time.Time
+	// keys are assignment codes; values are all of the
+	// grader assignments made for that assignment, in
+	// the order in which they were made
+	GraderAssignments map[string][]*GraderAssignment
+	// keys are assignment codes; value's keys are handin
+	// codes (as in Handins); innermost keys are student
+	// UIDs
+	Extensions map[string]map[string]map[string]*Extension
+	// keys are assignment codes; value's keys are handin
+	// codes (as in Handins); innermost keys are student
+	// UIDs, and values are numbers of late days
+	//
+	// LateDayChoices holds the number of late days
+	// students have chosen to spend on particular
+	// handins; LateDays is the ledger of late days
+	// actually spent, computed by UpdateLateDays
+	LateDayChoices map[string]map[string]map[string]int
+	LateDays       map[string]map[string]map[string]int
+	// GradeHistory holds every change made to
+	// Grades, in the order in which they were
+	// made; it is append-only, and is kept even
+	// when assignments are deleted
+	GradeHistory []*GradeChange
+	// keys are assignment codes; an assignment
+	// is present iff its grades have been released
+	Releases map[string]*Release
+	// Regrades holds all regrade requests which have
+	// been imported, in the order in which they were
+	// imported (a request's ID is its index plus one);
+	// requests are kept even when assignments are
+	// deleted so that IDs remain stable
+	Regrades []*RegradeRequest
+	// keys are section codes; a student's section
+	// is given by Student.Section
+	Sections map[string]*Section
+	// keys are assignment codes; value's keys are
+	// group codes. Only group assignments (see
+	// Assignment.Groups) have groups.
+	Groups map[string]map[string]*Group
 
 	Anonymizer Anonymizer
 }
@@ -28,7 +70,90 @@ func (d *DB) AddStudent(uid string) bool {
 	if ok {
 		return false
 	}
-	d.Students[uid] = &Student{uid}
+	d.Students[uid] = &Student{UID: uid, Status: EnrollmentActive}
+	return true
+}
+
+// DropStudent marks the student with the given uid as
+// having dropped the course (see Student.Dropped). Their
+// grades and handins are kept. It returns true if the
+// student was dropped, and false if the student does not
+// exist or had already been dropped.
+func (d *DB) DropStudent(uid string) bool {
+	s, ok := d.Students[uid]
+	if !ok || s.Dropped() {
+		return false
+	}
+	s.Status = EnrollmentDropped
+	return true
+}
+
+// ReenrollStudent undoes DropStudent. It returns true
+// if the student was re-enrolled, and false if the
+// student does not exist or had not been dropped.
+func (d *DB) ReenrollStudent(uid string) bool {
+	s, ok := d.Students[uid]
+	if !ok || !s.Dropped() {
+		return false
+	}
+	s.Status = EnrollmentActive
+	return true
+}
+
+// RemoveStudent removes the student with the given
+// uid from the database, along with their grades,
+// handin times, extensions, late days, grader
+// assignments, group memberships, and anonymous
+// tokens. The grade history and regrade requests
+// are kept, since they are append-only. It returns
+// true if the student was removed and false if the
+// student does not exist.
+func (d *DB) RemoveStudent(uid string) bool {
+	if _, ok := d.Students[uid]; !ok {
+		return false
+	}
+	delete(d.Students, uid)
+	for _, grades := range d.Grades {
+		delete(grades, uid)
+	}
+	for _, handins := range d.Handins {
+		for _, times := range handins {
+			delete(times, uid)
+		}
+	}
+	for _, handins := range d.Extensions {
+		for _, exts := range handins {
+			delete(exts, uid)
+		}
+	}
+	for _, m := range []map[string]map[string]map[string]int{d.LateDayChoices, d.LateDays} {
+		for _, handins := range m {
+			for _, days := range handins {
+				delete(days, uid)
+			}
+		}
+	}
+	for _, gas := range d.GraderAssignments {
+		for _, g := range gas {
+			delete(g.Graders, uid)
+			delete(g.Tokens, uid)
+		}
+	}
+	for _, gs := range d.Groups {
+		for _, g := range gs {
+			for i, m := range g.MemberUIDs {
+				if m == uid {
+					g.MemberUIDs = append(g.MemberUIDs[:i], g.MemberUIDs[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	for token, u := range d.Anonymizer {
+		if u == uid {
+			delete(d.Anonymizer, token)
+		}
+	}
 	return true
 }
 
@@ -64,17 +189,111 @@ func (d *DB) DeleteAssignment(code string) bool {
 	delete(d.Assignments, code)
 	delete(d.Grades, code)
 	delete(d.Handins, code)
+	delete(d.GraderAssignments, code)
+	delete(d.Extensions, code)
+	delete(d.LateDayChoices, code)
+	delete(d.LateDays, code)
+	delete(d.Releases, code)
+	delete(d.Groups, code)
 	return true
 }
 
+// ReplaceAssignment replaces the assignment in the
+// database which has the same code as a with a.
+// Unlike DeleteAssignment followed by AddAssignment,
+// extensions, late day choices, and groups are kept,
+// since they are often set up before an assignment's
+// configuration is final. It is an error if there is
+// no such assignment, if a no longer has a handin for
+// which extensions or late day choices exist, or if
+// groups exist and a is not a group assignment; in
+// that case, the database is left unchanged.
+func (d *DB) ReplaceAssignment(a *Assignment) error {
+	if _, ok := d.Assignments[a.Code]; !ok {
+		return fmt.Errorf("no such assignment: %v", a.Code)
+	}
+	handins := make(map[string]bool)
+	for _, h := range a.Handins {
+		handins[h.Code] = true
+	}
+	for h, exts := range d.Extensions[a.Code] {
+		if len(exts) > 0 && !handins[h] {
+			return fmt.Errorf("extensions have been granted for handin %q, which the new version of assignment %v does not have", h, a.Code)
+		}
+	}
+	for h, choices := range d.LateDayChoices[a.Code] {
+		if len(choices) > 0 && !handins[h] {
+			return fmt.Errorf("late days have been chosen for handin %q, which the new version of assignment %v does not have", h, a.Code)
+		}
+	}
+	if len(d.Groups[a.Code]) > 0 && !a.Groups {
+		return fmt.Errorf("groups have been created for assignment %v, but the new version is not a group assignment", a.Code)
+	}
+
+	exts, choices, groups := d.Extensions[a.Code], d.LateDayChoices[a.Code], d.Groups[a.Code]
+	d.DeleteAssignment(a.Code)
+	d.AddAssignment(a)
+	if exts != nil {
+		d.Extensions[a.Code] = exts
+	}
+	if choices != nil {
+		d.LateDayChoices[a.Code] = choices
+	}
+	if groups != nil {
+		d.Groups[a.Code] = groups
+	}
+	return nil
+}
+
+// AddGraderAssignment records g as the most recent
+// grader assignment for the given assignment.
+func (d *DB) AddGraderAssignment(code string, g *GraderAssignment) {
+	d.GraderAssignments[code] = append(d.GraderAssignments[code], g)
+}
+
+// initMaps initializes any of d's maps which are nil,
+// as they will be in databases created before the maps
+// were introduced, so that the rest of the package can
+// assume that they are not nil.
+func (d *DB) initMaps() {
+	if d.GraderAssignments == nil {
+		d.GraderAssignments = make(map[string][]*GraderAssignment)
+	}
+	if d.Extensions == nil {
+		d.Extensions = make(map[string]map[string]map[string]*Extension)
+	}
+	if d.LateDayChoices == nil {
+		d.LateDayChoices = make(map[string]map[string]map[string]int)
+	}
+	if d.LateDays == nil {
+		d.LateDays = make(map[string]map[string]map[string]int)
+	}
+	if d.Releases == nil {
+		d.Releases = make(map[string]*Release)
+	}
+	if d.Sections == nil {
+		d.Sections = make(map[string]*Section)
+	}
+	if d.Groups == nil {
+		d.Groups = make(map[string]map[string]*Group)
+	}
+}
+
 // NewDB creates a new DB as it should be in
 // a newly-initialized course
 func NewDB() *DB {
 	return &DB{
-		Students:    make(map[string]*Student),
-		Assignments: make(map[string]*Assignment),
-		Grades:      make(map[string]map[string]*AssignmentGrade),
-		Handins:     make(map[string]map[string]map[string]time.Time),
-		Anonymizer:  NewAnonymizer(),
+		Students:          make(map[string]*Student),
+		Assignments:       make(map[string]*Assignment),
+		Grades:            make(map[string]map[string]*AssignmentGrade),
+		Handins:           make(map[string]map[string]map[string]time.Time),
+		GraderAssignments: make(map[string][]*GraderAssignment),
+		Extensions:        make(map[string]map[string]map[string]*Extension),
+		LateDayChoices:    make(map[string]map[string]map[string]int),
+		LateDays:          make(map[string]map[string]map[string]int),
+		Releases:          make(map[string]*Release),
+		Sections:          make(map[string]*Section),
+		Groups:            make(map[string]map[string]*Group),
+		Anonymizer:        NewAnonymizer(),
 	}
 }
@@ -0,0 +1,65 @@
+package kudos
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestReplaceAssignment(t *testing.T) {
+	parse := func() *Assignment {
+		asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+		testutil.Must(t, err)
+		return asgn
+	}
+	asgn := parse()
+	asgn.Groups = true
+	d := NewDB()
+	testutil.MustError(t, "no such assignment: a", d.ReplaceAssignment(asgn))
+	d.AddAssignment(asgn)
+	d.GrantExtension("a", "first", "0", &Extension{})
+	d.LateDayChoices["a"] = map[string]map[string]int{"second": {"1": 1}}
+	testutil.Must(t, d.CreateGroup(asgn, "team1"))
+
+	// not a group assignment
+	testutil.MustError(t, "groups have been created for assignment a, but the new version is not a group assignment", d.ReplaceAssignment(parse()))
+	if d.Assignments["a"] != asgn {
+		t.Errorf("assignment replaced despite error")
+	}
+
+	// no longer has handin "second"
+	replacement := parse()
+	replacement.Groups = true
+	replacement.Handins = replacement.Handins[:1]
+	testutil.MustError(t, `late days have been chosen for handin "second", which the new version of assignment a does not have`, d.ReplaceAssignment(replacement))
+
+	replacement = parse()
+	replacement.Groups = true
+	testutil.Must(t, d.ReplaceAssignment(replacement))
+	if d.Assignments["a"] != replacement {
+		t.Errorf("assignment not replaced")
+	}
+	if d.Extensions["a"]["first"]["0"] == nil {
+		t.Errorf("extension not kept")
+	}
+	if d.LateDayChoices["a"]["second"]["1"] != 1 {
+		t.Errorf("late day choice not kept")
+	}
+	if _, ok := d.Groups["a"]["team1"]; !ok {
+		t.Errorf("group not kept")
+	}
+}
+
+func TestInitMaps(t *testing.T) {
+	// as in the oldest databases
+	d := &DB{}
+	d.initMaps()
+	v := reflect.ValueOf(d).Elem()
+	for _, name := range []string{"GraderAssignments", "Extensions", "LateDayChoices", "LateDays", "Releases", "Sections", "Groups"} {
+		if v.FieldByName(name).IsNil() {
+			t.Errorf("%v not initialized", name)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package kudos
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// DecimalPlaces is the number of digits after the
+// decimal point which a Decimal can represent.
+const DecimalPlaces = 6
+
+// the number of units in 1
+const decimalScale = 1000000
+
+// A Decimal is an exact decimal number, used for grades
+// and point values so that, for example, ten deductions
+// of 0.1 points sum to exactly 1. It is stored as a fixed-
+// point number with DecimalPlaces digits after the decimal
+// point; operations whose results cannot be represented
+// exactly (such as division) round half away from zero.
+// Conversions and multiplications whose results are out of
+// the range of a Decimal (about ±9.2 trillion) panic. The
+// zero value is 0.
+//
+// Decimals are marshaled to and unmarshaled from JSON as
+// numbers, so databases and configs written when grades
+// were floating point values can still be read (values
+// with too many digits after the decimal point are rounded
+// when they are read).
+type Decimal struct {
+	// the value times decimalScale
+	units int64
+}
+
+// DecimalFromInt returns the Decimal equal to n.
+func DecimalFromInt(n int64) Decimal { return Decimal{mulUnits(n, decimalScale)} }
+
+// DecimalFromFloat returns the Decimal closest to f.
+func DecimalFromFloat(f float64) Decimal {
+	return Decimal{roundUnits(f * decimalScale)}
+}
+
+// ParseDecimal parses s, which must be a decimal number
+// (optionally in exponential notation) with at most
+// DecimalPlaces digits after the decimal point.
+func ParseDecimal(s string) (Decimal, error) {
+	d, exact, err := parseDecimal(s)
+	if err != nil {
+		return Decimal{}, err
+	}
+	if !exact {
+		return Decimal{}, fmt.Errorf("%v has more than %v digits after the decimal point", s, DecimalPlaces)
+	}
+	return d, nil
+}
+
+// parseDecimal is like ParseDecimal, except that
+// values with too many digits after the decimal
+// point are rounded; exact is false if they were.
+func parseDecimal(s string) (d Decimal, exact bool, err error) {
+	r, ok := new(big.Rat).SetString(s)
+	// big.Rat accepts fractions such as "1/2",
+	// which are not decimal numbers
+	if !ok || strings.Contains(s, "/") {
+		return Decimal{}, false, fmt.Errorf("could not parse %q as a decimal number", s)
+	}
+	r.Mul(r, big.NewRat(decimalScale, 1))
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	exact = m.Sign() == 0
+	// round half away from zero: |m|/denom >= 1/2
+	if m.Abs(m).Lsh(m, 1).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	if !q.IsInt64() {
+		return Decimal{}, false, fmt.Errorf("%v is out of range", s)
+	}
+	return Decimal{q.Int64()}, exact, nil
+}
+
+func (d Decimal) Add(e Decimal) Decimal { return Decimal{d.units + e.units} }
+func (d Decimal) Sub(e Decimal) Decimal { return Decimal{d.units - e.units} }
+func (d Decimal) Neg() Decimal          { return Decimal{-d.units} }
+
+// MulInt returns d times n.
+func (d Decimal) MulInt(n int64) Decimal { return Decimal{mulUnits(d.units, n)} }
+
+// DivInt returns d divided by n, rounded.
+func (d Decimal) DivInt(n int64) Decimal { return Decimal{divRound(d.units, n)} }
+
+// MulFloat returns d times f, rounded. It is used
+// to apply fractional factors such as late penalties.
+func (d Decimal) MulFloat(f float64) Decimal {
+	return Decimal{roundUnits(float64(d.units) * f)}
+}
+
+// Round returns d rounded to the given number
+// of digits after the decimal point.
+func (d Decimal) Round(places int) Decimal {
+	if places >= DecimalPlaces {
+		return d
+	}
+	if places < 0 {
+		places = 0
+	}
+	unit := int64(math.Pow10(DecimalPlaces - places))
+	return Decimal{divRound(d.units, unit) * unit}
+}
+
+// Places returns the number of digits after the decimal
+// point needed to represent d (0 if d is an integer).
+func (d Decimal) Places() int {
+	places := DecimalPlaces
+	for u := d.units; places > 0 && u%10 == 0; u /= 10 {
+		places--
+	}
+	return places
+}
+
+// Cmp returns -1 if d < e, 0 if d == e, and 1 if d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	switch {
+	case d.units < e.units:
+		return -1
+	case d.units > e.units:
+		return 1
+	}
+	return 0
+}
+
+// Sign returns -1 if d < 0, 0 if d == 0, and 1 if d > 0.
+func (d Decimal) Sign() int { return d.Cmp(Decimal{}) }
+
+// Float64 returns the float64 closest to d. It is
+// intended for computations which are inherently
+// inexact, such as percentages and statistics.
+func (d Decimal) Float64() float64 { return float64(d.units) / decimalScale }
+
+// String formats d with as few digits after the
+// decimal point as are needed to represent it.
+func (d Decimal) String() string {
+	u := d.units
+	sign := ""
+	if u < 0 {
+		sign = "-"
+		u = -u
+	}
+	s := fmt.Sprintf("%v%v", sign, u/decimalScale)
+	if frac := u % decimalScale; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%0*d", DecimalPlaces, frac), "0")
+	}
+	return s
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	// strings are valid arguments to big.Rat.SetString,
+	// but they aren't JSON numbers
+	if len(b) > 0 && b[0] == '"' {
+		return fmt.Errorf("cannot unmarshal string into decimal number")
+	}
+	dd, _, err := parseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = dd
+	return nil
+}
+
+// multiplies a by b, panicking on overflow
+func mulUnits(a, b int64) int64 {
+	c := a * b
+	// if a is -1 and b is math.MinInt64, c/a
+	// overflows back to b
+	if a != 0 && (c/a != b || (a == -1 && b == math.MinInt64)) {
+		panic("lib/kudos: Decimal out of range")
+	}
+	return c
+}
+
+// rounds f half away from zero, panicking
+// if the result is not an int64
+func roundUnits(f float64) int64 {
+	f = math.Round(f)
+	// float64(math.MaxInt64) is 2^63, which
+	// is itself out of range
+	if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		panic("lib/kudos: Decimal out of range")
+	}
+	return int64(f)
+}
+
+// divides a by b, rounding half away from zero
+func divRound(a, b int64) int64 {
+	q, r := a/b, a%b
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= abs(b) {
+		if (a < 0) != (b < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// to make sorting Decimals easier
+type decimals []Decimal
+
+func (d decimals) Len() int           { return len(d) }
+func (d decimals) Less(i, j int) bool { return d[i].units < d[j].units }
+func (d decimals) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
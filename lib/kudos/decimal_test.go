@@ -0,0 +1,142 @@
+package kudos
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+// dec converts a constant to a Decimal; it
+// is intended for writing test cases concisely
+func dec(f float64) Decimal { return DecimalFromFloat(f) }
+
+var parseDecimalTests = []struct {
+	s   string
+	d   Decimal
+	str string
+	err string
+}{
+	{"0", Decimal{}, "0", ""},
+	{"50", dec(50), "50", ""},
+	{"-1.5", dec(-1.5), "-1.5", ""},
+	{"0.1", dec(0.1), "0.1", ""},
+	{"12.340000", dec(12.34), "12.34", ""},
+	{"1e2", dec(100), "100", ""},
+	{"2.5E-3", dec(0.0025), "0.0025", ""},
+	{"0.000001", Decimal{1}, "0.000001", ""},
+	{"0.0000001", Decimal{}, "", "0.0000001 has more than 6 digits after the decimal point"},
+	{"1/2", Decimal{}, "", `could not parse "1/2" as a decimal number`},
+	{"a", Decimal{}, "", `could not parse "a" as a decimal number`},
+	{"1e30", Decimal{}, "", "1e30 is out of range"},
+}
+
+func TestParseDecimal(t *testing.T) {
+	for i, test := range parseDecimalTests {
+		d, err := ParseDecimal(test.s)
+		prefix := fmt.Sprintf("test case %v (%q)", i, test.s)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+		if d != test.d || d.String() != test.str {
+			t.Errorf("%v: got %v (%v); want %v", prefix, d, d.units, test.str)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	// the motivating example: with float64,
+	// this sum is 0.9999999999999999
+	var sum Decimal
+	for i := 0; i < 10; i++ {
+		sum = sum.Add(dec(0.1))
+	}
+	if sum != dec(1) {
+		t.Errorf("unexpected sum: got %v; want 1", sum)
+	}
+
+	for i, test := range []struct {
+		got, want Decimal
+	}{
+		{dec(10).DivInt(3), Decimal{3333333}},
+		{dec(20).DivInt(3), Decimal{6666667}},
+		{dec(-20).DivInt(3), Decimal{-6666667}},
+		{dec(7).DivInt(2), dec(3.5)},
+		{dec(45).MulFloat(0.9), dec(40.5)},
+		{dec(2.5).MulInt(3), dec(7.5)},
+		{dec(1.005).Round(2), dec(1.01)},
+		{dec(-1.005).Round(2), dec(-1.01)},
+		{dec(1.004).Round(2), dec(1)},
+		{dec(15).Round(0), dec(15)},
+		{dec(4).Sub(dec(6.5)), dec(-2.5)},
+	} {
+		if test.got != test.want {
+			t.Errorf("test case %v: got %v; want %v", i, test.got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		d      Decimal
+		places int
+	}{
+		{dec(0), 0}, {dec(50), 0}, {dec(0.5), 1}, {dec(-0.25), 2}, {Decimal{1}, 6},
+	} {
+		if test.d.Places() != test.places {
+			t.Errorf("unexpected places for %v: got %v; want %v", test.d, test.d.Places(), test.places)
+		}
+	}
+}
+
+func TestDecimalOverflow(t *testing.T) {
+	for i, f := range []func(){
+		func() { DecimalFromInt(1 << 44) },
+		func() { DecimalFromFloat(1e13) },
+		func() { DecimalFromFloat(math.NaN()) },
+		func() { dec(1e12).MulInt(10) },
+		func() { dec(-1e12).MulInt(-10) },
+		func() { Decimal{math.MinInt64}.MulInt(-1) },
+		func() { Decimal{-1}.MulInt(math.MinInt64) },
+		func() { dec(1e12).MulFloat(10) },
+	} {
+		err := func() (err error) {
+			defer func() {
+				r := recover()
+				if r != nil {
+					err = fmt.Errorf("%v", r)
+				}
+			}()
+			f()
+			return nil
+		}()
+		testutil.MustErrorPrefix(t, fmt.Sprintf("test case %v", i), "lib/kudos: Decimal out of range", err)
+	}
+
+	// the largest values are still in range
+	if d := DecimalFromInt(9e12); d != dec(9e12) {
+		t.Errorf("unexpected value: got %v; want 9e12", d)
+	}
+	if d := (Decimal{math.MinInt64}).MulInt(1); d.units != math.MinInt64 {
+		t.Errorf("unexpected value: got %v; want %v", d.units, int64(math.MinInt64))
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	buf, err := json.Marshal([]Decimal{dec(1.5), dec(-2), {}})
+	testutil.Must(t, err)
+	if string(buf) != "[1.5,-2,0]" {
+		t.Errorf("unexpected JSON: got %s; want [1.5,-2,0]", buf)
+	}
+
+	// values written as floating point numbers
+	// (as in old databases) are rounded
+	var ds []Decimal
+	testutil.Must(t, json.Unmarshal([]byte("[49.99999999999999, 0.30000000000000004, 5.551115123125783e-17]"), &ds))
+	if len(ds) != 3 || ds[0] != dec(50) || ds[1] != dec(0.3) || ds[2] != dec(0) {
+		t.Errorf("unexpected values: got %v; want [50 0.3 0]", ds)
+	}
+	testutil.MustError(t, "cannot unmarshal string into decimal number", json.Unmarshal([]byte(`["1"]`), &ds))
+}
@@ -0,0 +1,125 @@
+package kudos
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A GraderAssignment records how the rubrics for some
+// of an assignment's problems were distributed among
+// graders.
+type GraderAssignment struct {
+	Problems []string
+	// keys are student UIDs; values are grader UIDs
+	Graders map[string]string
+	// keys are student UIDs; values are anonymous
+	// tokens; nil if the rubrics were not anonymous
+	Tokens map[string]string
+
+	AssignerUID string
+	Time        time.Time
+}
+
+// AssignGraders distributes students among graders so
+// that the number of students given to each grader is
+// as balanced as possible, and so that no student is
+// given to a grader for which conflict(student, grader)
+// returns true. The result maps each student to its
+// grader. The assignment is deterministic for a given
+// input; students with the fewest eligible graders are
+// assigned first, and ties between equally-loaded graders
+// are broken in favor of graders which appear earlier
+// in graders. If some student has no eligible grader,
+// an error is returned.
+func AssignGraders(students, graders []string, conflict func(student, grader string) bool) (map[string]string, error) {
+	if len(graders) == 0 {
+		return nil, fmt.Errorf("no graders given")
+	}
+
+	eligible := make(map[string][]int)
+	for _, s := range students {
+		for i, g := range graders {
+			if !conflict(s, g) {
+				eligible[s] = append(eligible[s], i)
+			}
+		}
+		if len(eligible[s]) == 0 {
+			return nil, fmt.Errorf("no eligible grader for student %v", s)
+		}
+	}
+
+	// order students by number of eligible
+	// graders (stable so that the result
+	// is deterministic)
+	ordered := make([]string, len(students))
+	copy(ordered, students)
+	for i := 1; i < len(ordered); i++ {
+		for j := i; j > 0 && len(eligible[ordered[j]]) < len(eligible[ordered[j-1]]); j-- {
+			ordered[j], ordered[j-1] = ordered[j-1], ordered[j]
+		}
+	}
+
+	load := make([]int, len(graders))
+	result := make(map[string]string)
+	for _, s := range ordered {
+		best := -1
+		for _, i := range eligible[s] {
+			if best == -1 || load[i] < load[best] {
+				best = i
+			}
+		}
+		load[best]++
+		result[s] = graders[best]
+	}
+	return result, nil
+}
+
+// AssignHandinGraders is like AssignGraders, except that
+// students who share a handin of asgn (see HandinOwners)
+// are given to the same grader, and count as one student
+// when balancing graders. A group is in conflict with a
+// grader if any of its members are.
+func (d *DB) AssignHandinGraders(asgn *Assignment, students, graders []string, conflict func(student, grader string) bool) (map[string]string, error) {
+	owners := d.HandinOwners(asgn, students)
+	var names []string
+	for name := range owners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	conflicts := func(name, grader string) bool {
+		for _, uid := range owners[name] {
+			if conflict(uid, grader) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, name := range names {
+		// report groups by name rather than as
+		// students, as AssignGraders would
+		if len(owners[name]) == 1 && owners[name][0] == name {
+			continue
+		}
+		eligible := false
+		for _, g := range graders {
+			eligible = eligible || !conflicts(name, g)
+		}
+		if !eligible && len(graders) > 0 {
+			return nil, fmt.Errorf("no eligible grader for group %v", name)
+		}
+	}
+
+	assigned, err := AssignGraders(names, graders, conflicts)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for name, grader := range assigned {
+		for _, uid := range owners[name] {
+			result[uid] = grader
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package kudos
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+var assignGradersTests = []struct {
+	students  []string
+	graders   []string
+	conflicts map[string]string
+	result    map[string]string
+	err       string
+}{
+	{[]string{"1", "2", "3", "4"}, []string{"a", "b"}, nil,
+		map[string]string{"1": "a", "2": "b", "3": "a", "4": "b"}, ""},
+	{[]string{"1", "2", "3"}, []string{"a", "b"}, map[string]string{"3": "a"},
+		map[string]string{"3": "b", "1": "a", "2": "a"}, ""},
+	{[]string{"1", "2"}, []string{"a"}, map[string]string{"2": "a"},
+		nil, "no eligible grader for student 2"},
+	{[]string{"1"}, nil, nil, nil, "no graders given"},
+}
+
+func TestAssignGraders(t *testing.T) {
+	for i, test := range assignGradersTests {
+		conflict := func(s, g string) bool { return test.conflicts[s] == g }
+		res, err := AssignGraders(test.students, test.graders, conflict)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+		if !reflect.DeepEqual(res, test.result) {
+			t.Errorf("%v: got %v; want %v", prefix, res, test.result)
+		}
+	}
+}
+
+func TestAssignHandinGraders(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	asgn.Groups = true
+	testutil.Must(t, d.CreateGroup(asgn, "team1"))
+	testutil.Must(t, d.CreateGroup(asgn, "team2"))
+	for uid, group := range map[string]string{"1": "team1", "2": "team1", "3": "team1", "4": "team2", "5": "team2"} {
+		testutil.Must(t, d.AddGroupMember(asgn, group, uid, GradeEditor{}))
+	}
+	students := []string{"1", "2", "3", "4", "5", "6"}
+	conflicts := map[string]string{"2": "a"}
+	conflict := func(s, g string) bool { return conflicts[s] == g }
+
+	// groups share a grader, count as one student
+	// when balancing, and conflict with a grader
+	// if any member does
+	res, err := d.AssignHandinGraders(asgn, students, []string{"a", "b"}, conflict)
+	testutil.Must(t, err)
+	expect := map[string]string{"1": "b", "2": "b", "3": "b", "4": "a", "5": "a", "6": "a"}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("got %v; want %v", res, expect)
+	}
+
+	_, err = d.AssignHandinGraders(asgn, students, []string{"a"}, conflict)
+	testutil.MustError(t, "no eligible grader for group team1", err)
+
+	// for other assignments, groups are ignored
+	asgn.Groups = false
+	res, err = d.AssignHandinGraders(asgn, students, []string{"a", "b"}, conflict)
+	testutil.Must(t, err)
+	expect = map[string]string{"2": "b", "1": "a", "3": "a", "4": "b", "5": "a", "6": "b"}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("got %v; want %v", res, expect)
+	}
+}
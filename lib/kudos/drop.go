@@ -0,0 +1,85 @@
+package kudos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joshlf/kudos/lib/config"
+	"github.com/joshlf/kudos/lib/perm"
+)
+
+// writeDropFile writes v, encoded as JSON, to the file
+// with the given name in dir, which is one student's
+// folder in a drop directory (see handin.InitFaclDropDir).
+// The file is first written to a temporary file whose
+// name matches tmpPattern (as in ioutil.TempFile), which
+// must end in ".tmp".
+func writeDropFile(dir, name, tmpPattern string, v interface{}) (err error) {
+	buf, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that
+	// a partially-written file will not be
+	// read (temporary files are ignored)
+	f, err := ioutil.TempFile(dir, tmpPattern)
+	if err != nil {
+		return err
+	}
+	tmppath := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmppath)
+		}
+	}()
+	_, err = f.Write(buf)
+	if err == nil {
+		// the directory's default ACL should make the
+		// file group-readable, but set the permissions
+		// explicitly in case it is missing
+		err = f.Chmod(perm.Parse("rw-rw----"))
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmppath, filepath.Join(dir, name))
+}
+
+// readDropFiles calls decode on the contents of each of
+// the files in dir (which was written to by writeDropFile)
+// in lexical order of their names, and returns the paths
+// of the files which were decoded successfully. If dir
+// does not exist, no files are read. Since students can
+// write arbitrary files to their folders, files which
+// cannot be read or decoded are skipped with a warning
+// rather than causing an error.
+func (c *Context) readDropFiles(dir string, decode func(buf []byte) error) (paths []string, err error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, fi := range infos {
+		path := filepath.Join(dir, fi.Name())
+		if config.IgnoreFileAndLog(c.Debug.Printf, path) {
+			continue
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			c.Warn.Printf("warning: skipping %v: %v\n", path, err)
+			continue
+		}
+		if err := decode(buf); err != nil {
+			c.Warn.Printf("warning: skipping %v: could not parse: %v\n", path, err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,128 @@
+package kudos
+
+import "time"
+
+// An Excusal records that a student has been excused
+// from a problem or from an entire assignment. Excused
+// problems are left out of both the points a student
+// receives and the points the student's grade is out of.
+//
+// An excusal takes precedence over any grades for the
+// problem and its subproblems. However, if a problem
+// has a grade, excusals of its subproblems have no
+// effect, since the grade covers them.
+type Excusal struct {
+	Reason     string
+	GranterUID string
+	Time       time.Time
+}
+
+// Excusal returns the excusal which applies to the given
+// problem of asgn: an excusal from the entire assignment,
+// from the problem itself, or from one of its ancestors.
+// If problem is the empty string, only an excusal from the
+// entire assignment is considered.
+func (a *AssignmentGrade) Excusal(asgn *Assignment, problem string) (e Excusal, ok bool) {
+	if e, ok := a.Excused[""]; ok {
+		return e, true
+	}
+	if problem == "" {
+		return Excusal{}, false
+	}
+	path, _ := asgn.FindProblemPathByCode(problem)
+	for _, code := range append(path, problem) {
+		if e, ok := a.Excused[code]; ok {
+			return e, true
+		}
+		if _, ok := a.Grades[code]; ok {
+			// the grade covers any
+			// excused subproblems
+			return Excusal{}, false
+		}
+	}
+	return Excusal{}, false
+}
+
+// AssignmentExcused returns whether the student
+// has been excused from the entire assignment.
+func (a *AssignmentGrade) AssignmentExcused() bool {
+	_, ok := a.Excused[""]
+	return ok
+}
+
+// OutOf computes the number of points which a's total
+// (see Total) is out of: the total points of asgn (not
+// including extra credit), less the points for any
+// excused problems.
+func (a *AssignmentGrade) OutOf(asgn *Assignment) Decimal {
+	var total Decimal
+	for _, p := range asgn.Problems {
+		total = total.Add(a.ProblemOutOf(asgn, p.Code))
+	}
+	return total
+}
+
+// ProblemOutOf is like OutOf, but computes the number of
+// points which a's total on the given problem is out of.
+func (a *AssignmentGrade) ProblemOutOf(asgn *Assignment, problem string) Decimal {
+	if _, ok := a.Excusal(asgn, problem); ok {
+		return Decimal{}
+	}
+	p, _ := asgn.FindProblemByCode(problem)
+	if p.ExtraCredit {
+		return Decimal{}
+	}
+	if _, ok := a.Grades[problem]; ok || len(p.Subproblems) == 0 {
+		return p.Points
+	}
+	if !p.Aggregate.sum() {
+		return a.aggregateProblemOutOf(asgn, p)
+	}
+	var total Decimal
+	for _, pp := range p.Subproblems {
+		total = total.Add(a.ProblemOutOf(asgn, pp.Code))
+	}
+	return total
+}
+
+// Excuse excuses the given student from the given problem
+// of asgn (or from the entire assignment if problem is the
+// empty string), replacing any previous excusal from the same
+// problem. It returns true if a previous excusal was replaced.
+// Excuse panics if the problem does not exist in asgn.
+func (d *DB) Excuse(asgn *Assignment, uid, problem string, e Excusal) (replaced bool) {
+	if problem != "" {
+		if _, ok := asgn.FindProblemByCode(problem); !ok {
+			panic("lib/kudos: DB.Excuse: no such problem")
+		}
+	}
+	g, ok := d.Grades[asgn.Code][uid]
+	if !ok {
+		g = NewAssignmentGrade()
+		d.Grades[asgn.Code][uid] = g
+	}
+	// grades created before excusals were
+	// introduced will not have this map
+	if g.Excused == nil {
+		g.Excused = make(map[string]Excusal)
+	}
+	_, replaced = g.Excused[problem]
+	g.Excused[problem] = e
+	return replaced
+}
+
+// Unexcuse revokes the given student's excusal from the
+// given problem of asgn (or from the entire assignment if
+// problem is the empty string). It returns false if the
+// student had not been excused.
+func (d *DB) Unexcuse(asgn *Assignment, uid, problem string) bool {
+	g, ok := d.Grades[asgn.Code][uid]
+	if !ok {
+		return false
+	}
+	if _, ok := g.Excused[problem]; !ok {
+		return false
+	}
+	delete(g.Excused, problem)
+	return true
+}
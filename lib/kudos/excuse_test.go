@@ -0,0 +1,96 @@
+package kudos
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestExcusal(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	editor := GradeEditor{UID: "100"}
+	e := Excusal{Reason: "sick", GranterUID: "100"}
+
+	// excused from a subproblem: prob2 is
+	// complete, and out of 25 points
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(40)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "0", "a", ProblemGrade{Grade: dec(20)}, false, editor))
+	if d.Excuse(asgn, "0", "b", e) {
+		t.Errorf("unexpected replaced excusal")
+	}
+	g := d.Grades[asgn.Code]["0"]
+	if total, ok := g.Total(asgn); !ok || total != dec(60) || g.OutOf(asgn) != dec(75) {
+		t.Errorf("unexpected total: got %v/%v (%v); want 60/75 (true)", total, g.OutOf(asgn), ok)
+	}
+	if _, ok := g.Excusal(asgn, "a"); ok {
+		t.Errorf("unexpected excusal from sibling of excused problem")
+	}
+	if g.AssignmentExcused() {
+		t.Errorf("unexpected excusal from entire assignment")
+	}
+
+	// a grade on the parent covers the excused subproblem
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob2", ProblemGrade{Grade: dec(45)}, true, editor))
+	g = d.Grades[asgn.Code]["0"]
+	if _, ok := g.Excusal(asgn, "b"); ok {
+		t.Errorf("unexpected excusal from subproblem of graded problem")
+	}
+	if total, ok := g.Total(asgn); !ok || total != dec(85) || g.OutOf(asgn) != dec(100) {
+		t.Errorf("unexpected total: got %v/%v (%v); want 85/100 (true)", total, g.OutOf(asgn), ok)
+	}
+
+	// an excusal of the problem itself takes
+	// precedence over its grade
+	if !d.Excuse(asgn, "0", "b", e) {
+		t.Errorf("expected replaced excusal")
+	}
+	d.Excuse(asgn, "0", "prob2", e)
+	if total, ok := g.Total(asgn); !ok || total != dec(40) || g.OutOf(asgn) != dec(50) {
+		t.Errorf("unexpected total: got %v/%v (%v); want 40/50 (true)", total, g.OutOf(asgn), ok)
+	}
+
+	// excused from the entire assignment
+	// without having any grades
+	d.Excuse(asgn, "1", "", e)
+	g = d.Grades[asgn.Code]["1"]
+	if total, ok := g.Total(asgn); !ok || total != dec(0) || g.OutOf(asgn) != dec(0) || !g.AssignmentExcused() {
+		t.Errorf("unexpected total: got %v/%v (%v); want 0/0 (true)", total, g.OutOf(asgn), ok)
+	}
+	p := d.PubGrade(&Course{}, asgn, "1")
+	if !p.Excused || !p.Complete || p.OutOf != dec(0) || !p.Problems[0].Excused {
+		t.Errorf("unexpected published grade: %+v", p)
+	}
+
+	if !d.Unexcuse(asgn, "1", "") {
+		t.Errorf("unexpected failure to revoke excusal")
+	}
+	if d.Unexcuse(asgn, "1", "") {
+		t.Errorf("unexpectedly revoked nonexistent excusal")
+	}
+	if _, ok := g.Total(asgn); ok {
+		t.Errorf("unexpected complete grade after revoking excusal")
+	}
+}
+
+func TestFinalGradeExcused(t *testing.T) {
+	c, err := parseCourse(strings.NewReader(`{"code":"course","ta_group":"tas","grading":{
+		"categories":[{"code":"homework","weight":100,"assignments":["hw1","hw2","hw3"]}]}}`))
+	testutil.Must(t, err)
+	d := makeGradingTestDB("hw1", "hw2", "hw3")
+	setTestGrade(d, "hw1", "0", 10)
+	setTestGrade(d, "hw2", "0", 5)
+	d.Excuse(d.Assignments["hw3"], "0", "", Excusal{})
+
+	f, err := d.FinalGrade(c, "0")
+	testutil.Must(t, err)
+	expect := &FinalGrade{
+		Percent:    75,
+		Categories: []CategoryGrade{{Code: "homework", Percent: 75}},
+		Excused:    []string{"hw3"},
+	}
+	if !reflect.DeepEqual(f, expect) {
+		t.Errorf("unexpected final grade: got %+v; want %+v", f, expect)
+	}
+}
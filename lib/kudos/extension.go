@@ -0,0 +1,51 @@
+package kudos
+
+import "time"
+
+// An Extension grants a student a new due date
+// for a handin.
+type Extension struct {
+	Due        time.Time
+	GranterUID string
+	Reason     string
+	// the time at which the extension was granted
+	Time time.Time
+}
+
+// GrantExtension records e as the extension for the
+// given student on the given handin of the given
+// assignment, replacing any previous extension. As
+// with d.Handins, handin should be the empty string
+// if the assignment has only one handin.
+func (d *DB) GrantExtension(assignment, handin, uid string, e *Extension) {
+	if d.Extensions[assignment] == nil {
+		d.Extensions[assignment] = make(map[string]map[string]*Extension)
+	}
+	if d.Extensions[assignment][handin] == nil {
+		d.Extensions[assignment][handin] = make(map[string]*Extension)
+	}
+	d.Extensions[assignment][handin][uid] = e
+}
+
+// RevokeExtension deletes the given student's extension
+// on the given handin of the given assignment. It returns
+// true if the extension was deleted and false if there
+// was no such extension.
+func (d *DB) RevokeExtension(assignment, handin, uid string) bool {
+	if _, ok := d.Extensions[assignment][handin][uid]; !ok {
+		return false
+	}
+	delete(d.Extensions[assignment][handin], uid)
+	return true
+}
+
+// DueDate returns the date on which the given
+// student's handin h for asgn is due, taking
+// into account any extension the student has
+// been granted.
+func (d *DB) DueDate(asgn *Assignment, h Handin, uid string) time.Time {
+	if e, ok := d.Extensions[asgn.Code][h.Code][uid]; ok {
+		return e.Due
+	}
+	return h.Due
+}
@@ -0,0 +1,67 @@
+package kudos
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+const extraCreditTestAssignment = `{"code":"a","extra_credit_cap":10,
+	"handins":[{"due":"Jul 4, 2015 at 12:00am (EST)","problems":["prob1","prob2","bonus"]}],
+	"problems":[
+		{"code":"prob1","points":50,"subproblems":[
+			{"code":"a","points":50},
+			{"code":"b","points":10,"extra_credit":true}
+		]},
+		{"code":"prob2","points":50},
+		{"code":"bonus","points":20,"extra_credit":true,"subproblems":[
+			{"code":"c","points":10},
+			{"code":"d","points":10}
+		]}
+	]}`
+
+func TestExtraCredit(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(extraCreditTestAssignment))
+	testutil.Must(t, err)
+	if asgn.TotalPoints() != dec(100) || asgn.ExtraCreditPoints() != dec(30) {
+		t.Fatalf("unexpected points: got %v (+%v); want 100 (+30)", asgn.TotalPoints(), asgn.ExtraCreditPoints())
+	}
+	if p, _ := asgn.FindProblemByCode("d"); !p.ExtraCredit {
+		t.Errorf("subproblem of extra credit problem is not extra credit")
+	}
+
+	d := NewDB()
+	d.AddAssignment(asgn)
+	editor := GradeEditor{UID: "100"}
+	set := func(problem string, grade float64) {
+		testutil.Must(t, d.SetGrade(asgn, "0", problem, ProblemGrade{Grade: dec(grade)}, false, editor))
+	}
+	check := func(expect float64) {
+		g := d.Grades[asgn.Code]["0"]
+		total, ok := g.Total(asgn)
+		if !ok || total != dec(expect) || g.OutOf(asgn) != dec(100) {
+			t.Errorf("unexpected total: got %v/%v (%v); want %v/100 (true)", total, g.OutOf(asgn), ok, expect)
+		}
+	}
+
+	// ungraded extra credit problems
+	// do not make the grade incomplete
+	set("a", 40)
+	set("prob2", 45)
+	check(85)
+	set("b", 10)
+	check(95)
+	// partially-graded extra credit
+	// problems count
+	set("c", 10)
+	check(105)
+	// capped at 110%
+	set("d", 10)
+	check(110)
+
+	lateness := []HandinLateness{{Penalty: 0.5}}
+	if total, ok := d.Grades[asgn.Code]["0"].AdjustedTotal(asgn, lateness); !ok || total != dec(57.5) {
+		t.Errorf("unexpected adjusted total: got %v (%v); want 57.5 (true)", total, ok)
+	}
+}
@@ -1,5 +1,36 @@
 package kudos
 
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrGradeExists = errors.New("grade already assigned")
+)
+
+// A ParentGradedError is returned when a grade cannot
+// be assigned to a problem because one of the problem's
+// ancestors already has a grade.
+type ParentGradedError struct {
+	Parent string
+}
+
+func (p *ParentGradedError) Error() string {
+	return fmt.Sprintf("grade already assigned to parent problem %v", p.Parent)
+}
+
+// A SubproblemGradedError is returned when a grade cannot
+// be assigned to a problem because one of the problem's
+// descendants already has a grade.
+type SubproblemGradedError struct {
+	Subproblem string
+}
+
+func (s *SubproblemGradedError) Error() string {
+	return fmt.Sprintf("grade already assigned to subproblem %v", s.Subproblem)
+}
+
 type AssignmentGrade struct {
 	// Grades contains the grade for every
 	// problem, including those which are not
@@ -7,52 +38,163 @@ type AssignmentGrade struct {
 	// be maintained that if a problem has
 	// a grade, none of its children have grades.
 	Grades map[string]ProblemGrade
+	// keys are problem codes, or the empty string
+	// if the student is excused from the entire
+	// assignment (see Excusal)
+	Excused map[string]Excusal
 }
 
 // Total computes the total number of points given by
 // the AssignmentGrade a on the Assignment asgn. If
 // a is not a complete grade, Total returns false.
-// If a is not a grade for asgn, the behavior of Total
-// is undefined (and it will likely panic).
-func (a *AssignmentGrade) Total(asgn *Assignment) (grade float64, ok bool) {
-	total := 0.0
+// Excused problems count as complete, and contribute
+// no points (see OutOf). Extra credit problems without
+// grades contribute no points, and if asgn caps extra
+// credit (see Assignment.ExtraCreditCap), the total is
+// capped accordingly. If a is not a grade for asgn, the
+// behavior of Total is undefined (and it will likely panic).
+func (a *AssignmentGrade) Total(asgn *Assignment) (grade Decimal, ok bool) {
+	var total Decimal
 	for _, p := range asgn.Problems {
 		g, ok := a.ProblemTotal(asgn, p.Code)
 		if !ok {
-			return 0.0, false
+			if p.ExtraCredit {
+				continue
+			}
+			return Decimal{}, false
 		}
-		total += g
+		total = total.Add(g)
 	}
-	return total, true
+	return asgn.capTotal(total, a.OutOf(asgn)), true
 }
 
 // ProblemTotal computes the total number of points
 // given by the AssignmentGrade a on the given problem
 // of the given assignment. If a is not a complete
 // grade for the given problem, ProblemTotal returns
-// false. If a is not a grade for asgn, the behavior
+// false. Subproblem grades are combined according to
+// the problem's aggregate rule (see AggregateRule).
+// Ungraded extra credit subproblems contribute no
+// points. If a is not a grade for asgn, the behavior
 // of ProblemTotal is undefined (and it will likely panic).
-func (a *AssignmentGrade) ProblemTotal(asgn *Assignment, problem string) (grade float64, ok bool) {
+func (a *AssignmentGrade) ProblemTotal(asgn *Assignment, problem string) (grade Decimal, ok bool) {
+	if _, ok := a.Excusal(asgn, problem); ok {
+		return Decimal{}, true
+	}
 	if g, ok := a.Grades[problem]; ok {
 		return g.Grade, true
 	}
-	total := 0.0
+	var total Decimal
 	p, _ := asgn.FindProblemByCode(problem)
 	if len(p.Subproblems) == 0 {
-		return 0.0, false
+		return Decimal{}, false
+	}
+	if !p.Aggregate.sum() {
+		return a.aggregateProblemTotal(asgn, p)
 	}
+	graded := false
 	for _, pp := range p.Subproblems {
 		g, ok := a.ProblemTotal(asgn, pp.Code)
 		if !ok {
-			return 0.0, false
+			// ungraded extra credit problems do
+			// not make their parents incomplete
+			if pp.ExtraCredit {
+				continue
+			}
+			return Decimal{}, false
 		}
-		total += g
+		total = total.Add(g)
+		graded = true
+	}
+	// an extra credit problem is only complete
+	// if at least one of its subproblems is
+	if p.ExtraCredit && !graded {
+		return Decimal{}, false
 	}
 	return total, true
 }
 
+// NewAssignmentGrade returns a new, empty AssignmentGrade.
+func NewAssignmentGrade() *AssignmentGrade {
+	return &AssignmentGrade{
+		Grades:  make(map[string]ProblemGrade),
+		Excused: make(map[string]Excusal),
+	}
+}
+
+// Clone returns a deep copy of a.
+func (a *AssignmentGrade) Clone() *AssignmentGrade {
+	aa := NewAssignmentGrade()
+	for code, g := range a.Grades {
+		aa.Grades[code] = g
+	}
+	for code, e := range a.Excused {
+		aa.Excused[code] = e
+	}
+	return aa
+}
+
+// SetProblemGrade assigns g as the grade for the given
+// problem of asgn, maintaining the invariant that if a
+// problem has a grade, none of its ancestors or descendants
+// do. If one of the problem's ancestors has a grade,
+// SetProblemGrade returns a *ParentGradedError. If force
+// is false and the problem itself already has a grade,
+// ErrGradeExists is returned, and if one of its descendants
+// has a grade, a *SubproblemGradedError is returned. If
+// force is true, any existing grades for the problem and
+// its descendants are deleted. If an error is returned,
+// a is left unmodified. SetProblemGrade panics if the
+// problem does not exist in asgn.
+func (a *AssignmentGrade) SetProblemGrade(asgn *Assignment, problem string, g ProblemGrade, force bool) error {
+	prob, ok := asgn.FindProblemByCode(problem)
+	if !ok {
+		panic("lib/kudos: SetProblemGrade: no such problem")
+	}
+	path, _ := asgn.FindProblemPathByCode(problem)
+
+	// it doesn't matter what order we traverse the path
+	// in because (assuming a is valid), at most one
+	// parent can have a grade assigned to it
+	for _, elem := range path {
+		if _, ok := a.Grades[elem]; ok {
+			return &ParentGradedError{elem}
+		}
+	}
+
+	if !force {
+		if _, ok := a.Grades[problem]; ok {
+			return ErrGradeExists
+		}
+		var err error
+		for _, p := range prob.Subproblems {
+			p.TraversePreOrder(func(p Problem) {
+				if _, ok := a.Grades[p.Code]; ok && err == nil {
+					err = &SubproblemGradedError{p.Code}
+				}
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	prob.TraversePreOrder(func(p Problem) { delete(a.Grades, p.Code) })
+	a.Grades[problem] = g
+	return nil
+}
+
 type ProblemGrade struct {
-	Grade     float64
+	Grade Decimal
+	// if the problem is graded on a scale,
+	// the symbol given (Grade is its value;
+	// see Scale); otherwise, empty
+	Symbol    string
 	Comment   string
 	GraderUID string
+	// if the grade was given to the student's
+	// group (see DB.SetGroupGrade), the group's
+	// code; otherwise, empty (in which case the
+	// grade overrides the group's grade)
+	Group string
 }
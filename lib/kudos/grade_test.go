@@ -0,0 +1,57 @@
+package kudos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+var setProblemGradeTests = []struct {
+	before  []string
+	problem string
+	force   bool
+	err     string
+	after   []string
+}{
+	{nil, "prob1", false, "", []string{"prob1"}},
+	{nil, "a", false, "", []string{"a"}},
+	{[]string{"prob1"}, "prob1", false, "grade already assigned", []string{"prob1"}},
+	{[]string{"prob1"}, "prob1", true, "", []string{"prob1"}},
+	{[]string{"prob2"}, "a", false, "grade already assigned to parent problem prob2", []string{"prob2"}},
+	{[]string{"prob2"}, "a", true, "grade already assigned to parent problem prob2", []string{"prob2"}},
+	{[]string{"a"}, "prob2", false, "grade already assigned to subproblem a", []string{"a"}},
+	{[]string{"a", "b"}, "prob2", true, "", []string{"prob2"}},
+	{[]string{"a", "prob1"}, "b", false, "", []string{"a", "b", "prob1"}},
+}
+
+func TestSetProblemGrade(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+	testutil.Must(t, err)
+	for i, test := range setProblemGradeTests {
+		a := NewAssignmentGrade()
+		for _, p := range test.before {
+			a.Grades[p] = ProblemGrade{}
+		}
+		err := a.SetProblemGrade(asgn, test.problem, ProblemGrade{}, test.force)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err == "" {
+			testutil.MustPrefix(t, prefix, err)
+		} else {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+		}
+		got := make(map[string]bool)
+		for p := range a.Grades {
+			got[p] = true
+		}
+		want := make(map[string]bool)
+		for _, p := range test.after {
+			want[p] = true
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got grades for %v; want %v", prefix, got, want)
+		}
+	}
+}
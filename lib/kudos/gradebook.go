@@ -0,0 +1,209 @@
+package kudos
+
+// A CellStatus describes the state of a single
+// grade in a Gradebook.
+type CellStatus string
+
+const (
+	// the grade has been fully assigned
+	CellGraded CellStatus = "graded"
+	// no grade has been assigned
+	CellMissing CellStatus = "missing"
+	// some, but not all, of the subproblems
+	// involved have been assigned grades
+	CellIncomplete CellStatus = "incomplete"
+	// the student has been excused (see Excusal)
+	CellExcused CellStatus = "excused"
+	// one of the problem's ancestors has been
+	// graded as a whole, so the problem has no
+	// grade of its own
+	CellCovered CellStatus = "covered"
+)
+
+// A Gradebook is a table of grades with
+// one row per student.
+type Gradebook struct {
+	Columns []GradebookColumn
+	Rows    []GradebookRow
+}
+
+// A GradebookColumn describes a single column
+// of a Gradebook. Each column holds either the
+// grades for a single problem or the totals
+// for a single assignment.
+type GradebookColumn struct {
+	// Key uniquely identifies the column; it is
+	// the assignment code for assignment totals,
+	// and <assignment>:<problem> for problems
+	Key        string
+	Assignment string
+	// empty for assignment totals
+	Problem string
+	Name    string
+	// for assignment totals, OutOf does not
+	// include extra credit
+	OutOf       Decimal
+	ExtraCredit bool
+}
+
+type GradebookRow struct {
+	UID      string
+	Username string
+	// one per column, in the same order
+	Cells []GradebookCell
+}
+
+type GradebookCell struct {
+	Status CellStatus
+	// only valid if Status is CellGraded
+	Grade Decimal
+}
+
+// ProblemGradebook computes a Gradebook with a column
+// for each problem (including subproblems, in pre-order)
+// of each of the given assignments, and a row for each
+// of the given students. The uname function is used to
+// compute each student's username.
+func (d *DB) ProblemGradebook(asgns []*Assignment, uids []string, uname func(uid string) string) *Gradebook {
+	g := &Gradebook{}
+	for _, asgn := range asgns {
+		var walk func(problems []Problem)
+		walk = func(problems []Problem) {
+			for _, p := range problems {
+				name := p.Name
+				if name == "" {
+					name = p.Code
+				}
+				g.Columns = append(g.Columns, GradebookColumn{
+					Key:         asgn.Code + ":" + p.Code,
+					Assignment:  asgn.Code,
+					Problem:     p.Code,
+					Name:        name,
+					OutOf:       p.Points,
+					ExtraCredit: p.ExtraCredit,
+				})
+				walk(p.Subproblems)
+			}
+		}
+		walk(asgn.Problems)
+	}
+
+	for _, uid := range uids {
+		row := GradebookRow{UID: uid, Username: uname(uid)}
+		for _, col := range g.Columns {
+			var cell GradebookCell
+			asgn := d.Assignments[col.Assignment]
+			grade, ok := d.Grades[col.Assignment][uid]
+			switch {
+			case !ok:
+				cell.Status = CellMissing
+			case excused(asgn, grade, col.Problem):
+				cell.Status = CellExcused
+			case ancestorHasGrade(asgn, grade, col.Problem):
+				cell.Status = CellCovered
+			case !problemHasGrade(asgn, grade, col.Problem):
+				cell.Status = CellMissing
+			default:
+				cell.Grade, ok = grade.ProblemTotal(asgn, col.Problem)
+				if ok {
+					cell.Status = CellGraded
+				} else {
+					cell.Status = CellIncomplete
+				}
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		g.Rows = append(g.Rows, row)
+	}
+	return g
+}
+
+// AssignmentGradebook computes a Gradebook with a column
+// for each of the given assignments, and a row for each
+// of the given students (see ProblemGradebook). Each cell
+// holds the student's total on the assignment, adjusted
+// for lateness (see AssignmentGrade.AdjustedTotal). Cells
+// are only marked excused if the student is excused from
+// every problem; otherwise, the totals of students excused
+// from some problems are scaled (as in AssignmentSamples)
+// so that they are out of the assignment's total points,
+// like everyone else's.
+func (d *DB) AssignmentGradebook(c *Course, asgns []*Assignment, uids []string, uname func(uid string) string) *Gradebook {
+	g := &Gradebook{}
+	for _, asgn := range asgns {
+		name := asgn.Name
+		if name == "" {
+			name = asgn.Code
+		}
+		g.Columns = append(g.Columns, GradebookColumn{
+			Key:        asgn.Code,
+			Assignment: asgn.Code,
+			Name:       name,
+			OutOf:      asgn.TotalPoints(),
+		})
+	}
+
+	for _, uid := range uids {
+		row := GradebookRow{UID: uid, Username: uname(uid)}
+		for _, asgn := range asgns {
+			var cell GradebookCell
+			grade, ok := d.Grades[asgn.Code][uid]
+			switch {
+			case !ok:
+				cell.Status = CellMissing
+			case grade.OutOf(asgn).Sign() == 0 && len(grade.Excused) > 0:
+				cell.Status = CellExcused
+			case len(grade.Grades) == 0:
+				cell.Status = CellMissing
+			default:
+				lateness := d.Lateness(asgn, uid, asgn.EffectiveLatePolicy(c))
+				cell.Grade, ok = grade.AdjustedTotal(asgn, lateness)
+				if ok {
+					cell.Status = CellGraded
+					if outOf, total := grade.OutOf(asgn), asgn.TotalPoints(); outOf != total {
+						cell.Grade = cell.Grade.MulFloat(total.Float64() / outOf.Float64())
+					}
+				} else {
+					cell.Status = CellIncomplete
+				}
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		g.Rows = append(g.Rows, row)
+	}
+	return g
+}
+
+// excused returns whether g excuses the
+// given problem (see AssignmentGrade.Excusal).
+func excused(asgn *Assignment, g *AssignmentGrade, problem string) bool {
+	_, ok := g.Excusal(asgn, problem)
+	return ok
+}
+
+// ancestorHasGrade returns whether g has a grade
+// for any of the given problem's ancestors.
+func ancestorHasGrade(asgn *Assignment, g *AssignmentGrade, problem string) bool {
+	path, _ := asgn.FindProblemPathByCode(problem)
+	for _, code := range path {
+		if _, ok := g.Grades[code]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// problemHasGrade returns whether g has a grade
+// for the given problem or any of its descendants.
+func problemHasGrade(asgn *Assignment, g *AssignmentGrade, problem string) bool {
+	if _, ok := g.Grades[problem]; ok {
+		return true
+	}
+	p, _ := asgn.FindProblemByCode(problem)
+	for _, pp := range p.Subproblems {
+		if problemHasGrade(asgn, g, pp.Code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package kudos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestGradebook(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	editor := GradeEditor{UID: "100"}
+	// student 0 is complete, student 1 is
+	// incomplete, and student 2 is missing
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(40)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob2", ProblemGrade{Grade: dec(45)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "1", "prob1", ProblemGrade{Grade: dec(30)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "1", "a", ProblemGrade{Grade: dec(20)}, false, editor))
+	// student 3 is excused from prob1, so their
+	// total is scaled in the assignment gradebook
+	d.Excuse(asgn, "3", "prob1", Excusal{Reason: "sick", GranterUID: "100"})
+	testutil.Must(t, d.SetGrade(asgn, "3", "prob2", ProblemGrade{Grade: dec(40)}, false, editor))
+
+	uids := []string{"0", "1", "2", "3"}
+	uname := func(uid string) string { return "user" + uid }
+	graded := func(g float64) GradebookCell { return GradebookCell{CellGraded, dec(g)} }
+	missing := GradebookCell{Status: CellMissing}
+	incomplete := GradebookCell{Status: CellIncomplete}
+	covered := GradebookCell{Status: CellCovered}
+	excused := GradebookCell{Status: CellExcused}
+
+	g := d.ProblemGradebook([]*Assignment{asgn}, uids, uname)
+	expect := &Gradebook{
+		Columns: []GradebookColumn{
+			{"a:prob1", "a", "prob1", "Problem 1", dec(50), false},
+			{"a:prob2", "a", "prob2", "Problem 2", dec(50), false},
+			{"a:a", "a", "a", "a", dec(25), false},
+			{"a:b", "a", "b", "b", dec(25), false},
+		},
+		Rows: []GradebookRow{
+			{"0", "user0", []GradebookCell{graded(40), graded(45), covered, covered}},
+			{"1", "user1", []GradebookCell{graded(30), incomplete, graded(20), missing}},
+			{"2", "user2", []GradebookCell{missing, missing, missing, missing}},
+			{"3", "user3", []GradebookCell{excused, graded(40), covered, covered}},
+		},
+	}
+	if !reflect.DeepEqual(g, expect) {
+		t.Errorf("unexpected problem gradebook: got %+v; want %+v", g, expect)
+	}
+
+	g = d.AssignmentGradebook(&Course{}, []*Assignment{asgn}, uids, uname)
+	expect = &Gradebook{
+		Columns: []GradebookColumn{{"a", "a", "", "a", dec(100), false}},
+		Rows: []GradebookRow{
+			{"0", "user0", []GradebookCell{graded(85)}},
+			{"1", "user1", []GradebookCell{incomplete}},
+			{"2", "user2", []GradebookCell{missing}},
+			{"3", "user3", []GradebookCell{graded(80)}},
+		},
+	}
+	if !reflect.DeepEqual(g, expect) {
+		t.Errorf("unexpected assignment gradebook: got %+v; want %+v", g, expect)
+	}
+}
@@ -0,0 +1,355 @@
+package kudos
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A GradingScheme describes how a student's final
+// grade is computed from their assignment grades.
+type GradingScheme struct {
+	Categories []GradingCategory
+	// sorted in order of decreasing cutoff
+	LetterGrades []LetterGrade
+}
+
+// A GradingCategory is a group of assignments
+// (for example, homeworks) which together make
+// up a fixed portion of the final grade.
+type GradingCategory struct {
+	Code string
+	Name string
+	// Weight is relative to the weights
+	// of the other categories
+	Weight float64
+	// DropLowest is the number of assignments
+	// with the lowest scores which are not
+	// counted towards the category's score
+	DropLowest  int
+	Assignments []string
+	// keys are assignment codes; assignments
+	// without an entry have a weight of 1
+	AssignmentWeights map[string]float64
+}
+
+// A LetterGrade is given to any student whose
+// final percentage is at least Cutoff (and who
+// does not qualify for a higher letter grade).
+type LetterGrade struct {
+	Letter string
+	Cutoff float64
+}
+
+// assignmentWeight returns the weight of the
+// given assignment within the category.
+func (g *GradingCategory) assignmentWeight(code string) float64 {
+	if w, ok := g.AssignmentWeights[code]; ok {
+		return w
+	}
+	return 1
+}
+
+// Letter returns the letter grade for the given
+// percentage, or the empty string if the percentage
+// is lower than all of the cutoffs.
+func (g *GradingScheme) Letter(percent float64) string {
+	for _, l := range g.LetterGrades {
+		if percent >= l.Cutoff {
+			return l.Letter
+		}
+	}
+	return ""
+}
+
+// A FinalGrade is a student's final grade
+// as computed by DB.FinalGrade.
+type FinalGrade struct {
+	Percent float64
+	Letter  string
+	// in the same order as the grading
+	// scheme's categories
+	Categories []CategoryGrade
+	// Incomplete holds the codes of assignments
+	// for which the student does not have a
+	// complete grade (including assignments in
+	// the grading scheme which have not yet been
+	// added); if it is non-empty, Percent and
+	// Letter are computed using only the complete
+	// grades
+	Incomplete []string
+	// Excused holds the codes of assignments
+	// from which the student has been excused
+	Excused []string
+}
+
+// A CategoryGrade is a student's score
+// in a single grading category.
+type CategoryGrade struct {
+	Code    string
+	Percent float64
+	// Empty is true if the student has no
+	// complete grades in this category (in
+	// which case the category does not count
+	// towards Percent in the FinalGrade)
+	Empty   bool
+	Dropped []string
+}
+
+// FinalGrade computes the final grade of the student
+// with the given UID according to c's grading scheme.
+// Each assignment's score is its lateness-adjusted total
+// (see AssignmentGrade.AdjustedTotal) as a percentage of
+// the points it is out of (see AssignmentGrade.OutOf);
+// assignments from which the student has been excused
+// are left out. Assignments in the grading scheme which
+// are not in d (for example, because they have not been
+// added yet) are reported as incomplete. It is an error
+// if c has no grading scheme, or if the grading scheme
+// refers to assignments which are worth no points.
+func (d *DB) FinalGrade(c *Course, uid string) (*FinalGrade, error) {
+	if c.Grading == nil {
+		return nil, fmt.Errorf("course has no grading scheme")
+	}
+
+	var f FinalGrade
+	var weights float64
+	for _, cat := range c.Grading.Categories {
+		var scores assignmentScores
+		for _, code := range cat.Assignments {
+			asgn, ok := d.Assignments[code]
+			if !ok {
+				f.Incomplete = append(f.Incomplete, code)
+				continue
+			}
+			total := asgn.TotalPoints()
+			if total.Sign() <= 0 {
+				return nil, fmt.Errorf("category %v: assignment %v is worth no points", cat.Code, code)
+			}
+			g, ok := d.Grades[code][uid]
+			if !ok {
+				f.Incomplete = append(f.Incomplete, code)
+				continue
+			}
+			// excused problems are left out of the total
+			// points; if the student is excused from every
+			// problem, the assignment is left out entirely
+			outOf := g.OutOf(asgn)
+			if outOf.Sign() <= 0 {
+				f.Excused = append(f.Excused, code)
+				continue
+			}
+			lateness := d.Lateness(asgn, uid, asgn.EffectiveLatePolicy(c))
+			points, ok := g.AdjustedTotal(asgn, lateness)
+			if !ok {
+				f.Incomplete = append(f.Incomplete, code)
+				continue
+			}
+			scores = append(scores, assignmentScore{code, 100 * points.Float64() / outOf.Float64(), cat.assignmentWeight(code)})
+		}
+
+		cg := CategoryGrade{Code: cat.Code}
+		// stable so that ties are broken by
+		// the order in the grading scheme
+		sort.Stable(scores)
+		// always keep at least one score
+		drop := cat.DropLowest
+		if drop >= len(scores) {
+			drop = len(scores) - 1
+		}
+		if drop < 0 {
+			drop = 0
+		}
+		for i := 0; i < drop; i++ {
+			cg.Dropped = append(cg.Dropped, scores[i].code)
+		}
+		var sum, sumWeights float64
+		for _, s := range scores[drop:] {
+			sum += s.percent * s.weight
+			sumWeights += s.weight
+		}
+		if sumWeights > 0 {
+			cg.Percent = sum / sumWeights
+			f.Percent += cg.Percent * cat.Weight
+			weights += cat.Weight
+		} else {
+			cg.Empty = true
+		}
+		f.Categories = append(f.Categories, cg)
+	}
+	if weights > 0 {
+		f.Percent /= weights
+	}
+	f.Letter = c.Grading.Letter(f.Percent)
+	return &f, nil
+}
+
+type assignmentScore struct {
+	code    string
+	percent float64
+	weight  float64
+}
+
+// implements the sort.Interface interface;
+// sorting is by increasing percentage
+type assignmentScores []assignmentScore
+
+func (a assignmentScores) Len() int           { return len(a) }
+func (a assignmentScores) Less(i, j int) bool { return a[i].percent < a[j].percent }
+func (a assignmentScores) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// implements the sort.Interface interface;
+// sorting is by decreasing cutoff
+type letterGrades []LetterGrade
+
+func (l letterGrades) Len() int           { return len(l) }
+func (l letterGrades) Less(i, j int) bool { return l[i].Cutoff > l[j].Cutoff }
+func (l letterGrades) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// NOTE: All of the convenience methods to retrieve
+// fields of the various parseable* types will either:
+//   - check to see if the field is set before dereferencing
+//     the pointer if the field is optional
+//   - assume that the field has been set and dereference
+//     the pointer if the field is mandatory
+//
+// These methods shouldn't be called except for during
+// validation (in a manner that makes sure this is safe)
+// or after validation (at which point these invariants
+// are guaranteed to hold)
+
+type parseableGradingScheme struct {
+	Categories   []parseableGradingCategory `json:"categories"`
+	LetterGrades []parseableLetterGrade     `json:"letter_grades"`
+}
+
+type parseableGradingCategory struct {
+	Code              *string            `json:"code"`
+	Name              *string            `json:"name"`
+	Weight            *float64           `json:"weight"`
+	DropLowest        *int               `json:"drop_lowest"`
+	Assignments       []string           `json:"assignments"`
+	AssignmentWeights map[string]float64 `json:"assignment_weights"`
+}
+
+type parseableLetterGrade struct {
+	Letter *string  `json:"letter"`
+	Cutoff *float64 `json:"cutoff"`
+}
+
+func (p *parseableGradingCategory) name() (s string) {
+	if p.Name != nil {
+		s = *p.Name
+	}
+	return
+}
+
+func (p *parseableGradingCategory) dropLowest() (n int) {
+	if p.DropLowest != nil {
+		n = *p.DropLowest
+	}
+	return
+}
+
+// Convert p to an exported GradingScheme type.
+// This function performs no validation,
+// so you must do validation independent
+// of this function.
+func (p *parseableGradingScheme) toGradingScheme() *GradingScheme {
+	var g GradingScheme
+	for _, c := range p.Categories {
+		g.Categories = append(g.Categories, GradingCategory{
+			Code:              *c.Code,
+			Name:              c.name(),
+			Weight:            *c.Weight,
+			DropLowest:        c.dropLowest(),
+			Assignments:       c.Assignments,
+			AssignmentWeights: c.AssignmentWeights,
+		})
+	}
+	for _, l := range p.LetterGrades {
+		g.LetterGrades = append(g.LetterGrades, LetterGrade{*l.Letter, *l.Cutoff})
+	}
+	sort.Sort(letterGrades(g.LetterGrades))
+	return &g
+}
+
+func validateGradingScheme(p *parseableGradingScheme) error {
+	if len(p.Categories) == 0 {
+		return fmt.Errorf("must have at least one category")
+	}
+	categories := make(map[string]bool)
+	assignments := make(map[string]string)
+	for _, c := range p.Categories {
+		if c.Code == nil {
+			return fmt.Errorf("category must have code")
+		}
+		if err := ValidateCode(*c.Code); err != nil {
+			return fmt.Errorf("bad category code %q: %v", *c.Code, err)
+		}
+		if categories[*c.Code] {
+			return fmt.Errorf("duplicate category: %v", *c.Code)
+		}
+		categories[*c.Code] = true
+		if err := validateGradingCategory(&c); err != nil {
+			return fmt.Errorf("category %v: %v", *c.Code, err)
+		}
+		for _, a := range c.Assignments {
+			if other, ok := assignments[a]; ok {
+				return fmt.Errorf("assignment %v is in multiple categories (%v and %v)", a, other, *c.Code)
+			}
+			assignments[a] = *c.Code
+		}
+	}
+
+	letters := make(map[string]bool)
+	cutoffs := make(map[float64]bool)
+	for _, l := range p.LetterGrades {
+		switch {
+		case l.Letter == nil || *l.Letter == "":
+			return fmt.Errorf("letter grade must have letter")
+		case letters[*l.Letter]:
+			return fmt.Errorf("duplicate letter grade: %v", *l.Letter)
+		case l.Cutoff == nil:
+			return fmt.Errorf("letter grade %v must have cutoff", *l.Letter)
+		case cutoffs[*l.Cutoff]:
+			return fmt.Errorf("letter grade %v: duplicate cutoff: %v", *l.Letter, *l.Cutoff)
+		}
+		letters[*l.Letter] = true
+		cutoffs[*l.Cutoff] = true
+	}
+	return nil
+}
+
+func validateGradingCategory(c *parseableGradingCategory) error {
+	switch {
+	case c.Weight == nil:
+		return fmt.Errorf("must have weight")
+	case *c.Weight < 0:
+		return fmt.Errorf("weight must be non-negative")
+	case len(c.Assignments) == 0:
+		return fmt.Errorf("must have at least one assignment")
+	case c.dropLowest() < 0:
+		return fmt.Errorf("drop_lowest must be non-negative")
+	case c.dropLowest() >= len(c.Assignments):
+		return fmt.Errorf("cannot drop all assignments")
+	}
+	in := make(map[string]bool)
+	for _, a := range c.Assignments {
+		if err := ValidateCode(a); err != nil {
+			return fmt.Errorf("bad assignment code %q: %v", a, err)
+		}
+		if in[a] {
+			return fmt.Errorf("duplicate assignment: %v", a)
+		}
+		in[a] = true
+	}
+	for a, w := range c.AssignmentWeights {
+		switch {
+		case !in[a]:
+			return fmt.Errorf("weight given for assignment not in category: %v", a)
+		case w < 0:
+			return fmt.Errorf("weight for assignment %v must be non-negative", a)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package kudos
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+// makeGradingTestDB creates a database with a single-problem,
+// single-handin assignment (worth 10 points) for each of the
+// given codes.
+func makeGradingTestDB(codes ...string) *DB {
+	d := NewDB()
+	for _, code := range codes {
+		d.AddAssignment(&Assignment{
+			Code:     code,
+			Handins:  []Handin{{Due: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), Problems: []string{"p"}}},
+			Problems: []Problem{{Code: "p", Points: dec(10)}},
+		})
+	}
+	return d
+}
+
+func setTestGrade(d *DB, code, uid string, grade float64) {
+	d.Grades[code][uid] = &AssignmentGrade{Grades: map[string]ProblemGrade{"p": {Grade: dec(grade)}}}
+}
+
+func TestFinalGrade(t *testing.T) {
+	c, err := parseCourse(strings.NewReader(`{"code":"course","ta_group":"tas","grading":{
+		"categories":[
+			{"code":"homework","weight":40,"drop_lowest":1,"assignments":["hw1","hw2","hw3"]},
+			{"code":"exams","weight":60,"assignments":["midterm","final"],"assignment_weights":{"final":2}}
+		],
+		"letter_grades":[{"letter":"B","cutoff":80},{"letter":"A","cutoff":90},{"letter":"C","cutoff":70}]}}`))
+	testutil.Must(t, err)
+	d := makeGradingTestDB("hw1", "hw2", "hw3", "midterm", "final")
+
+	// homework: 100, 50 (dropped), 80 => 90%
+	// exams: (60 + 2*90) / 3 => 80%
+	// total: (40*90 + 60*80) / 100 => 84%
+	setTestGrade(d, "hw1", "0", 10)
+	setTestGrade(d, "hw2", "0", 5)
+	setTestGrade(d, "hw3", "0", 8)
+	setTestGrade(d, "midterm", "0", 6)
+	setTestGrade(d, "final", "0", 9)
+	f, err := d.FinalGrade(c, "0")
+	testutil.Must(t, err)
+	expect := &FinalGrade{
+		Percent: 84,
+		Letter:  "B",
+		Categories: []CategoryGrade{
+			{Code: "homework", Percent: 90, Dropped: []string{"hw2"}},
+			{Code: "exams", Percent: 80},
+		},
+	}
+	if !reflect.DeepEqual(f, expect) {
+		t.Errorf("unexpected final grade: got %+v; want %+v", f, expect)
+	}
+
+	// student 1 has no exam grades and an incomplete
+	// grade for hw3, so only the two complete homeworks
+	// count (and the lower of them is dropped)
+	setTestGrade(d, "hw1", "1", 9)
+	setTestGrade(d, "hw2", "1", 10)
+	d.Grades["hw3"]["1"] = NewAssignmentGrade()
+	f, err = d.FinalGrade(c, "1")
+	testutil.Must(t, err)
+	expect = &FinalGrade{
+		Percent: 100,
+		Letter:  "A",
+		Categories: []CategoryGrade{
+			{Code: "homework", Percent: 100, Dropped: []string{"hw1"}},
+			{Code: "exams", Empty: true},
+		},
+		Incomplete: []string{"hw3", "midterm", "final"},
+	}
+	if !reflect.DeepEqual(f, expect) {
+		t.Errorf("unexpected final grade: got %+v; want %+v", f, expect)
+	}
+
+	// assignments which haven't been
+	// added yet are incomplete
+	d.DeleteAssignment("final")
+	f, err = d.FinalGrade(c, "0")
+	testutil.Must(t, err)
+	expect = &FinalGrade{
+		Percent: 72,
+		Letter:  "C",
+		Categories: []CategoryGrade{
+			{Code: "homework", Percent: 90, Dropped: []string{"hw2"}},
+			{Code: "exams", Percent: 60},
+		},
+		Incomplete: []string{"final"},
+	}
+	if !reflect.DeepEqual(f, expect) {
+		t.Errorf("unexpected final grade: got %+v; want %+v", f, expect)
+	}
+}
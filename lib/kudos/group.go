@@ -0,0 +1,299 @@
+package kudos
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Group is a team of students who work together on
+// a group assignment (see Assignment.Groups). A group
+// shares a single handin, and grades given to the
+// group apply to each of its members (see
+// DB.SetGroupGrade).
+type Group struct {
+	Code string
+	// the UIDs of the group's members, in
+	// the order in which they were added
+	MemberUIDs []string
+}
+
+// CreateGroup creates an empty group with the given
+// code for asgn, which must be a group assignment.
+func (d *DB) CreateGroup(asgn *Assignment, code string) error {
+	if !asgn.Groups {
+		return fmt.Errorf("assignment %v is not a group assignment", asgn.Code)
+	}
+	if _, ok := d.Groups[asgn.Code][code]; ok {
+		return fmt.Errorf("group already exists: %v", code)
+	}
+	if d.Groups[asgn.Code] == nil {
+		d.Groups[asgn.Code] = make(map[string]*Group)
+	}
+	d.Groups[asgn.Code][code] = &Group{Code: code}
+	return nil
+}
+
+// AddGroupMember adds the student with the given uid
+// to the given group of asgn. A student may only be
+// in one of an assignment's groups. The new member is
+// given the grades already given to the group (see
+// SetGroupGrade), except where they would be overridden
+// by the student's individual grades, and the changes
+// are recorded in d.GradeHistory. If members' group
+// grades conflict (as they may if some members had
+// individual grades when the group was graded), the
+// grade for the outermost problem is used.
+func (d *DB) AddGroupMember(asgn *Assignment, code, uid string, editor GradeEditor) error {
+	g, ok := d.Groups[asgn.Code][code]
+	if !ok {
+		return fmt.Errorf("no such group: %v", code)
+	}
+	if other, ok := d.StudentGroup(asgn.Code, uid); ok {
+		return fmt.Errorf("student is already in group %v", other.Code)
+	}
+
+	old := d.Grades[asgn.Code][uid]
+	var grade *AssignmentGrade
+	if old != nil {
+		grade = old.Clone()
+	} else {
+		grade = NewAssignmentGrade()
+	}
+	changed := false
+	asgn.TraverseProblemsPreOrder(func(p Problem) {
+		pg, ok := d.groupGrade(asgn.Code, g, p.Code)
+		if !ok || grade.hasIndividualGrade(asgn, p.Code) {
+			return
+		}
+		// since problems are traversed in pre-order,
+		// a *ParentGradedError means that the new
+		// member was just given the group's grade
+		// for one of the problem's ancestors
+		if grade.SetProblemGrade(asgn, p.Code, pg, true) == nil {
+			changed = true
+		}
+	})
+	if changed {
+		d.recordGradeChanges(asgn, uid, old, grade, editor)
+		d.Grades[asgn.Code][uid] = grade
+	}
+	g.MemberUIDs = append(g.MemberUIDs, uid)
+	return nil
+}
+
+// RemoveGroupMember removes the student with the
+// given uid from the given group of asgn. The grades
+// the student was given as a member of the group (see
+// SetGroupGrade) are deleted, while their individual
+// grades are kept, and the changes are recorded in
+// d.GradeHistory.
+func (d *DB) RemoveGroupMember(asgn *Assignment, code, uid string, editor GradeEditor) error {
+	g, ok := d.Groups[asgn.Code][code]
+	if !ok {
+		return fmt.Errorf("no such group: %v", code)
+	}
+	i := 0
+	for i < len(g.MemberUIDs) && g.MemberUIDs[i] != uid {
+		i++
+	}
+	if i == len(g.MemberUIDs) {
+		return fmt.Errorf("student is not in group %v", code)
+	}
+	g.MemberUIDs = append(g.MemberUIDs[:i], g.MemberUIDs[i+1:]...)
+
+	old, ok := d.Grades[asgn.Code][uid]
+	if !ok {
+		return nil
+	}
+	grade := old.Clone()
+	for problem, pg := range grade.Grades {
+		if pg.Group == code {
+			delete(grade.Grades, problem)
+		}
+	}
+	d.recordGradeChanges(asgn, uid, old, grade, editor)
+	d.Grades[asgn.Code][uid] = grade
+	return nil
+}
+
+// groupGrade returns the grade given to g (see SetGroupGrade)
+// for the given problem of the given assignment, if any.
+func (d *DB) groupGrade(acode string, g *Group, problem string) (ProblemGrade, bool) {
+	for _, uid := range g.MemberUIDs {
+		a, ok := d.Grades[acode][uid]
+		if !ok {
+			continue
+		}
+		if pg, ok := a.Grades[problem]; ok && pg.Group == g.Code {
+			return pg, true
+		}
+	}
+	return ProblemGrade{}, false
+}
+
+// StudentGroup returns the group of the given
+// assignment which the given student is in.
+func (d *DB) StudentGroup(acode, uid string) (*Group, bool) {
+	for _, g := range d.Groups[acode] {
+		for _, m := range g.MemberUIDs {
+			if m == uid {
+				return g, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// HandinOwners groups the given students by how they hand
+// in asgn. The keys of the returned map name handins (and
+// their directories), and the values are the UIDs (in the
+// order of uids) of the students who share each handin. For
+// group assignments, each group with a member in uids has
+// a handin named by the group's code; every other student
+// has a handin named by their UID. Since group codes must
+// start with a letter, the two can't be confused.
+func (d *DB) HandinOwners(asgn *Assignment, uids []string) map[string][]string {
+	owners := make(map[string][]string)
+	for _, uid := range uids {
+		name := uid
+		if asgn.Groups {
+			if g, ok := d.StudentGroup(asgn.Code, uid); ok {
+				name = g.Code
+			}
+		}
+		owners[name] = append(owners[name], uid)
+	}
+	return owners
+}
+
+// SetGroupGrade gives the grade g on the given problem of
+// asgn to each member of the given group, recording the
+// changes in d.GradeHistory. Each member's grade is set as
+// with SetGrade, except that members who have an individual
+// grade (that is, one not given to their group) for the
+// problem, one of its ancestors, or one of its descendants
+// keep it and are left alone; their UIDs are returned in
+// overrides. If setting any member's grade fails, none are
+// set.
+func (d *DB) SetGroupGrade(asgn *Assignment, code, problem string, g ProblemGrade, force bool, editor GradeEditor) (overrides []string, err error) {
+	group, ok := d.Groups[asgn.Code][code]
+	if !ok {
+		return nil, fmt.Errorf("no such group: %v", code)
+	}
+	g.Group = code
+
+	// compute every member's new grade before
+	// modifying any of them so that a failure
+	// leaves the database untouched
+	type change struct {
+		uid      string
+		old, new *AssignmentGrade
+	}
+	var changes []change
+	for _, uid := range group.MemberUIDs {
+		old := d.Grades[asgn.Code][uid]
+		var grade *AssignmentGrade
+		if old != nil {
+			if old.hasIndividualGrade(asgn, problem) {
+				overrides = append(overrides, uid)
+				continue
+			}
+			grade = old.Clone()
+		} else {
+			grade = NewAssignmentGrade()
+		}
+		if err := grade.SetProblemGrade(asgn, problem, g, force); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change{uid, old, grade})
+	}
+	for _, c := range changes {
+		d.recordGradeChanges(asgn, c.uid, c.old, c.new, editor)
+		d.Grades[asgn.Code][c.uid] = c.new
+	}
+	return overrides, nil
+}
+
+// hasIndividualGrade returns whether a has a grade not given
+// to a group (see SetGroupGrade) for the given problem of asgn,
+// one of its ancestors, or one of its descendants.
+func (a *AssignmentGrade) hasIndividualGrade(asgn *Assignment, problem string) bool {
+	individual := func(code string) bool {
+		g, ok := a.Grades[code]
+		return ok && g.Group == ""
+	}
+	path, _ := asgn.FindProblemPathByCode(problem)
+	for _, elem := range path {
+		if individual(elem) {
+			return true
+		}
+	}
+	prob, _ := asgn.FindProblemByCode(problem)
+	found := false
+	prob.TraversePreOrder(func(p Problem) { found = found || individual(p.Code) })
+	return found
+}
+
+// SetHandinGrade gives the grade g on the given problem of
+// asgn to the student with the given uid and to everyone who
+// shares their handin. If asgn is a group assignment, the
+// student is in a group, and the student does not have an
+// individual grade which would override the group's grade
+// (see SetGroupGrade), the grade is given to
+// the group as with SetGroupGrade, and overrides is as
+// returned by SetGroupGrade. Otherwise, it is given to the
+// student alone as with SetGrade. In either case, uids holds
+// the UIDs of the students whose grades may have changed.
+func (d *DB) SetHandinGrade(asgn *Assignment, uid, problem string, g ProblemGrade, force bool, editor GradeEditor) (uids, overrides []string, err error) {
+	group, ok := d.StudentGroup(asgn.Code, uid)
+	if ok && asgn.Groups {
+		a, ok := d.Grades[asgn.Code][uid]
+		if !ok || !a.hasIndividualGrade(asgn, problem) {
+			overrides, err = d.SetGroupGrade(asgn, group.Code, problem, g, force, editor)
+			if err != nil {
+				return nil, nil, err
+			}
+			return group.MemberUIDs, overrides, nil
+		}
+	}
+	if err := d.SetGrade(asgn, uid, problem, g, force, editor); err != nil {
+		return nil, nil, err
+	}
+	return []string{uid}, nil, nil
+}
+
+// DeleteGroupGrade deletes the grades given to the given
+// group (see SetGroupGrade) on the given problem of asgn,
+// leaving members' individual grades in place. It returns
+// true if any grades were deleted.
+func (d *DB) DeleteGroupGrade(asgn *Assignment, code, problem string, editor GradeEditor) bool {
+	group, ok := d.Groups[asgn.Code][code]
+	if !ok {
+		return false
+	}
+	deleted := false
+	for _, uid := range group.MemberUIDs {
+		if g, ok := d.Grades[asgn.Code][uid]; ok && g.Grades[problem].Group == code {
+			deleted = d.DeleteGrade(asgn, uid, problem, editor) || deleted
+		}
+	}
+	return deleted
+}
+
+// to make sorting Groups by code easier
+type groups []*Group
+
+func (g groups) Len() int           { return len(g) }
+func (g groups) Less(i, j int) bool { return g[i].Code < g[j].Code }
+func (g groups) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+
+// SortedGroups returns the groups of the
+// given assignment, sorted by code.
+func (d *DB) SortedGroups(acode string) []*Group {
+	var gs groups
+	for _, g := range d.Groups[acode] {
+		gs = append(gs, g)
+	}
+	sort.Sort(gs)
+	return gs
+}
@@ -0,0 +1,240 @@
+package kudos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestGroups(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	testutil.MustError(t, "assignment a is not a group assignment", d.CreateGroup(asgn, "team1"))
+
+	asgn.Groups = true
+	testutil.Must(t, d.CreateGroup(asgn, "team1"))
+	testutil.Must(t, d.CreateGroup(asgn, "team2"))
+	testutil.MustError(t, "group already exists: team1", d.CreateGroup(asgn, "team1"))
+	testutil.Must(t, d.AddGroupMember(asgn, "team1", "0", GradeEditor{}))
+	testutil.Must(t, d.AddGroupMember(asgn, "team1", "1", GradeEditor{}))
+	testutil.Must(t, d.AddGroupMember(asgn, "team2", "2", GradeEditor{}))
+	testutil.MustError(t, "student is already in group team1", d.AddGroupMember(asgn, "team2", "1", GradeEditor{}))
+	testutil.MustError(t, "no such group: team3", d.AddGroupMember(asgn, "team3", "3", GradeEditor{}))
+
+	if g, ok := d.StudentGroup(asgn.Code, "1"); !ok || g.Code != "team1" {
+		t.Errorf("unexpected group for student 1: %v, %v", g, ok)
+	}
+	if _, ok := d.StudentGroup(asgn.Code, "3"); ok {
+		t.Errorf("unexpected group for student 3")
+	}
+	owners := d.HandinOwners(asgn, []string{"0", "1", "3"})
+	expect := map[string][]string{"team1": {"0", "1"}, "3": {"3"}}
+	if !reflect.DeepEqual(owners, expect) {
+		t.Errorf("unexpected handin owners: got %v; want %v", owners, expect)
+	}
+	if p := d.PubStudent(&Course{}, "0"); !reflect.DeepEqual(p.Groups, map[string]string{asgn.Code: "team1"}) {
+		t.Errorf("unexpected published groups: %v", p.Groups)
+	}
+}
+
+func TestGroupGrades(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	asgn.Groups = true
+	testutil.Must(t, d.CreateGroup(asgn, "team"))
+	for _, uid := range []string{"0", "1", "2"} {
+		testutil.Must(t, d.AddGroupMember(asgn, "team", uid, GradeEditor{}))
+	}
+	editor := GradeEditor{UID: "100", Command: "kudos grade"}
+
+	// student 1 has an individual grade
+	// which overrides the group's grade
+	testutil.Must(t, d.SetGrade(asgn, "1", "prob1", ProblemGrade{Grade: dec(45)}, false, editor))
+	overrides, err := d.SetGroupGrade(asgn, "team", "prob1", ProblemGrade{Grade: dec(40)}, false, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(overrides, []string{"1"}) {
+		t.Errorf("unexpected overrides: %v", overrides)
+	}
+	check := func(uid, problem string, grade float64, group string) {
+		g, ok := d.Grades[asgn.Code][uid].Grades[problem]
+		if !ok || g.Grade != dec(grade) || g.Group != group {
+			t.Errorf("unexpected grade for student %v on %v: got %+v; want %v from group %q", uid, problem, g, grade, group)
+		}
+	}
+	check("0", "prob1", 40, "team")
+	check("1", "prob1", 45, "")
+	check("2", "prob1", 40, "team")
+
+	// regrading the group requires force
+	_, err = d.SetGroupGrade(asgn, "team", "prob1", ProblemGrade{Grade: dec(30)}, false, editor)
+	testutil.MustError(t, ErrGradeExists.Error(), err)
+	check("0", "prob1", 40, "team")
+	_, err = d.SetGroupGrade(asgn, "team", "prob1", ProblemGrade{Grade: dec(30)}, true, editor)
+	testutil.Must(t, err)
+	check("0", "prob1", 30, "team")
+	check("1", "prob1", 45, "")
+
+	// individual grades for a member on a problem's
+	// parent or subproblems also override the group's
+	testutil.Must(t, d.SetGrade(asgn, "2", "prob2", ProblemGrade{Grade: dec(45)}, false, editor))
+	overrides, err = d.SetGroupGrade(asgn, "team", "a", ProblemGrade{Grade: dec(20)}, false, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(overrides, []string{"2"}) {
+		t.Errorf("unexpected overrides: %v", overrides)
+	}
+	check("0", "a", 20, "team")
+	check("1", "a", 20, "team")
+	check("2", "prob2", 45, "")
+
+	// a failure for one member leaves
+	// every member's grades untouched
+	testutil.Must(t, d.SetGrade(asgn, "1", "b", ProblemGrade{Grade: dec(10)}, false, editor))
+	history := len(d.GradeHistory)
+	_, err = d.SetGroupGrade(asgn, "team", "prob2", ProblemGrade{Grade: dec(50)}, false, editor)
+	if _, ok := err.(*SubproblemGradedError); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, ok := d.Grades[asgn.Code]["0"].Grades["prob2"]; ok || len(d.GradeHistory) != history {
+		t.Errorf("unexpected grade changes after failure")
+	}
+
+	// forcing only replaces the grades of members
+	// without individual grades
+	overrides, err = d.SetGroupGrade(asgn, "team", "prob2", ProblemGrade{Grade: dec(50)}, true, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(overrides, []string{"1", "2"}) {
+		t.Errorf("unexpected overrides: %v", overrides)
+	}
+	check("0", "prob2", 50, "team")
+	check("1", "a", 20, "team")
+	check("1", "b", 10, "")
+	check("2", "prob2", 45, "")
+
+	if !d.DeleteGroupGrade(asgn, "team", "prob1", editor) {
+		t.Errorf("failed to delete group grade")
+	}
+	if _, ok := d.Grades[asgn.Code]["0"].Grades["prob1"]; ok {
+		t.Errorf("group grade not deleted")
+	}
+	check("1", "prob1", 45, "")
+	if d.DeleteGroupGrade(asgn, "team", "prob1", editor) {
+		t.Errorf("unexpected deletion of nonexistent group grade")
+	}
+}
+
+func TestGroupMembership(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	asgn.Groups = true
+	testutil.Must(t, d.CreateGroup(asgn, "team"))
+	editor := GradeEditor{UID: "100", Command: "kudos group"}
+	for _, uid := range []string{"0", "1"} {
+		testutil.Must(t, d.AddGroupMember(asgn, "team", uid, editor))
+	}
+	_, err := d.SetGroupGrade(asgn, "team", "prob1", ProblemGrade{Grade: dec(40)}, false, editor)
+	testutil.Must(t, err)
+	_, err = d.SetGroupGrade(asgn, "team", "a", ProblemGrade{Grade: dec(20)}, false, editor)
+	testutil.Must(t, err)
+	check := func(uid, problem string, grade float64, group string) {
+		g, ok := d.Grades[asgn.Code][uid].Grades[problem]
+		if !ok || g.Grade != dec(grade) || g.Group != group {
+			t.Errorf("unexpected grade for student %v on %v: got %+v; want %v from group %q", uid, problem, g, grade, group)
+		}
+	}
+
+	// new members are given the group's grades,
+	// except where they have individual grades
+	testutil.Must(t, d.AddGroupMember(asgn, "team", "2", editor))
+	check("2", "prob1", 40, "team")
+	check("2", "a", 20, "team")
+	testutil.Must(t, d.SetGrade(asgn, "3", "prob2", ProblemGrade{Grade: dec(45)}, false, editor))
+	history := len(d.GradeHistory)
+	testutil.Must(t, d.AddGroupMember(asgn, "team", "3", editor))
+	check("3", "prob1", 40, "team")
+	check("3", "prob2", 45, "")
+	if _, ok := d.Grades[asgn.Code]["3"].Grades["a"]; ok {
+		t.Errorf("group grade overrode individual grade")
+	}
+	if len(d.GradeHistory) != history+1 {
+		t.Errorf("unexpected grade history: %v changes", len(d.GradeHistory)-history)
+	}
+
+	// removed members lose the group's grades,
+	// but keep their individual grades
+	testutil.Must(t, d.RemoveGroupMember(asgn, "team", "3", editor))
+	if _, ok := d.Grades[asgn.Code]["3"].Grades["prob1"]; ok {
+		t.Errorf("group grade not deleted")
+	}
+	check("3", "prob2", 45, "")
+	if !reflect.DeepEqual(d.Groups[asgn.Code]["team"].MemberUIDs, []string{"0", "1", "2"}) {
+		t.Errorf("unexpected members: %v", d.Groups[asgn.Code]["team"].MemberUIDs)
+	}
+	testutil.MustError(t, "student is not in group team", d.RemoveGroupMember(asgn, "team", "3", editor))
+	testutil.MustError(t, "no such group: other", d.RemoveGroupMember(asgn, "other", "3", editor))
+	testutil.Must(t, d.RemoveGroupMember(asgn, "team", "0", editor))
+	check("1", "prob1", 40, "team")
+}
+
+func TestSetHandinGrade(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	asgn.Groups = true
+	testutil.Must(t, d.CreateGroup(asgn, "team"))
+	for _, uid := range []string{"0", "1", "2"} {
+		testutil.Must(t, d.AddGroupMember(asgn, "team", uid, GradeEditor{}))
+	}
+	editor := GradeEditor{UID: "100", Command: "kudos rubric ingest"}
+	check := func(uid string, grade float64, group string) {
+		g, ok := d.Grades[asgn.Code][uid].Grades["prob1"]
+		if !ok || g.Grade != dec(grade) || g.Group != group {
+			t.Errorf("unexpected grade for student %v: got %+v; want %v from group %q", uid, g, grade, group)
+		}
+	}
+
+	// a grade for a member goes to the whole group
+	// (as when ingesting a group's rubric or
+	// importing a member's row from a CSV file)
+	uids, overrides, err := d.SetHandinGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(40)}, false, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(uids, []string{"0", "1", "2"}) || len(overrides) != 0 {
+		t.Errorf("unexpected result: %v, %v", uids, overrides)
+	}
+	check("0", 40, "team")
+	check("1", 40, "team")
+	check("2", 40, "team")
+
+	// regrading a member regrades the group
+	// (as when resolving a regrade request)
+	_, _, err = d.SetHandinGrade(asgn, "1", "prob1", ProblemGrade{Grade: dec(30)}, false, editor)
+	testutil.MustError(t, ErrGradeExists.Error(), err)
+	uids, _, err = d.SetHandinGrade(asgn, "1", "prob1", ProblemGrade{Grade: dec(30)}, true, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(uids, []string{"0", "1", "2"}) {
+		t.Errorf("unexpected students: %v", uids)
+	}
+	check("0", 30, "team")
+	check("2", 30, "team")
+
+	// a member with an individual grade keeps it,
+	// and regrading them only changes their grade
+	testutil.Must(t, d.SetGrade(asgn, "2", "prob1", ProblemGrade{Grade: dec(45)}, true, editor))
+	uids, overrides, err = d.SetHandinGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(35)}, true, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(overrides, []string{"2"}) {
+		t.Errorf("unexpected overrides: %v", overrides)
+	}
+	check("0", 35, "team")
+	check("2", 45, "")
+	uids, _, err = d.SetHandinGrade(asgn, "2", "prob1", ProblemGrade{Grade: dec(50)}, true, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(uids, []string{"2"}) {
+		t.Errorf("unexpected students: %v", uids)
+	}
+	check("1", 35, "team")
+	check("2", 50, "")
+
+	// students outside of any group are graded alone
+	uids, _, err = d.SetHandinGrade(asgn, "3", "prob1", ProblemGrade{Grade: dec(20)}, false, editor)
+	testutil.Must(t, err)
+	if !reflect.DeepEqual(uids, []string{"3"}) {
+		t.Errorf("unexpected students: %v", uids)
+	}
+	check("3", 20, "")
+}
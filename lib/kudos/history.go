@@ -0,0 +1,164 @@
+package kudos
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// A GradeChange records a single change to a student's
+// grade on a single problem.
+type GradeChange struct {
+	Assignment string
+	Problem    string
+	StudentUID string
+	// Old is nil if the problem had no grade
+	// before the change; New is nil if the
+	// change deleted the grade
+	Old, New *ProblemGrade
+
+	EditorUID string
+	Time      time.Time
+	// the command which made the change
+	// (for example, "kudos grade")
+	Command string
+}
+
+// A GradeEditor identifies the user and command
+// responsible for changes to grades.
+type GradeEditor struct {
+	UID     string
+	Command string
+}
+
+// SetGrade sets the given student's grade on the given
+// problem of asgn according to the semantics of
+// AssignmentGrade.SetProblemGrade, and records each
+// problem whose grade is changed (including subproblem
+// grades which are deleted because force is true) in
+// d.GradeHistory. If an error is returned, d is left
+// unmodified.
+//
+// All changes to grades in d should be made through
+// SetGrade or DeleteGrade so that the history is complete.
+func (d *DB) SetGrade(asgn *Assignment, uid, problem string, g ProblemGrade, force bool, editor GradeEditor) error {
+	old := d.Grades[asgn.Code][uid]
+	var grade *AssignmentGrade
+	if old != nil {
+		grade = old.Clone()
+	} else {
+		grade = NewAssignmentGrade()
+	}
+	if err := grade.SetProblemGrade(asgn, problem, g, force); err != nil {
+		return err
+	}
+	d.recordGradeChanges(asgn, uid, old, grade, editor)
+	d.Grades[asgn.Code][uid] = grade
+	return nil
+}
+
+// DeleteGrade deletes the given student's grade on the
+// given problem of asgn, recording the change in
+// d.GradeHistory. It returns true if the grade was
+// deleted and false if there was no such grade.
+func (d *DB) DeleteGrade(asgn *Assignment, uid, problem string, editor GradeEditor) bool {
+	old, ok := d.Grades[asgn.Code][uid]
+	if !ok {
+		return false
+	}
+	if _, ok := old.Grades[problem]; !ok {
+		return false
+	}
+	g := old.Clone()
+	delete(g.Grades, problem)
+	d.recordGradeChanges(asgn, uid, old, g, editor)
+	d.Grades[asgn.Code][uid] = g
+	return true
+}
+
+// RoundGrades rounds every grade in d to the given number
+// of digits after the decimal point, recording each change
+// in d.GradeHistory. It is used to clean up grades stored
+// before grades were exact decimals (see Decimal). It returns
+// the number of grades which were changed.
+func (d *DB) RoundGrades(places int, editor GradeEditor) int {
+	// iterate in a consistent order so
+	// that the history is deterministic
+	var acodes []string
+	for code := range d.Grades {
+		acodes = append(acodes, code)
+	}
+	sort.Strings(acodes)
+	changed := 0
+	for _, acode := range acodes {
+		asgn := d.Assignments[acode]
+		var uids []string
+		for uid := range d.Grades[acode] {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+		for _, uid := range uids {
+			old := d.Grades[acode][uid]
+			g := old.Clone()
+			n := 0
+			for code, pg := range g.Grades {
+				if r := pg.Grade.Round(places); r != pg.Grade {
+					pg.Grade = r
+					g.Grades[code] = pg
+					n++
+				}
+			}
+			if n > 0 {
+				d.recordGradeChanges(asgn, uid, old, g, editor)
+				d.Grades[acode][uid] = g
+				changed += n
+			}
+		}
+	}
+	return changed
+}
+
+// GradeHistoryFor returns the changes in d.GradeHistory
+// to the given student's grades, in the order in which
+// they were made. If assignment is not the empty string,
+// only changes to that assignment are returned.
+func (d *DB) GradeHistoryFor(uid, assignment string) []*GradeChange {
+	var changes []*GradeChange
+	for _, c := range d.GradeHistory {
+		if c.StudentUID == uid && (assignment == "" || c.Assignment == assignment) {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// recordGradeChanges appends a GradeChange to d.GradeHistory
+// for each problem whose grade differs between old and new
+// (old may be nil), in pre-order.
+func (d *DB) recordGradeChanges(asgn *Assignment, uid string, old, new *AssignmentGrade, editor GradeEditor) {
+	now := time.Now()
+	asgn.TraverseProblemsPreOrder(func(p Problem) {
+		var before, after *ProblemGrade
+		if old != nil {
+			if g, ok := old.Grades[p.Code]; ok {
+				before = &g
+			}
+		}
+		if g, ok := new.Grades[p.Code]; ok {
+			after = &g
+		}
+		if reflect.DeepEqual(before, after) {
+			return
+		}
+		d.GradeHistory = append(d.GradeHistory, &GradeChange{
+			Assignment: asgn.Code,
+			Problem:    p.Code,
+			StudentUID: uid,
+			Old:        before,
+			New:        after,
+			EditorUID:  editor.UID,
+			Time:       now,
+			Command:    editor.Command,
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package kudos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestGradeHistory(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	editor := GradeEditor{UID: "100", Command: "kudos grade"}
+
+	testutil.Must(t, d.SetGrade(asgn, "0", "a", ProblemGrade{Grade: dec(10)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "0", "b", ProblemGrade{Grade: dec(20)}, false, editor))
+	// fails, and should not be recorded
+	err := d.SetGrade(asgn, "0", "prob2", ProblemGrade{Grade: dec(40)}, false, editor)
+	testutil.MustError(t, "grade already assigned to subproblem a", err)
+	// overwrites both subproblems
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob2", ProblemGrade{Grade: dec(40), Comment: "regraded"}, true, editor))
+	if !d.DeleteGrade(asgn, "0", "prob2", editor) {
+		t.Errorf("unexpected failure to delete grade")
+	}
+	if d.DeleteGrade(asgn, "0", "prob2", editor) {
+		t.Errorf("unexpected deletion of nonexistent grade")
+	}
+	testutil.Must(t, d.SetGrade(asgn, "1", "prob1", ProblemGrade{Grade: dec(50)}, false, editor))
+
+	type change struct {
+		problem  string
+		old, new *ProblemGrade
+	}
+	expect := []change{
+		{"a", nil, &ProblemGrade{Grade: dec(10)}},
+		{"b", nil, &ProblemGrade{Grade: dec(20)}},
+		{"prob2", nil, &ProblemGrade{Grade: dec(40), Comment: "regraded"}},
+		{"a", &ProblemGrade{Grade: dec(10)}, nil},
+		{"b", &ProblemGrade{Grade: dec(20)}, nil},
+		{"prob2", &ProblemGrade{Grade: dec(40), Comment: "regraded"}, nil},
+	}
+	var got []change
+	for _, c := range d.GradeHistoryFor("0", "") {
+		if c.Assignment != asgn.Code || c.StudentUID != "0" || c.EditorUID != "100" ||
+			c.Command != "kudos grade" || c.Time.IsZero() {
+			t.Errorf("unexpected grade change: %+v", c)
+		}
+		got = append(got, change{c.Problem, c.Old, c.New})
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected history: got %v; want %v", got, expect)
+	}
+	if len(d.GradeHistory) != len(expect)+1 {
+		t.Errorf("unexpected history length: got %v; want %v", len(d.GradeHistory), len(expect)+1)
+	}
+}
+
+func TestRoundGrades(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	editor := GradeEditor{UID: "100", Command: "kudos migrate"}
+
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(49.999999)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "0", "a", ProblemGrade{Grade: dec(12.5)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "1", "prob1", ProblemGrade{Grade: dec(40.126)}, false, editor))
+	start := len(d.GradeHistory)
+	if n := d.RoundGrades(2, editor); n != 2 {
+		t.Errorf("unexpected number of rounded grades: got %v; want 2", n)
+	}
+	if g := d.Grades[asgn.Code]["0"].Grades; g["prob1"].Grade != dec(50) || g["a"].Grade != dec(12.5) {
+		t.Errorf("unexpected grades: got %v", g)
+	}
+	if g := d.Grades[asgn.Code]["1"].Grades; g["prob1"].Grade != dec(40.13) {
+		t.Errorf("unexpected grades: got %v", g)
+	}
+	changes := d.GradeHistory[start:]
+	if len(changes) != 2 || changes[0].StudentUID != "0" || changes[1].StudentUID != "1" ||
+		changes[0].Old.Grade != dec(49.999999) || changes[0].New.Grade != dec(50) {
+		t.Errorf("unexpected history: %v", changes)
+	}
+}
@@ -0,0 +1,199 @@
+package kudos
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// A LatePolicy describes how late handins are penalized.
+type LatePolicy struct {
+	// Handins which are no more than GracePeriod
+	// late are not penalized.
+	GracePeriod time.Duration
+	// Penalty is the percentage of points deducted
+	// for each Unit (or fraction thereof) that a
+	// handin is late.
+	Penalty float64
+	Unit    time.Duration
+	// Handins which are more than MaxLateness late
+	// receive no points. If MaxLateness is 0, there
+	// is no maximum.
+	MaxLateness time.Duration
+}
+
+// PenaltyFor returns the fraction (in the range [0, 1])
+// of points deducted from a handin which is late by
+// the given amount.
+func (l *LatePolicy) PenaltyFor(late time.Duration) float64 {
+	switch {
+	case late <= 0 || late <= l.GracePeriod:
+		return 0
+	case l.MaxLateness > 0 && late > l.MaxLateness:
+		return 1
+	}
+	units := math.Ceil(float64(late) / float64(l.Unit))
+	return math.Min(1, units*l.Penalty/100)
+}
+
+// HandinLateness describes how late a student's
+// handin was.
+type HandinLateness struct {
+	// Handin is the code of the handin; it is the
+	// empty string if the assignment only has one
+	// handin.
+	Handin string
+	Due    time.Time
+	// HandedIn is the zero value if no handin
+	// has been recorded for the student.
+	HandedIn time.Time
+	// LateDays is the number of late days
+	// spent on this handin; Late has already
+	// been reduced accordingly
+	LateDays int
+	Late     time.Duration
+	// Penalty is the fraction (in the range [0, 1])
+	// of points deducted from the problems included
+	// in this handin.
+	Penalty float64
+}
+
+// Lateness computes the lateness of each of the given
+// student's handins for asgn according to policy, in
+// the same order as asgn.Handins, taking into account
+// any extensions the student has been granted and any
+// late days recorded in d.LateDays. If policy
+// is nil, no penalties are applied. Handins which have
+// not been recorded in d.Handins are considered to be
+// on time.
+func (d *DB) Lateness(asgn *Assignment, uid string, policy *LatePolicy) []HandinLateness {
+	var l []HandinLateness
+	for _, h := range asgn.Handins {
+		hl := HandinLateness{
+			Handin:   h.Code,
+			Due:      d.DueDate(asgn, h, uid),
+			LateDays: d.LateDays[asgn.Code][h.Code][uid],
+		}
+		if t, ok := d.Handins[asgn.Code][h.Code][uid]; ok {
+			hl.HandedIn = t
+			due := hl.Due.Add(time.Duration(hl.LateDays) * LateDay)
+			if t.After(due) {
+				hl.Late = t.Sub(due)
+			}
+		}
+		if policy != nil {
+			hl.Penalty = policy.PenaltyFor(hl.Late)
+		}
+		l = append(l, hl)
+	}
+	return l
+}
+
+// EffectiveLatePolicy returns the late policy which
+// applies to a: a's own policy if it has one, and
+// c's policy otherwise. If neither has a policy,
+// EffectiveLatePolicy returns nil.
+func (a *Assignment) EffectiveLatePolicy(c *Course) *LatePolicy {
+	if a.LatePolicy != nil {
+		return a.LatePolicy
+	}
+	return c.LatePolicy
+}
+
+// AdjustedTotal is like Total, except that the points
+// for each top-level problem are reduced by the penalty
+// of the handin which includes that problem. lateness
+// must be the result of calling DB.Lateness for the same
+// assignment and student. Extra credit is capped after
+// the penalties are applied.
+func (a *AssignmentGrade) AdjustedTotal(asgn *Assignment, lateness []HandinLateness) (grade Decimal, ok bool) {
+	penalties := make(map[string]float64)
+	for i, h := range asgn.Handins {
+		for _, p := range h.Problems {
+			penalties[p] = lateness[i].Penalty
+		}
+	}
+	var total Decimal
+	for _, p := range asgn.Problems {
+		g, ok := a.ProblemTotal(asgn, p.Code)
+		if !ok {
+			if p.ExtraCredit {
+				continue
+			}
+			return Decimal{}, false
+		}
+		total = total.Add(g.MulFloat(1 - penalties[p.Code]))
+	}
+	return asgn.capTotal(total, a.OutOf(asgn)), true
+}
+
+// NOTE: All of the convenience methods to retrieve
+// fields of the various parseable* types will either:
+//   - check to see if the field is set before dereferencing
+//     the pointer if the field is optional
+//   - assume that the field has been set and dereference
+//     the pointer if the field is mandatory
+//
+// These methods shouldn't be called except for during
+// validation (in a manner that makes sure this is safe)
+// or after validation (at which point these invariants
+// are guaranteed to hold)
+
+type parseableLatePolicy struct {
+	GracePeriod *duration `json:"grace_period"`
+	Penalty     *float64  `json:"penalty"`
+	Per         *string   `json:"per"`
+	MaxLateness *duration `json:"max_lateness"`
+}
+
+// Convert p to an exported LatePolicy type.
+// This function performs no validation,
+// so you must do validation independent
+// of this function.
+func (p *parseableLatePolicy) toLatePolicy() *LatePolicy {
+	return &LatePolicy{
+		GracePeriod: p.gracePeriod(),
+		Penalty:     *p.Penalty,
+		Unit:        latePolicyUnits[*p.Per],
+		MaxLateness: p.maxLateness(),
+	}
+}
+
+func (p *parseableLatePolicy) gracePeriod() (d time.Duration) {
+	if p.GracePeriod != nil {
+		d = time.Duration(*p.GracePeriod)
+	}
+	return
+}
+
+func (p *parseableLatePolicy) maxLateness() (d time.Duration) {
+	if p.MaxLateness != nil {
+		d = time.Duration(*p.MaxLateness)
+	}
+	return
+}
+
+var latePolicyUnits = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+func validateLatePolicy(p *parseableLatePolicy) error {
+	switch {
+	case p.Penalty == nil:
+		return fmt.Errorf("must have penalty")
+	case *p.Penalty < 0:
+		return fmt.Errorf("penalty must be non-negative")
+	case p.Per == nil:
+		return fmt.Errorf("must specify penalty period (per)")
+	case latePolicyUnits[*p.Per] == 0:
+		return fmt.Errorf("bad penalty period %q: must be \"hour\" or \"day\"", *p.Per)
+	case p.gracePeriod() < 0:
+		return fmt.Errorf("grace period must be non-negative")
+	case p.maxLateness() < 0:
+		return fmt.Errorf("maximum lateness must be non-negative")
+	case p.MaxLateness != nil && p.maxLateness() < p.gracePeriod():
+		return fmt.Errorf("maximum lateness must not be less than grace period")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package kudos
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+var penaltyForTests = []struct {
+	late    time.Duration
+	penalty float64
+}{
+	{-time.Hour, 0},
+	{0, 0},
+	{15 * time.Minute, 0},
+	{16 * time.Minute, 0.1},
+	{24 * time.Hour, 0.1},
+	{25 * time.Hour, 0.2},
+	{72 * time.Hour, 0.3},
+	{73 * time.Hour, 1},
+}
+
+func TestPenaltyFor(t *testing.T) {
+	l := &LatePolicy{
+		GracePeriod: 15 * time.Minute,
+		Penalty:     10,
+		Unit:        24 * time.Hour,
+		MaxLateness: 72 * time.Hour,
+	}
+	for _, test := range penaltyForTests {
+		p := l.PenaltyFor(test.late)
+		if p < test.penalty-1e-9 || p > test.penalty+1e-9 {
+			t.Errorf("unexpected penalty for %v: got %v; want %v", test.late, p, test.penalty)
+		}
+	}
+}
+
+func TestAdjustedTotal(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+	testutil.Must(t, err)
+	policy := &LatePolicy{Penalty: 10, Unit: time.Hour}
+
+	d := NewDB()
+	d.AddAssignment(asgn)
+	first, _ := asgn.FindHandinByCode("first")
+	second, _ := asgn.FindHandinByCode("second")
+	d.Handins[asgn.Code]["first"]["0"] = first.Due.Add(-time.Hour)
+	d.Handins[asgn.Code]["second"]["0"] = second.Due.Add(90 * time.Minute)
+
+	g := NewAssignmentGrade()
+	g.Grades["prob1"] = ProblemGrade{Grade: dec(50)}
+	g.Grades["prob2"] = ProblemGrade{Grade: dec(50)}
+
+	l := d.Lateness(asgn, "0", policy)
+	if len(l) != 2 || l[0].Penalty != 0 || l[1].Late != 90*time.Minute {
+		t.Fatalf("unexpected lateness: %v", l)
+	}
+	total, ok := g.AdjustedTotal(asgn, l)
+	if !ok || total != dec(90) {
+		t.Errorf("unexpected adjusted total: got (%v, %v); want (90, true)", total, ok)
+	}
+}
@@ -0,0 +1,262 @@
+package kudos
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LateDay is the amount of lateness covered
+// by a single late day.
+const LateDay = 24 * time.Hour
+
+// SetLateDayChoice records that the given student
+// has chosen to spend the given number of late days
+// on the given handin (as in DB.Handins, handin
+// should be the empty string if the assignment has
+// only one handin). This choice takes precedence
+// over the greedy allocation of late days performed
+// by UpdateLateDays.
+func (d *DB) SetLateDayChoice(assignment, handin, uid string, days int) {
+	if d.LateDayChoices[assignment] == nil {
+		d.LateDayChoices[assignment] = make(map[string]map[string]int)
+	}
+	if d.LateDayChoices[assignment][handin] == nil {
+		d.LateDayChoices[assignment][handin] = make(map[string]int)
+	}
+	d.LateDayChoices[assignment][handin][uid] = days
+}
+
+// DeleteLateDayChoice deletes the given student's
+// choice for the given handin. It returns true if
+// the choice was deleted and false if there was no
+// such choice.
+func (d *DB) DeleteLateDayChoice(assignment, handin, uid string) bool {
+	if _, ok := d.LateDayChoices[assignment][handin][uid]; !ok {
+		return false
+	}
+	delete(d.LateDayChoices[assignment][handin], uid)
+	return true
+}
+
+// LateDayChoiceFile is the format of the files in
+// which students submit their own late day choices.
+type LateDayChoiceFile struct {
+	Assignment string
+	// as in DB.Handins, the empty string if
+	// the assignment has only one handin
+	Handin string
+	// if Clear is true, the student's choice for
+	// the handin is cleared, and Days is ignored
+	Days  int
+	Clear bool
+	Time  time.Time
+}
+
+// WriteLateDayChoice writes f to the given student's late
+// days directory so that it can later be imported by
+// ReadLateDayChoices. It is intended to be called by
+// students.
+func (c *Context) WriteLateDayChoice(uid string, f *LateDayChoiceFile) error {
+	// start with the time so that files are
+	// read in the order in which they were
+	// written (see ReadLateDayChoices)
+	name := fmt.Sprintf("%v.%v", f.Time.UnixNano(), f.Assignment)
+	if f.Handin != "" {
+		name += "." + f.Handin
+	}
+	return writeDropFile(c.UserLateDaysDir(uid), name, "choice*.tmp", f)
+}
+
+// ReadLateDayChoices reads all of the late day choices in
+// the given student's late days directory, in the order in
+// which they were written (so that, when several choices
+// for the same handin are applied in order, the last one
+// wins). The returned paths are the paths of the files from
+// which each of the choices was read. If the student has
+// no late days directory, no choices are returned. Since
+// students can write arbitrary files to their late days
+// directories, files which cannot be read or parsed are
+// skipped with a warning rather than causing an error.
+func (c *Context) ReadLateDayChoices(uid string) (paths []string, choices []*LateDayChoiceFile, err error) {
+	paths, err = c.readDropFiles(c.UserLateDaysDir(uid), func(buf []byte) error {
+		var f LateDayChoiceFile
+		if err := json.Unmarshal(buf, &f); err != nil {
+			return err
+		}
+		choices = append(choices, &f)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return paths, choices, nil
+}
+
+// ApplyLateDayChoiceFile records the choice in f, which was
+// submitted by the student with the given uid, as with
+// SetLateDayChoice (or, if f.Clear is true, as with
+// DeleteLateDayChoice). Since f was written by a student,
+// it is validated first: it is an error if it names an
+// assignment or handin which does not exist or chooses
+// a negative number of late days. UpdateLateDays must be
+// called afterwards for the choice to take effect.
+func (d *DB) ApplyLateDayChoiceFile(uid string, f *LateDayChoiceFile) error {
+	asgn, ok := d.Assignments[f.Assignment]
+	if !ok {
+		return fmt.Errorf("no such assignment: %v", f.Assignment)
+	}
+	switch {
+	case f.Handin == "" && len(asgn.Handins) > 1:
+		return fmt.Errorf("assignment %v has multiple handins, but none was given", asgn.Code)
+	case f.Handin != "" && len(asgn.Handins) == 1:
+		return fmt.Errorf("assignment %v has one handin, but handin %q was given", asgn.Code, f.Handin)
+	case f.Handin != "":
+		if ValidateCode(f.Handin) != nil {
+			return fmt.Errorf("bad handin code: %q", f.Handin)
+		}
+		if _, ok := asgn.FindHandinByCode(f.Handin); !ok {
+			return fmt.Errorf("no such handin: %v", f.Handin)
+		}
+	}
+	if f.Clear {
+		d.DeleteLateDayChoice(asgn.Code, f.Handin, uid)
+		return nil
+	}
+	if f.Days < 0 {
+		return fmt.Errorf("negative number of late days: %v", f.Days)
+	}
+	d.SetLateDayChoice(asgn.Code, f.Handin, uid, f.Days)
+	return nil
+}
+
+// LateDaysUsed returns the total number of late days
+// recorded in the ledger for the given student.
+func (d *DB) LateDaysUsed(uid string) int {
+	used := 0
+	for _, handins := range d.LateDays {
+		for _, students := range handins {
+			used += students[uid]
+		}
+	}
+	return used
+}
+
+// UpdateLateDays recomputes the late day ledger
+// (d.LateDays) for the given students from their
+// handin times, due dates (including extensions),
+// and late day choices, with a budget of c.LateDays
+// late days per student.
+//
+// Handins are considered in order of their due dates.
+// Late days are first spent on handins for which the
+// student has made a choice (but never more than are
+// needed to make the handin on time, and never more
+// than the budget allows), and then the remaining
+// days are spent greedily on the rest of the late
+// handins. The greedy pass only spends days on a
+// handin if doing so lowers its penalty under the
+// applicable late policy, and then only as many as
+// are needed to reach the lowest penalty possible
+// with the days remaining. A handin whose lateness
+// would not be penalized (for example, because it
+// falls within the grace period) never uses late
+// days.
+func (d *DB) UpdateLateDays(c *Course, uids ...string) {
+	var handins handinRefs
+	for _, a := range d.Assignments {
+		for _, h := range a.Handins {
+			handins = append(handins, handinRef{a, h})
+		}
+	}
+	sort.Sort(handins)
+
+	for _, uid := range uids {
+		remaining := c.LateDays
+		spend := func(ref handinRef, days int) {
+			acode, hcode := ref.asgn.Code, ref.handin.Code
+			if d.LateDays[acode] == nil {
+				d.LateDays[acode] = make(map[string]map[string]int)
+			}
+			if d.LateDays[acode][hcode] == nil {
+				d.LateDays[acode][hcode] = make(map[string]int)
+			}
+			if days == 0 {
+				delete(d.LateDays[acode][hcode], uid)
+			} else {
+				d.LateDays[acode][hcode][uid] = days
+			}
+			remaining -= days
+		}
+		lateness := func(ref handinRef) time.Duration {
+			t, ok := d.Handins[ref.asgn.Code][ref.handin.Code][uid]
+			if !ok {
+				return 0
+			}
+			return t.Sub(d.DueDate(ref.asgn, ref.handin, uid))
+		}
+		needed := func(ref handinRef) int {
+			late := lateness(ref)
+			policy := ref.asgn.EffectiveLatePolicy(c)
+			if late <= 0 || (policy != nil && policy.PenaltyFor(late) == 0) {
+				return 0
+			}
+			return int((late + LateDay - 1) / LateDay)
+		}
+		min := func(a, b int) int {
+			if a < b {
+				return a
+			}
+			return b
+		}
+
+		var rest []handinRef
+		for _, ref := range handins {
+			choice, ok := d.LateDayChoices[ref.asgn.Code][ref.handin.Code][uid]
+			if !ok {
+				rest = append(rest, ref)
+				continue
+			}
+			spend(ref, min(choice, min(needed(ref), remaining)))
+		}
+		for _, ref := range rest {
+			// of the numbers of days which give the
+			// lowest penalty, spend the fewest
+			days := 0
+			if policy := ref.asgn.EffectiveLatePolicy(c); policy != nil {
+				late := lateness(ref)
+				lowest := policy.PenaltyFor(late)
+				for i := 1; i <= min(needed(ref), remaining); i++ {
+					if p := policy.PenaltyFor(late - time.Duration(i)*LateDay); p < lowest {
+						days, lowest = i, p
+					}
+				}
+			}
+			spend(ref, days)
+		}
+	}
+}
+
+type handinRef struct {
+	asgn   *Assignment
+	handin Handin
+}
+
+// implements the sort.Interface interface;
+// sorting is by due date, then by assignment
+// code, then by handin code
+type handinRefs []handinRef
+
+func (h handinRefs) Len() int      { return len(h) }
+func (h handinRefs) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h handinRefs) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	switch {
+	case !a.handin.Due.Equal(b.handin.Due):
+		return a.handin.Due.Before(b.handin.Due)
+	case a.asgn.Code != b.asgn.Code:
+		return a.asgn.Code < b.asgn.Code
+	}
+	return a.handin.Code < b.handin.Code
+}
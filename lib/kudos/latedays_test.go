@@ -0,0 +1,128 @@
+package kudos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshlf/kudos/lib/log"
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestUpdateLateDays(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+	testutil.Must(t, err)
+	first, _ := asgn.FindHandinByCode("first")
+	second, _ := asgn.FindHandinByCode("second")
+	c := &Course{LateDays: 3, LatePolicy: &LatePolicy{GracePeriod: time.Hour, Penalty: 10, Unit: time.Hour}}
+
+	d := NewDB()
+	d.AddAssignment(asgn)
+	// student 0 is 2 days late on both handins;
+	// greedily, the first handin gets 2 days, and
+	// the second gets none, since the last day
+	// would not lower its penalty
+	d.Handins[asgn.Code]["first"]["0"] = first.Due.Add(36 * time.Hour)
+	d.Handins[asgn.Code]["second"]["0"] = second.Due.Add(36 * time.Hour)
+	// student 1 is within the grace period on the
+	// first handin, and has chosen to spend no days
+	// on the second handin
+	d.Handins[asgn.Code]["first"]["1"] = first.Due.Add(time.Minute)
+	d.Handins[asgn.Code]["second"]["1"] = second.Due.Add(time.Hour * 2)
+	d.SetLateDayChoice(asgn.Code, "second", "1", 0)
+	// student 2 has an extension, and has chosen
+	// to spend days on the second handin
+	d.GrantExtension(asgn.Code, "first", "2", &Extension{Due: first.Due.Add(LateDay)})
+	d.Handins[asgn.Code]["first"]["2"] = first.Due.Add(36 * time.Hour)
+	d.Handins[asgn.Code]["second"]["2"] = second.Due.Add(60 * time.Hour)
+	d.SetLateDayChoice(asgn.Code, "second", "2", 5)
+
+	d.UpdateLateDays(c, "0", "1", "2")
+	expect := map[string]map[string]map[string]int{
+		asgn.Code: {
+			"first":  {"0": 2},
+			"second": {"2": 3},
+		},
+	}
+	if !reflect.DeepEqual(d.LateDays, expect) {
+		t.Errorf("unexpected late days: got %v; want %v", d.LateDays, expect)
+	}
+
+	l := d.Lateness(asgn, "0", c.LatePolicy)
+	if l[0].Late != 0 || l[1].Late != 36*time.Hour {
+		t.Errorf("unexpected lateness: %v", l)
+	}
+}
+
+func TestLateDayChoiceFiles(t *testing.T) {
+	dir := testutil.MustTempDir(t, "", "kudos")
+	defer os.RemoveAll(dir)
+
+	c := &Context{
+		GlobalConfig: &GlobalConfig{CoursePathPrefix: dir},
+		CourseCode:   "course",
+		Logger:       log.NewLogger(),
+	}
+	testutil.Must(t, os.MkdirAll(c.UserLateDaysDir("0"), 0700))
+
+	paths, choices, err := c.ReadLateDayChoices("1")
+	if err != nil || len(paths) != 0 || len(choices) != 0 {
+		t.Errorf("unexpected result reading nonexistent directory: %v %v %v", paths, choices, err)
+	}
+
+	testutil.Must(t, ioutil.WriteFile(filepath.Join(c.UserLateDaysDir("0"), "choice123.tmp"), []byte("{"), 0600))
+	testutil.Must(t, ioutil.WriteFile(filepath.Join(c.UserLateDaysDir("0"), "junk"), []byte("junk"), 0600))
+
+	// choices are read in the order in which
+	// they were made, regardless of the order
+	// in which their files are written
+	later := &LateDayChoiceFile{"a", "first", 0, true, time.Date(2015, 8, 2, 0, 0, 0, 0, time.UTC)}
+	earlier := &LateDayChoiceFile{"a", "first", 2, false, time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)}
+	testutil.Must(t, c.WriteLateDayChoice("0", later))
+	testutil.Must(t, c.WriteLateDayChoice("0", earlier))
+	paths, choices, err = c.ReadLateDayChoices("0")
+	testutil.Must(t, err)
+	expect := []*LateDayChoiceFile{earlier, later}
+	if len(paths) != 2 || !reflect.DeepEqual(choices, expect) {
+		t.Errorf("unexpected late day choices: got %v; want %v", choices, expect)
+	}
+}
+
+var applyLateDayChoiceFileTests = []struct {
+	f   LateDayChoiceFile
+	err string
+}{
+	{LateDayChoiceFile{Assignment: "a", Handin: "first", Days: 2}, ""},
+	{LateDayChoiceFile{Assignment: "b", Handin: "first", Days: 2}, "no such assignment: b"},
+	{LateDayChoiceFile{Assignment: "a", Days: 2}, "assignment a has multiple handins, but none was given"},
+	{LateDayChoiceFile{Assignment: "a", Handin: "third", Days: 2}, "no such handin: third"},
+	{LateDayChoiceFile{Assignment: "a", Handin: "-", Days: 2}, `bad handin code: "-"`},
+	{LateDayChoiceFile{Assignment: "a", Handin: "second", Days: -1}, "negative number of late days: -1"},
+}
+
+func TestApplyLateDayChoiceFile(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	for i, test := range applyLateDayChoiceFileTests {
+		err := d.ApplyLateDayChoiceFile("0", &test.f)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+	}
+	expect := map[string]map[string]map[string]int{asgn.Code: {"first": {"0": 2}}}
+	if !reflect.DeepEqual(d.LateDayChoices, expect) {
+		t.Errorf("unexpected late day choices: got %v; want %v", d.LateDayChoices, expect)
+	}
+
+	testutil.Must(t, d.ApplyLateDayChoiceFile("0", &LateDayChoiceFile{Assignment: "a", Handin: "first", Clear: true}))
+	if _, ok := d.LateDayChoices[asgn.Code]["first"]["0"]; ok {
+		t.Errorf("late day choice not cleared")
+	}
+}
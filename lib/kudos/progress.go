@@ -0,0 +1,85 @@
+package kudos
+
+// An UngradedProblem is a leaf problem which
+// has not been graded for a particular student.
+type UngradedProblem struct {
+	Problem    string
+	StudentUID string
+	// empty if no grader has been assigned
+	GraderUID string
+}
+
+// GradingProgress computes how much of asgn has been
+// graded for the given students. Only leaf problems are
+// considered; a leaf problem is graded if it or one of
+// its ancestors has a grade (see AssignmentGrade). Excused
+// problems are left out. total is the number of
+// (student, leaf problem) pairs, graded is the number of
+// those which have been graded, and ungraded lists the
+// rest, ordered by problem (in pre-order) and then by
+// student (in the order of uids).
+func (d *DB) GradingProgress(asgn *Assignment, uids []string) (graded, total int, ungraded []UngradedProblem) {
+	asgn.TraverseProblemsPreOrder(func(p Problem) {
+		if len(p.Subproblems) > 0 {
+			return
+		}
+		path, _ := asgn.FindProblemPathByCode(p.Code)
+		path = append(path, p.Code)
+		for _, uid := range uids {
+			covered := false
+			if g, ok := d.Grades[asgn.Code][uid]; ok {
+				if _, ok := g.Excusal(asgn, p.Code); ok {
+					continue
+				}
+				for _, code := range path {
+					if _, ok := g.Grades[code]; ok {
+						covered = true
+					}
+				}
+			}
+			total++
+			if covered {
+				graded++
+				continue
+			}
+			grader, _ := d.AssignedGrader(asgn, uid, p.Code)
+			ungraded = append(ungraded, UngradedProblem{p.Code, uid, grader})
+		}
+	})
+	return graded, total, ungraded
+}
+
+// AssignedGrader returns the grader most recently assigned
+// (see DB.GraderAssignments) to grade the given problem of
+// asgn for the given student. A grader assigned to one of
+// the problem's ancestors is also considered assigned to
+// the problem itself.
+func (d *DB) AssignedGrader(asgn *Assignment, uid, problem string) (grader string, ok bool) {
+	path, _ := asgn.FindProblemPathByCode(problem)
+	path = append(path, problem)
+	gas := d.GraderAssignments[asgn.Code]
+	for i := len(gas) - 1; i >= 0; i-- {
+		for _, p := range gas[i].Problems {
+			for _, code := range path {
+				if p != code {
+					continue
+				}
+				if g, ok := gas[i].Graders[uid]; ok {
+					return g, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// HandedIn returns whether the given student
+// has submitted any of asgn's handins.
+func (d *DB) HandedIn(asgn *Assignment, uid string) bool {
+	for _, students := range d.Handins[asgn.Code] {
+		if _, ok := students[uid]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package kudos
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestGradingProgress(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	editor := GradeEditor{UID: "100"}
+	// student 0's grade on prob2 covers both
+	// of its subproblems
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(40)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob2", ProblemGrade{Grade: dec(45)}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "1", "a", ProblemGrade{Grade: dec(20)}, false, editor))
+
+	d.AddGraderAssignment(asgn.Code, &GraderAssignment{
+		Problems: []string{"prob1", "prob2"},
+		Graders:  map[string]string{"0": "100", "1": "100", "2": "101"},
+	})
+	// a later assignment of a subproblem
+	// overrides the earlier one
+	d.AddGraderAssignment(asgn.Code, &GraderAssignment{
+		Problems: []string{"b"},
+		Graders:  map[string]string{"1": "102"},
+	})
+
+	graded, total, ungraded := d.GradingProgress(asgn, []string{"0", "1", "2", "3"})
+	if graded != 4 || total != 12 {
+		t.Errorf("unexpected progress: got %v/%v; want 4/12", graded, total)
+	}
+	expect := []UngradedProblem{
+		{"prob1", "1", "100"},
+		{"prob1", "2", "101"},
+		{"prob1", "3", ""},
+		{"a", "2", "101"},
+		{"a", "3", ""},
+		{"b", "1", "102"},
+		{"b", "2", "101"},
+		{"b", "3", ""},
+	}
+	if !reflect.DeepEqual(ungraded, expect) {
+		t.Errorf("unexpected ungraded problems: got %v; want %v", ungraded, expect)
+	}
+
+	if d.HandedIn(asgn, "0") {
+		t.Errorf("unexpected handin")
+	}
+	d.Handins[asgn.Code]["second"]["0"] = time.Now()
+	if !d.HandedIn(asgn, "0") {
+		t.Errorf("missing handin")
+	}
+}
@@ -1,6 +1,15 @@
 package kudos
 
-import "time"
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	acl "github.com/joshlf/go-acl"
+	"github.com/joshlf/kudos/lib/config"
+	"github.com/joshlf/kudos/lib/perm"
+)
 
 type PubDB struct {
 	Assignments map[string]*PubAssignment // keys are assignment codes
@@ -19,9 +28,184 @@ type PubAssignment struct {
 	Name string
 
 	Handins []PubHandin
+
+	// the zero value if the assignment's
+	// grades have not been released
+	Released time.Time
 }
 
 type PubHandin struct {
 	Code string
 	Due  time.Time
 }
+
+// A PubStudent holds the information published
+// to a single student. Unlike the PubDB, which
+// is world-readable, each PubStudent is stored
+// in its own file which can only be read by
+// the student it describes.
+type PubStudent struct {
+	UID string
+	// keys are assignment codes; value's keys
+	// are handin codes (as in DB.Handins)
+	Extensions map[string]map[string]time.Time
+
+	LateDayBudget int
+	// keys are as in Extensions; values are
+	// the number of late days spent
+	LateDays map[string]map[string]int
+
+	// keys are assignment codes; only assignments
+	// whose grades have been released are included
+	Grades map[string]*PubGrade
+
+	// the student's regrade requests, in
+	// the same order as in DB.Regrades
+	Regrades []*RegradeRequest
+
+	// keys are assignment codes; values are the
+	// codes of the groups the student is in (and
+	// thus the names of their handins)
+	Groups map[string]string
+}
+
+// PubStudent computes the information that should
+// be published to the student with the given UID
+// in the course c.
+func (d *DB) PubStudent(c *Course, uid string) *PubStudent {
+	p := &PubStudent{
+		UID:           uid,
+		Extensions:    make(map[string]map[string]time.Time),
+		LateDayBudget: c.LateDays,
+		LateDays:      make(map[string]map[string]int),
+		Grades:        make(map[string]*PubGrade),
+		Groups:        make(map[string]string),
+	}
+	for acode, handins := range d.Extensions {
+		for hcode, exts := range handins {
+			if e, ok := exts[uid]; ok {
+				if p.Extensions[acode] == nil {
+					p.Extensions[acode] = make(map[string]time.Time)
+				}
+				p.Extensions[acode][hcode] = e.Due
+			}
+		}
+	}
+	for acode, handins := range d.LateDays {
+		for hcode, students := range handins {
+			if n, ok := students[uid]; ok {
+				if p.LateDays[acode] == nil {
+					p.LateDays[acode] = make(map[string]int)
+				}
+				p.LateDays[acode][hcode] = n
+			}
+		}
+	}
+	for _, r := range d.Regrades {
+		if r.StudentUID == uid {
+			p.Regrades = append(p.Regrades, r)
+		}
+	}
+	for acode := range d.Groups {
+		if g, ok := d.StudentGroup(acode, uid); ok {
+			p.Groups[acode] = g.Code
+		}
+	}
+	for acode := range d.Releases {
+		if asgn, ok := d.Assignments[acode]; ok {
+			p.Grades[acode] = d.PubGrade(c, asgn, uid)
+		}
+	}
+	return p
+}
+
+// DueDate is like DB.DueDate, but uses the
+// extensions published in p.
+func (p *PubStudent) DueDate(asgn *Assignment, h Handin) time.Time {
+	if due, ok := p.Extensions[asgn.Code][h.Code]; ok {
+		return due
+	}
+	return h.Due
+}
+
+// WritePubStudent publishes p, replacing any previously
+// published information for the same student. The file
+// is replaced atomically, and is given an ACL granting
+// read access to the student.
+func (c *Context) WritePubStudent(p *PubStudent) (err error) {
+	// courses initialized before per-student
+	// files were introduced will not have
+	// this directory
+	dir := c.PubStudentsDir()
+	err = os.Mkdir(dir, config.PubStudentsDirPerms)
+	if err == nil {
+		// set permissions explicitly since original
+		// permissions might be masked (by umask)
+		err = os.Chmod(dir, config.PubStudentsDirPerms)
+	}
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	buf, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, p.UID+".tmp")
+	if err != nil {
+		return err
+	}
+	tmppath := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmppath)
+		}
+	}()
+	_, err = f.Write(buf)
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	a := append(
+		acl.FromUnix(perm.Parse("rw-rw----")),
+		acl.Entry{Tag: acl.TagUser, Qualifier: p.UID, Perms: perm.ParseSingle("r--")},
+		acl.Entry{Tag: acl.TagMask, Perms: perm.ParseSingle("rw-")},
+	)
+	err = acl.Set(tmppath, a)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmppath, c.PubStudentFile(p.UID))
+}
+
+// RemovePubStudent removes any information published
+// to the student with the given UID.
+func (c *Context) RemovePubStudent(uid string) error {
+	err := os.Remove(c.PubStudentFile(uid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ReadPubStudent reads the information published to
+// the student with the given UID. Like ReadPubDB, it
+// does not acquire any locks, and is intended to be
+// called by students.
+func (c *Context) ReadPubStudent(uid string) (*PubStudent, error) {
+	f, err := os.Open(c.PubStudentFile(uid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var p PubStudent
+	err = json.NewDecoder(f).Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
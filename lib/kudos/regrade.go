@@ -0,0 +1,115 @@
+package kudos
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type RegradeState string
+
+const (
+	RegradeOpen     RegradeState = "open"
+	RegradeAccepted RegradeState = "accepted"
+	RegradeRejected RegradeState = "rejected"
+)
+
+// A RegradeRequest is a student's request that
+// their grade on a problem be reconsidered.
+type RegradeRequest struct {
+	ID         int
+	Assignment string
+	Problem    string
+	StudentUID string
+	Reason     string
+	// the time at which the request was imported
+	// (the time in the student's RegradeRequestFile
+	// is not used, since students can write
+	// arbitrary request files)
+	Time time.Time
+
+	State RegradeState
+	// Response, ResolverUID, and Resolved
+	// are only set once the request has
+	// been accepted or rejected
+	Response    string
+	ResolverUID string
+	Resolved    time.Time
+}
+
+// AddRegradeRequest adds r to the database as an open
+// request, assigning it the next available ID.
+func (d *DB) AddRegradeRequest(r *RegradeRequest) {
+	r.ID = len(d.Regrades) + 1
+	r.State = RegradeOpen
+	d.Regrades = append(d.Regrades, r)
+}
+
+// FindRegradeRequest returns the regrade request
+// with the given ID.
+func (d *DB) FindRegradeRequest(id int) (r *RegradeRequest, ok bool) {
+	// IDs are assigned sequentially starting at 1
+	if id < 1 || id > len(d.Regrades) {
+		return nil, false
+	}
+	return d.Regrades[id-1], true
+}
+
+// ResolveRegradeRequest marks r as resolved with the
+// given state (which must be RegradeAccepted or
+// RegradeRejected) and response. It is an error to
+// resolve a request which is not open.
+func (d *DB) ResolveRegradeRequest(r *RegradeRequest, state RegradeState, response, resolverUID string) error {
+	if state != RegradeAccepted && state != RegradeRejected {
+		panic("lib/kudos: ResolveRegradeRequest: bad state")
+	}
+	if r.State != RegradeOpen {
+		return fmt.Errorf("regrade request already %v", r.State)
+	}
+	r.State = state
+	r.Response = response
+	r.ResolverUID = resolverUID
+	r.Resolved = time.Now()
+	return nil
+}
+
+// RegradeRequestFile is the format of the files
+// in which students submit regrade requests.
+type RegradeRequestFile struct {
+	Assignment string
+	Problem    string
+	Reason     string
+	Time       time.Time
+}
+
+// WriteRegradeRequest writes r to the given student's
+// regrade directory so that it can later be imported
+// by ReadRegradeRequests. It is intended to be called
+// by students.
+func (c *Context) WriteRegradeRequest(uid string, r *RegradeRequestFile) error {
+	name := fmt.Sprintf("%v.%v.%v", r.Assignment, r.Problem, r.Time.UnixNano())
+	return writeDropFile(c.UserRegradeDir(uid), name, "request*.tmp", r)
+}
+
+// ReadRegradeRequests reads all of the regrade requests
+// in the given student's regrade directory. The returned
+// paths are the paths of the files from which each of
+// the requests was read. If the student has no regrade
+// directory, no requests are returned. Since students
+// can write arbitrary files to their regrade directories,
+// files which cannot be read or parsed are skipped with
+// a warning rather than causing an error.
+func (c *Context) ReadRegradeRequests(uid string) (paths []string, requests []*RegradeRequestFile, err error) {
+	paths, err = c.readDropFiles(c.UserRegradeDir(uid), func(buf []byte) error {
+		var r RegradeRequestFile
+		if err := json.Unmarshal(buf, &r); err != nil {
+			return err
+		}
+		requests = append(requests, &r)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return paths, requests, nil
+}
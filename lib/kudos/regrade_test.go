@@ -0,0 +1,69 @@
+package kudos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshlf/kudos/lib/log"
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestRegradeRequests(t *testing.T) {
+	d := NewDB()
+	d.AddRegradeRequest(&RegradeRequest{Assignment: "a", Problem: "prob1", StudentUID: "0"})
+	d.AddRegradeRequest(&RegradeRequest{Assignment: "a", Problem: "prob2", StudentUID: "1"})
+
+	r, ok := d.FindRegradeRequest(2)
+	if !ok || r.ID != 2 || r.StudentUID != "1" || r.State != RegradeOpen {
+		t.Fatalf("unexpected regrade request: %+v", r)
+	}
+	if _, ok := d.FindRegradeRequest(3); ok {
+		t.Errorf("unexpectedly found nonexistent regrade request")
+	}
+
+	testutil.Must(t, d.ResolveRegradeRequest(r, RegradeRejected, "no", "100"))
+	if r.State != RegradeRejected || r.Response != "no" || r.ResolverUID != "100" || r.Resolved.IsZero() {
+		t.Errorf("unexpected resolved regrade request: %+v", r)
+	}
+	err := d.ResolveRegradeRequest(r, RegradeAccepted, "yes", "100")
+	testutil.MustError(t, "regrade request already rejected", err)
+
+	p := d.PubStudent(&Course{}, "1")
+	if !reflect.DeepEqual(p.Regrades, []*RegradeRequest{r}) {
+		t.Errorf("unexpected published regrade requests: %v", p.Regrades)
+	}
+}
+
+func TestRegradeRequestFiles(t *testing.T) {
+	dir := testutil.MustTempDir(t, "", "kudos")
+	defer os.RemoveAll(dir)
+
+	c := &Context{
+		GlobalConfig: &GlobalConfig{CoursePathPrefix: dir},
+		CourseCode:   "course",
+		Logger:       log.NewLogger(),
+	}
+	testutil.Must(t, os.MkdirAll(c.UserRegradeDir("0"), 0700))
+
+	paths, requests, err := c.ReadRegradeRequests("1")
+	if err != nil || len(paths) != 0 || len(requests) != 0 {
+		t.Errorf("unexpected result reading nonexistent directory: %v %v %v", paths, requests, err)
+	}
+
+	// a partially-written request and junk left
+	// by the student should both be skipped
+	testutil.Must(t, ioutil.WriteFile(filepath.Join(c.UserRegradeDir("0"), "request123.tmp"), []byte("{"), 0600))
+	testutil.Must(t, ioutil.WriteFile(filepath.Join(c.UserRegradeDir("0"), "junk"), []byte("junk"), 0600))
+
+	r := &RegradeRequestFile{"a", "prob1", "reason", time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)}
+	testutil.Must(t, c.WriteRegradeRequest("0", r))
+	paths, requests, err = c.ReadRegradeRequests("0")
+	testutil.Must(t, err)
+	if len(paths) != 1 || !reflect.DeepEqual(requests, []*RegradeRequestFile{r}) {
+		t.Errorf("unexpected regrade requests: got %v; want %v", requests, []*RegradeRequestFile{r})
+	}
+}
@@ -0,0 +1,147 @@
+package kudos
+
+import "time"
+
+// A Release records that an assignment's grades
+// have been released to students.
+type Release struct {
+	ReleaserUID string
+	Time        time.Time
+}
+
+// ReleaseGrades records that the grades for the given
+// assignment have been released, replacing any previous
+// release. Once an assignment's grades have been released,
+// they are included in the information published to each
+// student (see DB.PubStudent).
+func (d *DB) ReleaseGrades(assignment string, r *Release) {
+	d.Releases[assignment] = r
+}
+
+// WithdrawGrades withdraws the release of the given
+// assignment's grades. It returns true if the release
+// was withdrawn and false if the assignment's grades
+// had not been released.
+func (d *DB) WithdrawGrades(assignment string) bool {
+	if _, ok := d.Releases[assignment]; !ok {
+		return false
+	}
+	delete(d.Releases, assignment)
+	return true
+}
+
+// A PubGrade is a student's grade on a single
+// assignment as published to that student.
+type PubGrade struct {
+	Assignment string
+	Name       string
+	Released   time.Time
+
+	// all problems (including subproblems)
+	// in pre-order
+	Problems []PubProblemGrade
+
+	// whether the student has been excused
+	// from the entire assignment
+	Excused bool
+	// Total and AdjustedTotal are only
+	// valid if Complete is true; OutOf
+	// does not include excused problems
+	Complete      bool
+	Total         Decimal
+	AdjustedTotal Decimal
+	OutOf         Decimal
+}
+
+// A PubProblemGrade is a student's grade on a
+// single problem as published to that student.
+type PubProblemGrade struct {
+	Code string
+	Name string
+	// the number of ancestors the problem
+	// has (0 for top-level problems)
+	Depth       int
+	Points      Decimal
+	ExtraCredit bool
+	// how the problem's subproblems are
+	// combined (see Problem.DescribeAggregate)
+	Aggregate string
+
+	// Grade is only valid if Graded is true;
+	// if the problem itself has no grade but
+	// its subproblems do, Grade is computed
+	// from their grades (see AggregateRule)
+	Graded bool
+	Grade  Decimal
+	// set if the problem was graded on a
+	// scale (see ProblemGrade.Symbol)
+	Symbol  string
+	Comment string
+	// if Excused is true, Graded is
+	// true and Grade is 0
+	Excused bool
+}
+
+// PubGrade computes the student's grade on asgn as it
+// should be published to the student. The grade is
+// computed regardless of whether asgn's grades have
+// been released.
+func (d *DB) PubGrade(c *Course, asgn *Assignment, uid string) *PubGrade {
+	p := &PubGrade{
+		Assignment: asgn.Code,
+		Name:       asgn.Name,
+	}
+	if r, ok := d.Releases[asgn.Code]; ok {
+		p.Released = r.Time
+	}
+	g, ok := d.Grades[asgn.Code][uid]
+	if !ok {
+		g = NewAssignmentGrade()
+	}
+	p.Excused = g.AssignmentExcused()
+	p.OutOf = g.OutOf(asgn)
+
+	var walk func(problems []Problem, depth int)
+	walk = func(problems []Problem, depth int) {
+		for _, prob := range problems {
+			pg := PubProblemGrade{
+				Code:        prob.Code,
+				Name:        prob.Name,
+				Depth:       depth,
+				Points:      prob.Points,
+				ExtraCredit: prob.ExtraCredit,
+				Aggregate:   prob.DescribeAggregate(),
+			}
+			pg.Grade, pg.Graded = g.ProblemTotal(asgn, prob.Code)
+			_, pg.Excused = g.Excusal(asgn, prob.Code)
+			pg.Symbol = g.Grades[prob.Code].Symbol
+			pg.Comment = g.Grades[prob.Code].Comment
+			p.Problems = append(p.Problems, pg)
+			walk(prob.Subproblems, depth+1)
+		}
+	}
+	walk(asgn.Problems, 0)
+
+	p.Total, p.Complete = g.Total(asgn)
+	if p.Complete {
+		lateness := d.Lateness(asgn, uid, asgn.EffectiveLatePolicy(c))
+		p.AdjustedTotal, _ = g.AdjustedTotal(asgn, lateness)
+	}
+	return p
+}
+
+// PubAssignment computes the information about asgn
+// which should be published in the PubDB.
+func (d *DB) PubAssignment(asgn *Assignment) *PubAssignment {
+	p := &PubAssignment{
+		Code: asgn.Code,
+		Name: asgn.Name,
+	}
+	for _, h := range asgn.Handins {
+		p.Handins = append(p.Handins, PubHandin{h.Code, h.Due})
+	}
+	if r, ok := d.Releases[asgn.Code]; ok {
+		p.Released = r.Time
+	}
+	return p
+}
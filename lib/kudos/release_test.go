@@ -0,0 +1,53 @@
+package kudos
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestPubGrade(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	c := &Course{}
+	editor := GradeEditor{UID: "100"}
+	testutil.Must(t, d.SetGrade(asgn, "0", "prob1", ProblemGrade{Grade: dec(40), Comment: "good"}, false, editor))
+	testutil.Must(t, d.SetGrade(asgn, "0", "a", ProblemGrade{Grade: dec(20)}, false, editor))
+
+	if p := d.PubStudent(c, "0"); len(p.Grades) != 0 {
+		t.Errorf("unexpected grades published before release: %v", p.Grades)
+	}
+
+	released := time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)
+	d.ReleaseGrades(asgn.Code, &Release{ReleaserUID: "100", Time: released})
+	expect := &PubGrade{
+		Assignment: "a",
+		Name:       "a",
+		Released:   released,
+		Problems: []PubProblemGrade{
+			{Code: "prob1", Name: "Problem 1", Points: dec(50), Graded: true, Grade: dec(40), Comment: "good"},
+			{Code: "prob2", Name: "Problem 2", Points: dec(50)},
+			{Code: "a", Depth: 1, Points: dec(25), Graded: true, Grade: dec(20)},
+			{Code: "b", Depth: 1, Points: dec(25)},
+		},
+		OutOf: dec(100),
+	}
+	p := d.PubStudent(c, "0")
+	if !reflect.DeepEqual(p.Grades["a"], expect) {
+		t.Errorf("unexpected published grade: got %+v; want %+v", p.Grades["a"], expect)
+	}
+
+	testutil.Must(t, d.SetGrade(asgn, "0", "b", ProblemGrade{Grade: dec(25)}, false, editor))
+	g := d.PubStudent(c, "0").Grades["a"]
+	if !g.Complete || g.Total != dec(85) || g.AdjustedTotal != dec(85) || !g.Problems[1].Graded || g.Problems[1].Grade != dec(45) {
+		t.Errorf("unexpected published grade: %+v", g)
+	}
+
+	if !d.WithdrawGrades(asgn.Code) {
+		t.Errorf("unexpected failure to withdraw grades")
+	}
+	if p := d.PubStudent(c, "0"); len(p.Grades) != 0 {
+		t.Errorf("unexpected grades published after withdrawal: %v", p.Grades)
+	}
+}
@@ -0,0 +1,244 @@
+package kudos
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// the headers of the optional columns
+// in roster CSV files
+const (
+	rosterNameColumn    = "name"
+	rosterEmailColumn   = "email"
+	rosterIDColumn      = "id"
+	rosterSectionColumn = "section"
+	rosterStatusColumn  = "status"
+)
+
+// A RosterRow is a single row of a roster CSV file.
+type RosterRow struct {
+	// the line on which the row appeared (as
+	// in CSVGradeRow)
+	Line int
+	// the username or UID identifying the student
+	Student string
+	// the student's information; the UID
+	// is not set, and empty fields were
+	// not given
+	Info Student
+}
+
+// ParseRosterCSV parses a CSV file giving the course
+// roster. The first row must be a header. One column,
+// whose header is "student", must give each row's
+// student (by username or UID); the remaining columns
+// are optional, and may be "name", "email", "id" (an
+// external ID such as a registrar number), "section",
+// and "status" (one of active, dropped, or auditing;
+// an empty status is active). Sections must be valid
+// codes (see ValidateCode), but are not checked against
+// the course's sections (see DB.ImportRoster).
+//
+// Students are not resolved, so it is the caller's
+// responsibility to check that no student appears
+// more than once.
+func ParseRosterCSV(r io.Reader) ([]RosterRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// maps headers to column indices
+	cols := make(map[string]int)
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if _, ok := cols[h]; ok {
+			return nil, fmt.Errorf("duplicate column: %v", h)
+		}
+		switch h {
+		case CSVStudentColumn, rosterNameColumn, rosterEmailColumn,
+			rosterIDColumn, rosterSectionColumn, rosterStatusColumn:
+		default:
+			return nil, fmt.Errorf("unknown column %q", h)
+		}
+		cols[h] = i
+	}
+	if _, ok := cols[CSVStudentColumn]; !ok {
+		return nil, fmt.Errorf("missing %v column", CSVStudentColumn)
+	}
+
+	// maps external IDs to the lines
+	// on which they appeared
+	ids := make(map[string]int)
+	var rows []RosterRow
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(h string) string {
+			i, ok := cols[h]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		row := RosterRow{Line: line, Student: field(CSVStudentColumn)}
+		if row.Student == "" {
+			return nil, fmt.Errorf("line %v: missing student", line)
+		}
+		row.Info = Student{
+			Name:       field(rosterNameColumn),
+			Email:      field(rosterEmailColumn),
+			ExternalID: field(rosterIDColumn),
+			Section:    field(rosterSectionColumn),
+		}
+		if sec := row.Info.Section; sec != "" {
+			if err := ValidateCode(sec); err != nil {
+				return nil, fmt.Errorf("line %v: bad section code %q: %v", line, sec, err)
+			}
+		}
+		row.Info.Status, err = ParseEnrollment(field(rosterStatusColumn))
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		if id := row.Info.ExternalID; id != "" {
+			if l, ok := ids[id]; ok {
+				return nil, fmt.Errorf("line %v: id %v already appeared on line %v", line, id, l)
+			}
+			ids[id] = line
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// A RosterConflict is a field of a student's information
+// whose value in the database differs from its value in
+// an imported roster.
+type RosterConflict struct {
+	UID      string
+	Field    string
+	Old, New string
+}
+
+// A RosterImport describes the changes made by
+// DB.ImportRoster. Each slice holds student UIDs.
+type RosterImport struct {
+	// students who were not in the database
+	Added []string
+	// students whose information was changed,
+	// not counting changes in enrollment status
+	// which are recorded in Dropped or Reenrolled
+	Updated []string
+	// students who were dropped, either because
+	// they were missing from the roster or because
+	// the roster marked them as dropped
+	Dropped []string
+	// students who had been dropped but
+	// were on the roster and not dropped
+	Reenrolled []string
+	// fields whose values in the database were
+	// not empty and differed from the roster
+	Conflicts []RosterConflict
+}
+
+// ImportRoster reconciles the students in the database
+// with roster, which must be the complete course roster
+// (with at most one entry per UID). Students who are not
+// in the database are added; students in the database
+// who are not in roster are marked as dropped (their
+// grades and handins are kept). For students already in
+// the database, empty fields in roster are ignored, and
+// empty fields in the database are filled in; fields
+// which have different, non-empty values are reported as
+// conflicts, and are only overwritten if overwrite is true.
+// Enrollment statuses are always taken from roster.
+// Every section given in roster must already exist (see
+// DB.AddSection); if any does not, an error is returned
+// and the database is left unchanged.
+func (d *DB) ImportRoster(roster []*Student, overwrite bool) (*RosterImport, error) {
+	for _, r := range roster {
+		if r.Section == "" {
+			continue
+		}
+		if _, ok := d.Sections[r.Section]; !ok {
+			return nil, fmt.Errorf("no such section: %v", r.Section)
+		}
+	}
+
+	var imp RosterImport
+	onRoster := make(map[string]bool)
+	for _, r := range roster {
+		onRoster[r.UID] = true
+		s, ok := d.Students[r.UID]
+		if !ok {
+			ss := *r
+			ss.Status = r.Enrollment()
+			d.Students[r.UID] = &ss
+			imp.Added = append(imp.Added, r.UID)
+			continue
+		}
+
+		updated := false
+		for _, f := range []struct {
+			name     string
+			old, new *string
+		}{
+			{rosterNameColumn, &s.Name, &r.Name},
+			{rosterEmailColumn, &s.Email, &r.Email},
+			{rosterIDColumn, &s.ExternalID, &r.ExternalID},
+			{rosterSectionColumn, &s.Section, &r.Section},
+		} {
+			if *f.new == "" || *f.old == *f.new {
+				continue
+			}
+			if *f.old != "" {
+				imp.Conflicts = append(imp.Conflicts, RosterConflict{r.UID, f.name, *f.old, *f.new})
+				if !overwrite {
+					continue
+				}
+			}
+			*f.old = *f.new
+			updated = true
+		}
+
+		switch old, new := s.Enrollment(), r.Enrollment(); {
+		case old == new:
+		case new == EnrollmentDropped:
+			imp.Dropped = append(imp.Dropped, r.UID)
+		case old == EnrollmentDropped:
+			imp.Reenrolled = append(imp.Reenrolled, r.UID)
+		default:
+			updated = true
+		}
+		s.Status = r.Enrollment()
+		if updated {
+			imp.Updated = append(imp.Updated, r.UID)
+		}
+	}
+
+	var missing []string
+	for uid, s := range d.Students {
+		if !onRoster[uid] && !s.Dropped() {
+			missing = append(missing, uid)
+		}
+	}
+	sort.Strings(missing)
+	for _, uid := range missing {
+		d.Students[uid].Status = EnrollmentDropped
+		imp.Dropped = append(imp.Dropped, uid)
+	}
+	return &imp, nil
+}
@@ -0,0 +1,117 @@
+package kudos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+var parseRosterCSVTestCases = []struct {
+	csv  string
+	rows []RosterRow
+	err  string
+}{
+	{"", nil, "missing header"},
+	{"name\nfoo\n", nil, "missing student column"},
+	{"student,name,name\n", nil, "duplicate column: name"},
+	{"student,phone\n", nil, `unknown column "phone"`},
+	{"student,name\n,Foo\n", nil, "line 2: missing student"},
+	{"student,status\nfoo,withdrawn\n", nil, `line 2: unknown enrollment status "withdrawn"; must be one of active, dropped, or auditing`},
+	{"student,id\nfoo,123\nbar,456\nbaz,123\n", nil, "line 4: id 123 already appeared on line 2"},
+	{"student,section\nfoo,-\n", nil, `line 2: bad section code "-": contains illegal characters; must be alphanumeric and start with an alphabetic character`},
+	{"student\n", nil, ""},
+	{"email,student,name,id,section,status\nfoo@example.com, foo ,Foo Bar,123,A,\n,0,,,,auditing\n", []RosterRow{
+		{2, "foo", Student{Name: "Foo Bar", Email: "foo@example.com", ExternalID: "123", Section: "A", Status: EnrollmentActive}},
+		{3, "0", Student{Status: EnrollmentAuditing}},
+	}, ""},
+}
+
+func TestParseRosterCSV(t *testing.T) {
+	for i, test := range parseRosterCSVTestCases {
+		rows, err := ParseRosterCSV(strings.NewReader(test.csv))
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+		if !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%v: unexpected rows: got %v; want %v", prefix, rows, test.rows)
+		}
+	}
+}
+
+func TestImportRoster(t *testing.T) {
+	newDB := func() *DB {
+		d := NewDB()
+		for _, uid := range []string{"0", "1", "2", "3", "4"} {
+			d.AddStudent(uid)
+		}
+		d.Students["0"].Name = "Zero"
+		d.Students["0"].Email = "zero@example.com"
+		d.Students["3"].Status = EnrollmentDropped
+		// as in databases created before
+		// enrollment statuses were introduced
+		d.Students["4"].Status = ""
+		d.AddSection(&Section{Code: "A"})
+		return d
+	}
+	roster := []*Student{
+		{UID: "0", Name: "Zero", Email: "0@example.com", Section: "A"},
+		{UID: "1", Status: EnrollmentAuditing},
+		{UID: "3", ExternalID: "333"},
+		{UID: "5", Name: "Five", Status: EnrollmentActive},
+	}
+
+	d := newDB()
+	imp, err := d.ImportRoster(roster, false)
+	testutil.Must(t, err)
+	expect := &RosterImport{
+		Added:      []string{"5"},
+		Updated:    []string{"0", "1", "3"},
+		Dropped:    []string{"2", "4"},
+		Reenrolled: []string{"3"},
+		Conflicts:  []RosterConflict{{"0", "email", "zero@example.com", "0@example.com"}},
+	}
+	if !reflect.DeepEqual(imp, expect) {
+		t.Errorf("unexpected import: got %+v; want %+v", imp, expect)
+	}
+	for uid, s := range map[string]Student{
+		"0": {UID: "0", Name: "Zero", Email: "zero@example.com", Section: "A", Status: EnrollmentActive},
+		"1": {UID: "1", Status: EnrollmentAuditing},
+		"2": {UID: "2", Status: EnrollmentDropped},
+		"3": {UID: "3", ExternalID: "333", Status: EnrollmentActive},
+		"4": {UID: "4", Status: EnrollmentDropped},
+		"5": {UID: "5", Name: "Five", Status: EnrollmentActive},
+	} {
+		if !reflect.DeepEqual(*d.Students[uid], s) {
+			t.Errorf("unexpected student %v: got %+v; want %+v", uid, *d.Students[uid], s)
+		}
+	}
+
+	// importing again changes nothing
+	imp, err = d.ImportRoster(roster, false)
+	testutil.Must(t, err)
+	if len(imp.Added)+len(imp.Updated)+len(imp.Dropped)+len(imp.Reenrolled) != 0 || len(imp.Conflicts) != 1 {
+		t.Errorf("unexpected changes on reimport: %+v", imp)
+	}
+
+	d = newDB()
+	_, err = d.ImportRoster(roster, true)
+	testutil.Must(t, err)
+	if e := d.Students["0"].Email; e != "0@example.com" {
+		t.Errorf("conflicting email not overwritten: got %v", e)
+	}
+
+	// sections must exist, and a failed
+	// import leaves the database untouched
+	d = newDB()
+	_, err = d.ImportRoster(append(roster, &Student{UID: "6", Section: "B"}), false)
+	testutil.MustError(t, "no such section: B", err)
+	if _, ok := d.Students["5"]; ok || d.Students["0"].Section != "" {
+		t.Errorf("database modified by failed import")
+	}
+}
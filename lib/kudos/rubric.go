@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 )
 
 type RubricGrade struct {
 	Problem string
-	Grade   float64
+	Grade   Decimal
+	// if the grade was given as a symbol (see
+	// Scale), Grade is only valid once the rubric
+	// has been checked (see CheckAssignment)
+	Symbol  string
 	Comment string
 }
 
@@ -30,12 +35,14 @@ func (r *Rubric) GetUID(ctx *Context) (uid string, ok bool) {
 }
 
 // A jsonVerifiedGrade accepts a json field whose value
-// is either a boolean or a number. If it is a boolean,
-// then it is taken to mean that the original grade
-// was not overwritten by the user.
+// is either a boolean, a number, or a string (a symbol
+// from a Scale). If it is a boolean, then it is taken
+// to mean that the original grade was not overwritten
+// by the user.
 type jsonVerifiedGrade struct {
-	set   bool
-	grade float64
+	set    bool
+	grade  Decimal
+	symbol string
 }
 
 func (j *jsonVerifiedGrade) UnmarshalJSON(b []byte) error {
@@ -43,16 +50,27 @@ func (j *jsonVerifiedGrade) UnmarshalJSON(b []byte) error {
 	err := json.Unmarshal(b, &empty)
 	if err == nil {
 		if empty {
-			return fmt.Errorf("grade must be false or a number")
+			return fmt.Errorf("grade must be false, a number, or a symbol")
 		}
 		j.set = false
 		return nil
 	}
 
-	var grade float64
+	var symbol string
+	err = json.Unmarshal(b, &symbol)
+	if err == nil {
+		if symbol == "" {
+			return fmt.Errorf("grade must be false, a number, or a symbol")
+		}
+		j.set = true
+		j.symbol = symbol
+		return nil
+	}
+
+	var grade Decimal
 	err = json.Unmarshal(b, &grade)
 	if err != nil {
-		return fmt.Errorf("grade must be false or a number")
+		return fmt.Errorf("grade must be false, a number, or a symbol")
 	}
 	j.set = true
 	j.grade = grade
@@ -60,7 +78,10 @@ func (j *jsonVerifiedGrade) UnmarshalJSON(b []byte) error {
 }
 
 func (j *jsonVerifiedGrade) MarshalJSON() ([]byte, error) {
-	if j.set {
+	switch {
+	case j.set && j.symbol != "":
+		return json.Marshal(j.symbol)
+	case j.set:
 		return json.Marshal(j.grade)
 	}
 	return json.Marshal(false)
@@ -131,6 +152,68 @@ func GenerateRubric(w io.Writer, asgn *Assignment, uid, token string, problems .
 	return err
 }
 
+// CheckAssignment verifies that r is a valid rubric for
+// asgn: that it refers to asgn, that every graded problem
+// exists, that problems graded on a scale are given one
+// of the scale's symbols (and other problems are given
+// numbers), that no grade is negative or higher than the
+// problem's point value, and that no two graded problems
+// are ancestors of one another. Symbolic grades' values
+// are filled in from the problems' scales.
+func (r *Rubric) CheckAssignment(asgn *Assignment) error {
+	if r.Assignment != asgn.Code {
+		return fmt.Errorf("rubric is for assignment %v, not %v", r.Assignment, asgn.Code)
+	}
+	seen := make(map[string]bool)
+	for i, g := range r.Grades {
+		p, ok := asgn.FindProblemByCode(g.Problem)
+		if !ok {
+			return fmt.Errorf("no such problem: %v", g.Problem)
+		}
+		if p.Scale != nil {
+			grade, _, err := p.ParseGrade(g.Symbol)
+			if err != nil {
+				return err
+			}
+			r.Grades[i].Grade = grade
+			seen[g.Problem] = true
+			continue
+		}
+		switch {
+		case g.Symbol != "":
+			return fmt.Errorf("problem %v is not graded on a scale; grade must be a number", g.Problem)
+		case g.Grade.Sign() < 0:
+			return fmt.Errorf("grade for problem %v is negative", g.Problem)
+		case g.Grade.Cmp(p.Points) > 0:
+			return fmt.Errorf("grade for problem %v is higher than the maximum (%v points)", g.Problem, p.Points)
+		}
+		seen[g.Problem] = true
+	}
+	for _, g := range r.Grades {
+		path, _ := asgn.FindProblemPathByCode(g.Problem)
+		for _, parent := range path {
+			if seen[parent] {
+				return fmt.Errorf("problem %v conflicts with problem %v "+
+					"(%v is a child of %v)", g.Problem, parent, g.Problem, parent)
+			}
+		}
+	}
+	return nil
+}
+
+func ParseRubricFile(path string) (*Rubric, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := parseRubric(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse: %v", err)
+	}
+	return r, nil
+}
+
 func parseRubric(r io.Reader) (*Rubric, error) {
 	d := json.NewDecoder(r)
 	var rubric parseableRubric
@@ -156,6 +239,7 @@ func parseRubric(r io.Reader) (*Rubric, error) {
 		gg := RubricGrade{
 			Problem: *g.Problem,
 			Grade:   g.Grade.grade,
+			Symbol:  g.Grade.symbol,
 		}
 		if g.Comment != nil {
 			gg.Comment = *g.Comment
@@ -32,9 +32,9 @@ var rubricErrTests = []struct {
 	{`{"anonymous_token":"aa","assignment":"a","grades":[{"problem":"a"}]}`,
 		"no grade given for problem a"},
 	{`{"anonymous_token":"aa","assignment":"a","grades":[{"problem":"a","grade":""}]}`,
-		"grade must be false or a number"},
+		"grade must be false, a number, or a symbol"},
 	{`{"anonymous_token":"aa","assignment":"a","grades":[{"problem":"a","grade":true}]}`,
-		"grade must be false or a number"},
+		"grade must be false, a number, or a symbol"},
 	{`{"anonymous_token":"aa","assignment":"a","grades":[{"problem":"a","grade":false}]}`,
 		"grade left unset for problem a"},
 	{`{"anonymous_token":"aa","assignment":"a","grades":[{"problem":"a","grade":0}]}`, ""},
@@ -53,3 +53,31 @@ func TestParseRubricError(t *testing.T) {
 		}
 	}
 }
+
+var rubricCheckAssignmentTests = []struct {
+	grades []RubricGrade
+	err    string
+}{
+	{[]RubricGrade{{Problem: "prob1", Grade: dec(50)}}, ""},
+	{[]RubricGrade{{Problem: "c", Grade: dec(0)}}, "no such problem: c"},
+	{[]RubricGrade{{Problem: "prob1", Grade: dec(-1)}}, "grade for problem prob1 is negative"},
+	{[]RubricGrade{{Problem: "a", Grade: dec(26)}},
+		"grade for problem a is higher than the maximum (25 points)"},
+	{[]RubricGrade{{Problem: "a", Grade: dec(1)}, {Problem: "prob2", Grade: dec(1)}},
+		"problem a conflicts with problem prob2 (a is a child of prob2)"},
+}
+
+func TestRubricCheckAssignment(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(findProblemPathByCodeTestAssignment))
+	testutil.Must(t, err)
+	for i, test := range rubricCheckAssignmentTests {
+		r := &Rubric{UID: "0", Assignment: "a", Grades: test.grades}
+		err := r.CheckAssignment(asgn)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err == "" {
+			testutil.MustPrefix(t, prefix, err)
+		} else {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package kudos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Scale is a named set of symbolic grades (for example,
+// "check-plus", "check", and "check-minus"), each of which
+// stands for a number of points. Problems graded on a
+// scale are given one of the scale's symbols rather than
+// a number, and the symbol is recorded along with its
+// point value (see ProblemGrade).
+type Scale struct {
+	Name string
+	// maps symbols to point values
+	Values map[string]Decimal
+}
+
+// Symbols returns s's symbols ordered from highest
+// to lowest point value (and then alphabetically).
+func (s *Scale) Symbols() []string {
+	var syms scaleSymbols
+	for sym := range s.Values {
+		syms.syms = append(syms.syms, sym)
+	}
+	syms.s = s
+	sort.Sort(syms)
+	return syms.syms
+}
+
+type scaleSymbols struct {
+	s    *Scale
+	syms []string
+}
+
+func (s scaleSymbols) Len() int { return len(s.syms) }
+func (s scaleSymbols) Less(i, j int) bool {
+	vi, vj := s.s.Values[s.syms[i]], s.s.Values[s.syms[j]]
+	if c := vi.Cmp(vj); c != 0 {
+		return c > 0
+	}
+	return s.syms[i] < s.syms[j]
+}
+func (s scaleSymbols) Swap(i, j int) { s.syms[i], s.syms[j] = s.syms[j], s.syms[i] }
+
+// ParseGrade parses a grade for p given as a string.
+// If p is graded on a scale, the grade must be one
+// of the scale's symbols, and both the symbol and
+// its point value are returned. Otherwise, the grade
+// must be a number, and symbol is empty.
+func (p Problem) ParseGrade(s string) (grade Decimal, symbol string, err error) {
+	if p.Scale != nil {
+		g, ok := p.Scale.Values[s]
+		if !ok {
+			return Decimal{}, "", fmt.Errorf("problem %v is graded on scale %v; grade must be one of: %v",
+				p.Code, p.Scale.Name, strings.Join(p.Scale.Symbols(), ", "))
+		}
+		return g, s, nil
+	}
+	grade, err = ParseDecimal(s)
+	return grade, "", err
+}
+
+func validateScales(scales map[string]map[string]Decimal) error {
+	var names []string
+	for name := range scales {
+		names = append(names, name)
+	}
+	// validate in a consistent order so
+	// that errors are deterministic
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ValidateCode(name); err != nil {
+			return fmt.Errorf("bad scale name %q: %v", name, err)
+		}
+		if len(scales[name]) == 0 {
+			return fmt.Errorf("scale %v must have at least one symbol", name)
+		}
+		var syms []string
+		for sym := range scales[name] {
+			syms = append(syms, sym)
+		}
+		sort.Strings(syms)
+		for _, sym := range syms {
+			switch {
+			case sym == "" || strings.TrimSpace(sym) != sym:
+				return fmt.Errorf("scale %v has bad symbol %q: must be non-empty and have no surrounding whitespace", name, sym)
+			case isNumber(sym):
+				// otherwise, a grade given on the command
+				// line would be ambiguous
+				return fmt.Errorf("scale %v has bad symbol %q: must not be a number", name, sym)
+			case scales[name][sym].Sign() < 0:
+				return fmt.Errorf("scale %v's symbol %v has negative value", name, sym)
+			}
+		}
+	}
+	return nil
+}
+
+func isNumber(s string) bool {
+	_, _, err := parseDecimal(s)
+	return err == nil
+}
@@ -0,0 +1,97 @@
+package kudos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+const scaleTestAssignment = `{"code":"a",
+	"handins":[{"due":"Jul 4, 2015 at 12:00am (EST)","problems":["lab","prob"]}],
+	"scales":{"checks":{"check-plus":10,"check":8,"check-minus":5}},
+	"problems":[
+		{"code":"lab","points":10,"scale":"checks"},
+		{"code":"prob","points":10}
+	]}`
+
+var parseGradeTests = []struct {
+	problem string
+	grade   string
+	value   float64
+	symbol  string
+	err     string
+}{
+	{"lab", "check", 8, "check", ""},
+	{"lab", "check-plus", 10, "check-plus", ""},
+	{"lab", "8", 0, "", "problem lab is graded on scale checks; grade must be one of: check-plus, check, check-minus"},
+	{"prob", "7.5", 7.5, "", ""},
+	{"prob", "check", 0, "", `could not parse "check" as a decimal number`},
+}
+
+func TestParseGrade(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(scaleTestAssignment))
+	testutil.Must(t, err)
+	for i, test := range parseGradeTests {
+		p, _ := asgn.FindProblemByCode(test.problem)
+		value, symbol, err := p.ParseGrade(test.grade)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+		if value != dec(test.value) || symbol != test.symbol {
+			t.Errorf("%v: unexpected grade: got %v (%q); want %v (%q)", prefix, value, symbol, test.value, test.symbol)
+		}
+	}
+}
+
+var scaleRubricTests = []struct {
+	grades []RubricGrade
+	expect float64
+	err    string
+}{
+	{[]RubricGrade{{Problem: "lab", Symbol: "check-minus"}}, 5, ""},
+	{[]RubricGrade{{Problem: "lab", Grade: dec(5)}}, 0,
+		"problem lab is graded on scale checks; grade must be one of: check-plus, check, check-minus"},
+	{[]RubricGrade{{Problem: "prob", Symbol: "check"}}, 0,
+		"problem prob is not graded on a scale; grade must be a number"},
+}
+
+func TestScaleRubric(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(scaleTestAssignment))
+	testutil.Must(t, err)
+	for i, test := range scaleRubricTests {
+		r := &Rubric{UID: "0", Assignment: "a", Grades: test.grades}
+		err := r.CheckAssignment(asgn)
+		prefix := fmt.Sprintf("test case %v", i)
+		if test.err != "" {
+			testutil.MustErrorPrefix(t, prefix, test.err, err)
+			continue
+		}
+		testutil.MustPrefix(t, prefix, err)
+		if r.Grades[0].Grade != dec(test.expect) {
+			t.Errorf("%v: unexpected grade: got %v; want %v", prefix, r.Grades[0].Grade, test.expect)
+		}
+	}
+
+	r, err := parseRubric(strings.NewReader(`{"uid":"0","assignment":"a","grades":[{"problem":"lab","grade":"check"}]}`))
+	testutil.Must(t, err)
+	if r.Grades[0].Symbol != "check" {
+		t.Errorf("unexpected symbol: got %q; want %q", r.Grades[0].Symbol, "check")
+	}
+}
+
+func TestScaleCSV(t *testing.T) {
+	asgn, err := parseAssignment(strings.NewReader(scaleTestAssignment))
+	testutil.Must(t, err)
+	rows, err := ParseGradeCSV(strings.NewReader("student,lab,prob\nfoo,check,7\n"), asgn)
+	testutil.Must(t, err)
+	expect := []CSVGradeRow{{2, "foo", []CSVGrade{{"lab", dec(8), "check", ""}, {"prob", dec(7), "", ""}}}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Errorf("unexpected rows: got %v; want %v", rows, expect)
+	}
+}
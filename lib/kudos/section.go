@@ -0,0 +1,83 @@
+package kudos
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Section is a group of students, such
+// as a discussion section, which may be
+// led by a TA.
+type Section struct {
+	Code string
+	// Name is informational, and may be empty
+	Name string
+	// the UID of the TA who leads the
+	// section; empty if there is none
+	LeaderUID string
+}
+
+// AddSection adds s to the database. It returns true
+// if the section was added and false if a section with
+// the same code already exists in the database.
+func (d *DB) AddSection(s *Section) bool {
+	if _, ok := d.Sections[s.Code]; ok {
+		return false
+	}
+	d.Sections[s.Code] = s
+	return true
+}
+
+// AssignSection puts the student with the given uid
+// in the given section, replacing any previous section.
+// If section is empty, the student is removed from
+// their section.
+func (d *DB) AssignSection(uid, section string) error {
+	s, ok := d.Students[uid]
+	if !ok {
+		return fmt.Errorf("no such student")
+	}
+	if _, ok := d.Sections[section]; !ok && section != "" {
+		return fmt.Errorf("no such section: %v", section)
+	}
+	s.Section = section
+	return nil
+}
+
+// InSection returns whether the student with the given
+// uid is in the given section. Every student is in the
+// empty section; this makes it convenient to use with
+// optional section filters.
+func (d *DB) InSection(uid, section string) bool {
+	if section == "" {
+		return true
+	}
+	s, ok := d.Students[uid]
+	return ok && s.Section == section
+}
+
+// SectionStudents returns the UIDs, in sorted order,
+// of the students in the given section.
+func (d *DB) SectionStudents(section string) []string {
+	var uids []string
+	for uid, s := range d.Students {
+		if s.Section == section {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// LedSections returns the codes, in sorted order, of
+// the sections led by the TA with the given uid.
+func (d *DB) LedSections(uid string) []string {
+	var codes []string
+	for code, s := range d.Sections {
+		if s.LeaderUID == uid {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
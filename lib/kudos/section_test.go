@@ -0,0 +1,57 @@
+package kudos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestSections(t *testing.T) {
+	d := NewDB()
+	// as in databases created before sections
+	// were introduced, once they are opened
+	d.Sections = nil
+	d.initMaps()
+	for _, uid := range []string{"0", "1", "2"} {
+		d.AddStudent(uid)
+	}
+	if !d.AddSection(&Section{Code: "a", LeaderUID: "100"}) ||
+		!d.AddSection(&Section{Code: "b", LeaderUID: "101"}) ||
+		!d.AddSection(&Section{Code: "c", LeaderUID: "100"}) {
+		t.Fatalf("failed to add sections")
+	}
+	if d.AddSection(&Section{Code: "a"}) {
+		t.Errorf("unexpected addition of duplicate section")
+	}
+
+	testutil.Must(t, d.AssignSection("0", "a"))
+	testutil.Must(t, d.AssignSection("1", "a"))
+	testutil.Must(t, d.AssignSection("2", "b"))
+	testutil.MustError(t, "no such section: d", d.AssignSection("0", "d"))
+	testutil.MustError(t, "no such student", d.AssignSection("3", "a"))
+	if s := d.SectionStudents("a"); !reflect.DeepEqual(s, []string{"0", "1"}) {
+		t.Errorf("unexpected students in section a: %v", s)
+	}
+	testutil.Must(t, d.AssignSection("1", ""))
+	if s := d.SectionStudents("a"); !reflect.DeepEqual(s, []string{"0"}) {
+		t.Errorf("unexpected students in section a after unassignment: %v", s)
+	}
+
+	for _, test := range []struct {
+		uid, section string
+		in           bool
+	}{
+		{"0", "a", true}, {"0", "b", false}, {"0", "", true},
+		{"1", "a", false}, {"1", "", true}, {"3", "a", false},
+	} {
+		if d.InSection(test.uid, test.section) != test.in {
+			t.Errorf("unexpected membership of %v in section %q: got %v; want %v",
+				test.uid, test.section, !test.in, test.in)
+		}
+	}
+
+	if s := d.LedSections("100"); !reflect.DeepEqual(s, []string{"a", "c"}) {
+		t.Errorf("unexpected sections led by 100: %v", s)
+	}
+}
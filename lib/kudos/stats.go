@@ -0,0 +1,181 @@
+package kudos
+
+import (
+	"math"
+	"sort"
+)
+
+// A Sample is a sorted list of grades.
+type Sample []float64
+
+// NewSample creates a Sample from the given values.
+// values is not modified.
+func NewSample(values []float64) Sample {
+	s := make(Sample, len(values))
+	copy(s, values)
+	sort.Float64s(s)
+	return s
+}
+
+// Mean returns the mean of s,
+// or 0 if s is empty.
+func (s Sample) Mean() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range s {
+		sum += v
+	}
+	return sum / float64(len(s))
+}
+
+// StdDev returns the (population) standard
+// deviation of s, or 0 if s is empty.
+func (s Sample) StdDev() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	mean := s.Mean()
+	sum := 0.0
+	for _, v := range s {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(s)))
+}
+
+// Quantile returns the q-quantile of s (for
+// 0 <= q <= 1), interpolating linearly between
+// values if necessary. Quantile(0) is the
+// minimum, Quantile(0.5) is the median, and
+// Quantile(1) is the maximum. If s is empty,
+// Quantile returns 0.
+func (s Sample) Quantile(q float64) float64 {
+	if q < 0 || q > 1 {
+		panic("lib/kudos: Sample.Quantile: quantile out of range")
+	}
+	if len(s) == 0 {
+		return 0
+	}
+	pos := q * float64(len(s)-1)
+	i := int(pos)
+	if i == len(s)-1 {
+		return s[i]
+	}
+	frac := pos - float64(i)
+	return s[i] + frac*(s[i+1]-s[i])
+}
+
+func (s Sample) Median() float64 { return s.Quantile(0.5) }
+func (s Sample) Min() float64    { return s.Quantile(0) }
+func (s Sample) Max() float64    { return s.Quantile(1) }
+
+// Histogram divides the range [0, max] into the given
+// number of equal-width bins, and returns the number of
+// values in s falling into each bin. Each bin includes
+// its lower bound, and the last bin also includes max.
+// Values below 0 are counted in the first bin, and values
+// above max (for example, grades including extra credit)
+// are counted in the last.
+func (s Sample) Histogram(max float64, bins int) []int {
+	if bins < 1 {
+		panic("lib/kudos: Sample.Histogram: bins must be positive")
+	}
+	counts := make([]int, bins)
+	for _, v := range s {
+		i := 0
+		if max > 0 {
+			i = int(v / max * float64(bins))
+		}
+		switch {
+		case i < 0:
+			i = 0
+		case i >= bins:
+			i = bins - 1
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// AssignmentSamples holds the grades
+// given on a single assignment.
+type AssignmentSamples struct {
+	// the totals of complete grades (not adjusted
+	// for lateness); the totals of students excused
+	// from some problems are scaled to be out of the
+	// assignment's total points, and students excused
+	// from all problems are omitted
+	Total Sample
+	// keys are problem codes; the grades are
+	// totals as computed by ProblemTotal (and
+	// scaled as for Total), and incomplete and
+	// excused grades are omitted
+	Problems map[string]Sample
+	// keys are problem codes; values' keys are
+	// grader UIDs. Only grades assigned directly
+	// to a problem (as opposed to computed from
+	// its subproblems) are included.
+	Graders map[string]map[string]Sample
+}
+
+// AssignmentSamples collects the grades given
+// to the students in the database on asgn. If
+// section is not empty, only students in that
+// section are included.
+func (d *DB) AssignmentSamples(asgn *Assignment, section string) *AssignmentSamples {
+	var total []float64
+	problems := make(map[string][]float64)
+	graders := make(map[string]map[string][]float64)
+	for uid := range d.Students {
+		if !d.InSection(uid, section) {
+			continue
+		}
+		g, ok := d.Grades[asgn.Code][uid]
+		if !ok {
+			continue
+		}
+		if t, ok := g.Total(asgn); ok {
+			// scale the totals of students excused from
+			// some problems so that they are comparable
+			if outOf := g.OutOf(asgn); outOf.Sign() > 0 {
+				total = append(total, t.Float64()*asgn.TotalPoints().Float64()/outOf.Float64())
+			}
+		}
+		asgn.TraverseProblemsPreOrder(func(p Problem) {
+			if _, ok := g.Excusal(asgn, p.Code); ok {
+				return
+			}
+			if t, ok := g.ProblemTotal(asgn, p.Code); ok {
+				f := t.Float64()
+				// scale as for the assignment's total
+				if outOf := g.ProblemOutOf(asgn, p.Code); outOf.Sign() > 0 {
+					f = f * p.Points.Float64() / outOf.Float64()
+				}
+				problems[p.Code] = append(problems[p.Code], f)
+			}
+			if pg, ok := g.Grades[p.Code]; ok {
+				if graders[p.Code] == nil {
+					graders[p.Code] = make(map[string][]float64)
+				}
+				graders[p.Code][pg.GraderUID] = append(graders[p.Code][pg.GraderUID], pg.Grade.Float64())
+			}
+		})
+	}
+
+	s := &AssignmentSamples{
+		Total:    NewSample(total),
+		Problems: make(map[string]Sample),
+		Graders:  make(map[string]map[string]Sample),
+	}
+	for code, values := range problems {
+		s.Problems[code] = NewSample(values)
+	}
+	for code, gs := range graders {
+		s.Graders[code] = make(map[string]Sample)
+		for uid, values := range gs {
+			s.Graders[code][uid] = NewSample(values)
+		}
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package kudos
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+var sampleTestCases = []struct {
+	values                         []float64
+	mean, median, stddev, min, max float64
+	q25                            float64
+	histogram                      []int
+}{
+	{nil, 0, 0, 0, 0, 0, 0, []int{0, 0, 0, 0}},
+	{[]float64{5}, 5, 5, 0, 5, 5, 5, []int{0, 0, 1, 0}},
+	{[]float64{10, 0, 4, 6}, 5, 5, math.Sqrt(13), 0, 10, 3, []int{1, 1, 1, 1}},
+	{[]float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 4.5, 2, 2, 9, 4, []int{1, 3, 3, 1}},
+	// out-of-range values are clamped
+	{[]float64{-1, 12}, 5.5, 5.5, 6.5, -1, 12, 2.25, []int{1, 0, 0, 1}},
+}
+
+func TestSample(t *testing.T) {
+	for i, test := range sampleTestCases {
+		s := NewSample(test.values)
+		prefix := fmt.Sprintf("test case %v", i)
+		check := func(name string, got, want float64) {
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("%v: unexpected %v: got %v; want %v", prefix, name, got, want)
+			}
+		}
+		check("mean", s.Mean(), test.mean)
+		check("median", s.Median(), test.median)
+		check("standard deviation", s.StdDev(), test.stddev)
+		check("minimum", s.Min(), test.min)
+		check("maximum", s.Max(), test.max)
+		check("25th percentile", s.Quantile(0.25), test.q25)
+		if h := s.Histogram(10, 4); !reflect.DeepEqual(h, test.histogram) {
+			t.Errorf("%v: unexpected histogram: got %v; want %v", prefix, h, test.histogram)
+		}
+	}
+}
+
+func TestAssignmentSamples(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	for _, uid := range []string{"0", "1", "2"} {
+		d.Students[uid] = &Student{UID: uid}
+	}
+	d.AddSection(&Section{Code: "a"})
+	testutil.Must(t, d.AssignSection("1", "a"))
+	set := func(uid, problem string, grade float64, grader string) {
+		testutil.Must(t, d.SetGrade(asgn, uid, problem, ProblemGrade{Grade: dec(grade), GraderUID: grader}, false, GradeEditor{}))
+	}
+	set("0", "prob1", 40, "100")
+	set("0", "prob2", 50, "101")
+	set("1", "prob1", 30, "101")
+	set("1", "a", 20, "100")
+	set("1", "b", 10, "100")
+	set("2", "prob1", 20, "100")
+	// not a student
+	set("3", "prob1", 0, "100")
+
+	s := d.AssignmentSamples(asgn, "")
+	expect := &AssignmentSamples{
+		Total: Sample{60, 90},
+		Problems: map[string]Sample{
+			"prob1": {20, 30, 40},
+			"prob2": {30, 50},
+			"a":     {20},
+			"b":     {10},
+		},
+		Graders: map[string]map[string]Sample{
+			"prob1": {"100": {20, 40}, "101": {30}},
+			"prob2": {"101": {50}},
+			"a":     {"100": {20}},
+			"b":     {"100": {10}},
+		},
+	}
+	if !reflect.DeepEqual(s, expect) {
+		t.Errorf("unexpected samples: got %+v; want %+v", s, expect)
+	}
+
+	s = d.AssignmentSamples(asgn, "a")
+	expect = &AssignmentSamples{
+		Total: Sample{60},
+		Problems: map[string]Sample{
+			"prob1": {30},
+			"prob2": {30},
+			"a":     {20},
+			"b":     {10},
+		},
+		Graders: map[string]map[string]Sample{
+			"prob1": {"101": {30}},
+			"a":     {"100": {20}},
+			"b":     {"100": {10}},
+		},
+	}
+	if !reflect.DeepEqual(s, expect) {
+		t.Errorf("unexpected samples for section a: got %+v; want %+v", s, expect)
+	}
+}
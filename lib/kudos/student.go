@@ -1,5 +1,54 @@
 package kudos
 
+import "fmt"
+
 type Student struct {
 	UID string
+	// Name, Email, ExternalID (for example, a
+	// registrar number), and Section are
+	// informational, and may be empty
+	Name       string
+	Email      string
+	ExternalID string
+	Section    string
+	// databases created before enrollment
+	// statuses were introduced will have
+	// an empty Status; use Enrollment
+	Status Enrollment
+}
+
+// Enrollment returns s's enrollment status.
+func (s *Student) Enrollment() Enrollment {
+	if s.Status == "" {
+		return EnrollmentActive
+	}
+	return s.Status
+}
+
+// Dropped returns whether s has dropped the course.
+// Dropped students' grades and handins are kept,
+// but they are left out of grading work such as
+// progress reports and rubric distribution.
+func (s *Student) Dropped() bool { return s.Enrollment() == EnrollmentDropped }
+
+// An Enrollment is a student's enrollment status.
+type Enrollment string
+
+const (
+	EnrollmentActive   Enrollment = "active"
+	EnrollmentDropped  Enrollment = "dropped"
+	EnrollmentAuditing Enrollment = "auditing"
+)
+
+// ParseEnrollment parses s as an enrollment status;
+// the empty string is parsed as EnrollmentActive.
+func ParseEnrollment(s string) (Enrollment, error) {
+	switch e := Enrollment(s); e {
+	case "":
+		return EnrollmentActive, nil
+	case EnrollmentActive, EnrollmentDropped, EnrollmentAuditing:
+		return e, nil
+	}
+	return "", fmt.Errorf("unknown enrollment status %q; must be one of %v, %v, or %v",
+		s, EnrollmentActive, EnrollmentDropped, EnrollmentAuditing)
 }
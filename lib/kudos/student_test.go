@@ -0,0 +1,72 @@
+package kudos
+
+import (
+	"testing"
+
+	"github.com/joshlf/kudos/lib/testutil"
+)
+
+func TestDropStudent(t *testing.T) {
+	d := NewDB()
+	d.AddStudent("0")
+	if d.DropStudent("1") || d.ReenrollStudent("1") {
+		t.Errorf("unexpected change to nonexistent student")
+	}
+	if d.ReenrollStudent("0") {
+		t.Errorf("unexpected re-enrollment of enrolled student")
+	}
+	if !d.DropStudent("0") || !d.Students["0"].Dropped() {
+		t.Errorf("failed to drop student")
+	}
+	if d.DropStudent("0") {
+		t.Errorf("unexpected drop of dropped student")
+	}
+	if !d.ReenrollStudent("0") || d.Students["0"].Enrollment() != EnrollmentActive {
+		t.Errorf("failed to re-enroll student")
+	}
+}
+
+func TestRemoveStudent(t *testing.T) {
+	d, asgn := makeAssignmentTestDB(t)
+	first, _ := asgn.FindHandinByCode("first")
+	editor := GradeEditor{UID: "100", Command: "kudos grade"}
+	for _, uid := range []string{"0", "1"} {
+		d.AddStudent(uid)
+		testutil.Must(t, d.SetGrade(asgn, uid, "prob1", ProblemGrade{Grade: dec(10)}, false, editor))
+		d.Handins[asgn.Code]["first"][uid] = first.Due
+		d.GrantExtension(asgn.Code, "first", uid, &Extension{Due: first.Due.Add(LateDay)})
+		d.SetLateDayChoice(asgn.Code, "first", uid, 1)
+		_, err := d.Anonymizer.NewToken(uid)
+		testutil.Must(t, err)
+	}
+	d.AddGraderAssignment(asgn.Code, &GraderAssignment{Graders: map[string]string{"0": "100", "1": "100"}})
+	history := len(d.GradeHistory)
+
+	if !d.RemoveStudent("0") {
+		t.Fatalf("failed to remove student")
+	}
+	if d.RemoveStudent("0") {
+		t.Errorf("unexpected removal of nonexistent student")
+	}
+	_, inStudents := d.Students["0"]
+	_, inGrades := d.Grades[asgn.Code]["0"]
+	_, inHandins := d.Handins[asgn.Code]["first"]["0"]
+	_, inExtensions := d.Extensions[asgn.Code]["first"]["0"]
+	_, inChoices := d.LateDayChoices[asgn.Code]["first"]["0"]
+	_, inGraders := d.GraderAssignments[asgn.Code][0].Graders["0"]
+	if inStudents || inGrades || inHandins || inExtensions || inChoices || inGraders {
+		t.Errorf("student not completely removed: %v %v %v %v %v %v",
+			inStudents, inGrades, inHandins, inExtensions, inChoices, inGraders)
+	}
+	for _, uid := range d.Anonymizer {
+		if uid == "0" {
+			t.Errorf("anonymous token not removed")
+		}
+	}
+	if len(d.Anonymizer) != 1 || d.Grades[asgn.Code]["1"] == nil || d.Handins[asgn.Code]["first"]["1"].IsZero() {
+		t.Errorf("other student's information removed")
+	}
+	if len(d.GradeHistory) != history {
+		t.Errorf("unexpected change to grade history")
+	}
+}